@@ -0,0 +1,234 @@
+package aggregate
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dcos/dcos-check-runner/client"
+	"github.com/pkg/errors"
+)
+
+const (
+	statusOK      = 0
+	statusUnknown = 3
+)
+
+const (
+	// DefaultNodeURL is the default template for the base URL of a node's check runner API.
+	DefaultNodeURL = "http://{host}:8000"
+
+	// DefaultTimeout is the default timeout for running the checks of a single node.
+	DefaultTimeout = 60 * time.Second
+
+	// DefaultConcurrency is the default number of nodes checked at the same time.
+	DefaultConcurrency = 32
+)
+
+// Aggregator runs node checks on all nodes of a cluster and combines their results.
+type Aggregator struct {
+	// Source discovers the nodes to be checked.
+	Source NodeSource
+
+	// NodeURL is a template for the base URL of a node's check runner API, matching its --base-uri. The string
+	// "{host}" is replaced with the node's host.
+	NodeURL string
+
+	// Timeout bounds the check run of a single node.
+	Timeout time.Duration
+
+	// Concurrency is the maximum number of nodes checked at the same time.
+	Concurrency int
+
+	// Client is used for requests to the nodes. If nil, http.DefaultClient is used.
+	Client *http.Client
+
+	// Token is sent as bearer token in requests to the nodes, if set.
+	Token string
+
+	// TokenFile is a file containing the bearer token sent to the nodes if Token is not set. It is read for each run,
+	// so that a renewed token is picked up.
+	TokenFile string
+}
+
+// NewAggregator returns an *Aggregator for the nodes of source, using the default settings.
+func NewAggregator(source NodeSource) *Aggregator {
+	return &Aggregator{
+		Source:      source,
+		NodeURL:     DefaultNodeURL,
+		Timeout:     DefaultTimeout,
+		Concurrency: DefaultConcurrency,
+	}
+}
+
+// CheckResult is the result of a check on a single node.
+type CheckResult struct {
+	Output string `json:"output"`
+	Status int    `json:"status"`
+}
+
+// NodeResult is the result of running the checks of a single node.
+type NodeResult struct {
+	Role string `json:"role,omitempty"`
+
+	// Status is the node's combined check status. It is 3 (UNKNOWN) if the checks could not be run.
+	Status int `json:"status"`
+
+	// Unreachable is true if the node's check runner API could not be reached.
+	Unreachable bool `json:"unreachable,omitempty"`
+
+	// Error describes why the node's checks could not be run.
+	Error string `json:"error,omitempty"`
+
+	Checks map[string]*CheckResult `json:"checks,omitempty"`
+}
+
+// Result is the combined result of running node checks on all nodes.
+type Result struct {
+	// Status is the cluster-wide combined status, the highest status of all nodes.
+	Status int `json:"status"`
+
+	// Nodes maps hosts to their results.
+	Nodes map[string]*NodeResult `json:"nodes"`
+
+	// Checks maps check names to a map of hosts to the check's status on that host.
+	Checks map[string]map[string]int `json:"checks"`
+}
+
+// Nodes returns the nodes which would be checked.
+func (a *Aggregator) Nodes(ctx context.Context) ([]Node, error) {
+	nodes, err := a.Source.Nodes(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to discover nodes")
+	}
+	return nodes, nil
+}
+
+// Run runs node checks on all nodes concurrently. If checks are given, only those checks are run. An error is only
+// returned if the nodes could not be discovered or the token could not be read, failures of individual nodes are
+// reported in the Result.
+func (a *Aggregator) Run(ctx context.Context, checks ...string) (*Result, error) {
+	token, err := a.token()
+	if err != nil {
+		return nil, err
+	}
+	nodes, err := a.Nodes(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	concurrency := a.Concurrency
+	if concurrency < 1 {
+		concurrency = DefaultConcurrency
+	}
+	sem := make(chan struct{}, concurrency)
+
+	var (
+		wg sync.WaitGroup
+		mu sync.Mutex
+	)
+	result := &Result{
+		Status: statusOK,
+		Nodes:  make(map[string]*NodeResult),
+		Checks: make(map[string]map[string]int),
+	}
+
+	for _, node := range nodes {
+		wg.Add(1)
+		go func(node Node) {
+			defer wg.Done()
+
+			var nodeResult *NodeResult
+			select {
+			case sem <- struct{}{}:
+				nodeResult = a.runNode(ctx, node, token, checks)
+				<-sem
+			case <-ctx.Done():
+				nodeResult = &NodeResult{Role: node.Role, Status: statusUnknown, Error: ctx.Err().Error()}
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			result.Nodes[node.Host] = nodeResult
+			result.Status = maxStatus(result.Status, nodeResult.Status)
+			for name, check := range nodeResult.Checks {
+				if _, ok := result.Checks[name]; !ok {
+					result.Checks[name] = make(map[string]int)
+				}
+				result.Checks[name][node.Host] = check.Status
+			}
+		}(node)
+	}
+	wg.Wait()
+
+	return result, nil
+}
+
+// token returns the bearer token sent to the nodes, or "" if none is configured.
+func (a *Aggregator) token() (string, error) {
+	if a.Token != "" || a.TokenFile == "" {
+		return a.Token, nil
+	}
+	token, err := ioutil.ReadFile(a.TokenFile)
+	if err != nil {
+		return "", errors.Wrap(err, "unable to read token")
+	}
+	return strings.TrimSpace(string(token)), nil
+}
+
+// runNode runs the node checks of a single node, authenticating with token if it is set.
+func (a *Aggregator) runNode(ctx context.Context, node Node, token string, checks []string) *NodeResult {
+	result := &NodeResult{Role: node.Role, Status: statusUnknown}
+
+	timeout := a.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	nodeURL := a.NodeURL
+	if nodeURL == "" {
+		nodeURL = DefaultNodeURL
+	}
+	c, err := client.New(strings.Replace(nodeURL, "{host}", node.Host, -1), "")
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	if a.Client != nil {
+		c.HTTPClient = a.Client
+	}
+	c.Token = token
+
+	nodeResult, err := c.RunChecks(ctx, client.CheckTypeNode, checks...)
+	if err != nil {
+		if _, ok := errors.Cause(err).(*url.Error); ok {
+			result.Unreachable = true
+		}
+		result.Error = err.Error()
+		return result
+	}
+
+	result.Status = nodeResult.Status
+	result.Checks = make(map[string]*CheckResult, len(nodeResult.Checks))
+	for name, check := range nodeResult.Checks {
+		result.Checks[name] = &CheckResult{Output: check.Output, Status: check.Status}
+	}
+	return result
+}
+
+// maxStatus returns the higher of two check statuses. Values outside of 0-3 result in 3 (UNKNOWN).
+func maxStatus(a, b int) int {
+	if a > statusUnknown || a < statusOK || b > statusUnknown || b < statusOK {
+		return statusUnknown
+	}
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,162 @@
+// Package aggregate runs node checks on all nodes of a cluster through their check runner HTTP API and combines the
+// results.
+package aggregate
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Node is a cluster node running the check runner HTTP server.
+type Node struct {
+	// Host is the node's hostname or IP address.
+	Host string `json:"host"`
+
+	// Role is the node's DC/OS role, if known.
+	Role string `json:"role,omitempty"`
+}
+
+// NodeSource discovers the nodes of a cluster.
+type NodeSource interface {
+	Nodes(ctx context.Context) ([]Node, error)
+}
+
+// StaticSource is a fixed list of nodes.
+type StaticSource []Node
+
+// Nodes returns the nodes in s.
+func (s StaticSource) Nodes(ctx context.Context) ([]Node, error) {
+	return s, nil
+}
+
+// FileSource reads nodes from a file. Each line contains a host, optionally followed by whitespace and the node's
+// role. Empty lines and lines starting with # are ignored. The file is read on every call, so it can be updated
+// while the server is running.
+type FileSource string
+
+// Nodes returns the nodes listed in the file.
+func (s FileSource) Nodes(ctx context.Context) ([]Node, error) {
+	f, err := os.Open(string(s))
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to open nodes file")
+	}
+	defer f.Close()
+
+	var nodes []Node
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		node := Node{Host: fields[0]}
+		if len(fields) > 1 {
+			node.Role = fields[1]
+		}
+		nodes = append(nodes, node)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrap(err, "unable to read nodes file")
+	}
+	return nodes, nil
+}
+
+// MesosStateSource discovers agents from the Mesos master state, e.g. http://leader.mesos:5050/state. If the
+// location is not an http(s) URL it is read as a file containing the state JSON.
+type MesosStateSource struct {
+	Location string
+	Client   *http.Client
+}
+
+// mesosState is the subset of the Mesos master state used to discover agents.
+type mesosState struct {
+	Slaves []struct {
+		Hostname string `json:"hostname"`
+		Active   *bool  `json:"active"`
+	} `json:"slaves"`
+}
+
+// Nodes returns the active agents registered with the Mesos master.
+func (s MesosStateSource) Nodes(ctx context.Context) ([]Node, error) {
+	var body io.ReadCloser
+	if strings.HasPrefix(s.Location, "http://") || strings.HasPrefix(s.Location, "https://") {
+		req, err := http.NewRequest("GET", s.Location, nil)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid Mesos state URL")
+		}
+		client := s.Client
+		if client == nil {
+			client = http.DefaultClient
+		}
+		resp, err := client.Do(req.WithContext(ctx))
+		if err != nil {
+			return nil, errors.Wrap(err, "unable to get Mesos state")
+		}
+		if resp.StatusCode != http.StatusOK {
+			resp.Body.Close()
+			return nil, errors.Errorf("unable to get Mesos state: %s", resp.Status)
+		}
+		body = resp.Body
+	} else {
+		f, err := os.Open(s.Location)
+		if err != nil {
+			return nil, errors.Wrap(err, "unable to open Mesos state file")
+		}
+		body = f
+	}
+	defer body.Close()
+
+	var state mesosState
+	if err := json.NewDecoder(body).Decode(&state); err != nil {
+		return nil, errors.Wrap(err, "unable to decode Mesos state")
+	}
+
+	var nodes []Node
+	for _, agent := range state.Slaves {
+		if agent.Active != nil && !*agent.Active {
+			continue
+		}
+		nodes = append(nodes, Node{Host: agent.Hostname, Role: "agent"})
+	}
+	return nodes, nil
+}
+
+// MultiSource combines the nodes of several sources. Nodes found by more than one source are returned once, the
+// first known role is kept.
+type MultiSource []NodeSource
+
+// Nodes returns the nodes of all sources, sorted by host.
+func (s MultiSource) Nodes(ctx context.Context) ([]Node, error) {
+	byHost := make(map[string]Node)
+	for _, source := range s {
+		nodes, err := source.Nodes(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, node := range nodes {
+			if existing, ok := byHost[node.Host]; ok && existing.Role != "" {
+				continue
+			}
+			byHost[node.Host] = node
+		}
+	}
+
+	nodes := make([]Node, 0, len(byHost))
+	for _, node := range byHost {
+		nodes = append(nodes, node)
+	}
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i].Host < nodes[j].Host
+	})
+	return nodes, nil
+}
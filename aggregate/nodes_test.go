@@ -0,0 +1,77 @@
+package aggregate
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"testing"
+)
+
+const testMesosState = `{
+  "hostname": "10.0.0.1",
+  "slaves": [
+    {"id": "a1", "hostname": "10.0.1.1", "active": true},
+    {"id": "a2", "hostname": "10.0.1.2", "active": false},
+    {"id": "a3", "hostname": "10.0.1.3"}
+  ]
+}`
+
+func TestSources(t *testing.T) {
+	f, err := ioutil.TempFile("", "nodes")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.WriteString("# masters\n10.0.0.1 master\n\n10.0.1.1\n"); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	nodes, err := FileSource(f.Name()).Nodes(context.TODO())
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []Node{{Host: "10.0.0.1", Role: "master"}, {Host: "10.0.1.1"}}
+	if !reflect.DeepEqual(nodes, expected) {
+		t.Fatalf("expected nodes %v, got %v", expected, nodes)
+	}
+
+	mesos := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(testMesosState))
+	}))
+	defer mesos.Close()
+
+	nodes, err = MesosStateSource{Location: mesos.URL + "/state"}.Nodes(context.TODO())
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected = []Node{{Host: "10.0.1.1", Role: "agent"}, {Host: "10.0.1.3", Role: "agent"}}
+	if !reflect.DeepEqual(nodes, expected) {
+		t.Fatalf("expected nodes %v, got %v", expected, nodes)
+	}
+
+	// Nodes found by several sources are only returned once, with their role if any source knows it.
+	nodes, err = MultiSource{
+		StaticSource{{Host: "10.0.1.3"}},
+		FileSource(f.Name()),
+		MesosStateSource{Location: mesos.URL + "/state"},
+	}.Nodes(context.TODO())
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected = []Node{
+		{Host: "10.0.0.1", Role: "master"},
+		{Host: "10.0.1.1", Role: "agent"},
+		{Host: "10.0.1.3", Role: "agent"},
+	}
+	if !reflect.DeepEqual(nodes, expected) {
+		t.Fatalf("expected nodes %v, got %v", expected, nodes)
+	}
+
+	if _, err := FileSource("/nonexistent").Nodes(context.TODO()); err == nil {
+		t.Fatal("expected an error for a nonexistent file")
+	}
+}
@@ -0,0 +1,60 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/dcos/dcos-check-runner/aggregate"
+	"github.com/dcos/dcos-check-runner/auth"
+	"github.com/pkg/errors"
+)
+
+// checkTypeAggregate is the check type of the /aggregate/ endpoint in auth policies.
+const checkTypeAggregate = "aggregate"
+
+// listNodes responds with the nodes which are checked by the /aggregate/ endpoint.
+func (rh *runnerHandler) listNodes(w http.ResponseWriter, r *http.Request) {
+	httpErr := rh.authorize(r, checkTypeAggregate, auth.ActionList)
+	if httpErr != nil {
+		http.Error(w, httpErr.Error(), httpErr.statusCode)
+		return
+	}
+
+	nodes, err := rh.aggregator.Nodes(r.Context())
+	if err != nil {
+		errMsg := "Error discovering nodes"
+		reqLogger(r).Error(errors.Wrap(err, errMsg))
+		http.Error(w, errMsg, http.StatusInternalServerError)
+		return
+	}
+
+	if nodes == nil {
+		nodes = []aggregate.Node{}
+	}
+	writeJSONResponse(w, r, nodes)
+}
+
+// runAggregate runs node checks on all nodes and responds with the per-node results and the cluster-wide status.
+// The checks to run can be selected in the request body like for /node/.
+func (rh *runnerHandler) runAggregate(w http.ResponseWriter, r *http.Request) {
+	httpErr := rh.authorize(r, checkTypeAggregate, auth.ActionRun)
+	if httpErr != nil {
+		http.Error(w, httpErr.Error(), httpErr.statusCode)
+		return
+	}
+
+	checks, httpErr := checksFromBody(r)
+	if httpErr != nil {
+		http.Error(w, httpErr.Error(), httpErr.statusCode)
+		return
+	}
+
+	result, err := rh.aggregator.Run(r.Context(), checks...)
+	if err != nil {
+		errMsg := "Error running node checks on cluster nodes"
+		reqLogger(r).Error(errors.Wrap(err, errMsg))
+		http.Error(w, errMsg, http.StatusInternalServerError)
+		return
+	}
+
+	writeJSONResponse(w, r, result)
+}
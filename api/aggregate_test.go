@@ -0,0 +1,220 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dcos/dcos-check-runner/aggregate"
+	"github.com/dcos/dcos-check-runner/auth"
+)
+
+func TestAggregate(t *testing.T) {
+	master, err := newTestServer("master", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer master.Close()
+	agent, err := newTestServer("agent", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer agent.Close()
+
+	// A node whose check runner is not running.
+	down, err := newTestServer("agent", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	downHost := strings.TrimPrefix(down.URL, "http://")
+	down.Close()
+
+	masterHost := strings.TrimPrefix(master.URL, "http://")
+	agentHost := strings.TrimPrefix(agent.URL, "http://")
+	a := aggregate.NewAggregator(aggregate.StaticSource{
+		{Host: masterHost, Role: "master"},
+		{Host: agentHost, Role: "agent"},
+		{Host: downHost, Role: "agent"},
+	})
+	a.NodeURL = "http://{host}"
+	a.Timeout = 5 * time.Second
+
+	r, err := newTestRunner("master")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := httptest.NewServer(NewRouter(r, "", WithAggregator(a)))
+	defer s.Close()
+
+	t.Run("list nodes", func(t *testing.T) {
+		resp := getResponse(t, "GET", s.URL+"/aggregate/", nil, nil)
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("expected status %d, got %d", http.StatusOK, resp.StatusCode)
+		}
+		var nodes []aggregate.Node
+		if err := json.NewDecoder(resp.Body).Decode(&nodes); err != nil {
+			t.Fatal(err)
+		}
+		if len(nodes) != 3 {
+			t.Fatalf("expected 3 nodes, got %d", len(nodes))
+		}
+	})
+
+	t.Run("run node checks", func(t *testing.T) {
+		resp := getResponse(t, "POST", s.URL+"/aggregate/", nil, nil)
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("expected status %d, got %d", http.StatusOK, resp.StatusCode)
+		}
+		var result aggregate.Result
+		if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+			t.Fatal(err)
+		}
+
+		// The unreachable node results in an UNKNOWN cluster status.
+		if result.Status != 3 {
+			t.Fatalf("expected status 3, got %d", result.Status)
+		}
+		if nodeResult := result.Nodes[downHost]; !nodeResult.Unreachable || nodeResult.Status != 3 || nodeResult.Error == "" {
+			t.Fatalf("unexpected result for unreachable node: %+v", nodeResult)
+		}
+		expectedChecks := map[string]*aggregate.CheckResult{
+			"node-check":        {Status: 0, Output: "node-check\n"},
+			"node-check-master": {Status: 0, Output: "node-check-master\n"},
+		}
+		if nodeResult := result.Nodes[masterHost]; nodeResult.Unreachable || nodeResult.Status != 0 || !reflect.DeepEqual(nodeResult.Checks, expectedChecks) {
+			t.Fatalf("unexpected result for master: %+v", nodeResult)
+		}
+		expectedMatrix := map[string]map[string]int{
+			"node-check":        {masterHost: 0, agentHost: 0},
+			"node-check-master": {masterHost: 0},
+			"node-check-agent":  {agentHost: 0},
+		}
+		if !reflect.DeepEqual(result.Checks, expectedMatrix) {
+			t.Fatalf("expected checks %v, got %v", expectedMatrix, result.Checks)
+		}
+	})
+
+	t.Run("run selected node checks", func(t *testing.T) {
+		body, err := json.Marshal(map[string]interface{}{"check": []string{"node-check-agent"}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		headers := map[string]string{"Content-Type": "application/json"}
+		resp := getResponse(t, "POST", s.URL+"/aggregate/", headers, bytes.NewReader(body))
+		var result aggregate.Result
+		if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+			t.Fatal(err)
+		}
+
+		// The agent check doesn't apply to the master.
+		masterResult := result.Nodes[masterHost]
+		if masterResult.Status != 0 || len(masterResult.Checks) != 0 {
+			t.Fatalf("unexpected result for master: %+v", masterResult)
+		}
+		agentResult := result.Nodes[agentHost]
+		if agentResult.Status != 0 || len(agentResult.Checks) != 1 {
+			t.Fatalf("unexpected result for agent: %+v", agentResult)
+		}
+	})
+
+	t.Run("node errors", func(t *testing.T) {
+		body, err := json.Marshal(map[string]interface{}{"check": []string{"nonexistent"}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		headers := map[string]string{"Content-Type": "application/json"}
+		resp := getResponse(t, "POST", s.URL+"/aggregate/", headers, bytes.NewReader(body))
+		var result aggregate.Result
+		if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+			t.Fatal(err)
+		}
+
+		// Nodes which respond with an error are not reported as unreachable.
+		agentResult := result.Nodes[agentHost]
+		if agentResult.Status != 3 || agentResult.Unreachable || agentResult.Error != "404 Not Found: missing checks: [nonexistent]" {
+			t.Fatalf("unexpected result for agent: %+v", agentResult)
+		}
+	})
+
+	t.Run("aggregation is disabled by default", func(t *testing.T) {
+		if sc := getResponse(t, "POST", master.URL+"/aggregate/", nil, nil).StatusCode; sc != http.StatusNotFound {
+			t.Fatalf("expected status %d, got %d", http.StatusNotFound, sc)
+		}
+	})
+}
+
+func TestAggregateAuth(t *testing.T) {
+	r, err := newTestRunner("agent")
+	if err != nil {
+		t.Fatal(err)
+	}
+	authenticator := auth.NewTokenAuthenticator(map[string]string{"s3cr3t": "aggregator"})
+	node := httptest.NewServer(NewRouter(r, "", WithAuth(authenticator, nil)))
+	defer node.Close()
+
+	dir, err := ioutil.TempDir("", "aggregate-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	tokenFile := filepath.Join(dir, "token")
+	if err := ioutil.WriteFile(tokenFile, []byte("s3cr3t\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	host := strings.TrimPrefix(node.URL, "http://")
+	newAggregator := func() *aggregate.Aggregator {
+		a := aggregate.NewAggregator(aggregate.StaticSource{{Host: host, Role: "agent"}})
+		a.NodeURL = "http://{host}"
+		a.Timeout = 5 * time.Second
+		return a
+	}
+
+	t.Run("without token", func(t *testing.T) {
+		result, err := newAggregator().Run(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if nodeResult := result.Nodes[host]; nodeResult.Status != 3 || !strings.HasPrefix(nodeResult.Error, "401 Unauthorized") {
+			t.Fatalf("unexpected result %+v", nodeResult)
+		}
+	})
+
+	t.Run("token", func(t *testing.T) {
+		a := newAggregator()
+		a.Token = "s3cr3t"
+		result, err := a.Run(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if nodeResult := result.Nodes[host]; nodeResult.Status != 0 || len(nodeResult.Checks) != 2 {
+			t.Fatalf("unexpected result %+v", nodeResult)
+		}
+	})
+
+	t.Run("token file", func(t *testing.T) {
+		a := newAggregator()
+		a.TokenFile = tokenFile
+		result, err := a.Run(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if nodeResult := result.Nodes[host]; nodeResult.Status != 0 || len(nodeResult.Checks) != 2 {
+			t.Fatalf("unexpected result %+v", nodeResult)
+		}
+
+		a.TokenFile = filepath.Join(dir, "nonexistent")
+		if _, err := a.Run(context.Background()); err == nil {
+			t.Fatal("expected an error reading a nonexistent token file")
+		}
+	})
+}
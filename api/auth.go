@@ -0,0 +1,110 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/dcos/dcos-check-runner/auth"
+	"github.com/sirupsen/logrus"
+)
+
+// identityContextKey is the key at which the client's identity is stored in a request's context by authMiddleware().
+const identityContextKey contextKey = "identity"
+
+// listenerAuthContextKey is the key at which the authentication of the listener a request was received on is stored
+// in the request's context by ListenerAuth().
+const listenerAuthContextKey contextKey = "listener-auth"
+
+// listenerAuth is the authentication and authorization of requests received on a listener.
+type listenerAuth struct {
+	authenticator auth.Authenticator
+	policy        auth.Policy
+}
+
+// WithAuth requires requests to be authenticated by authenticator, and authorizes them with policy. If policy is nil,
+// all authenticated clients are allowed to list and run all checks.
+func WithAuth(authenticator auth.Authenticator, policy auth.Policy) Option {
+	return func(rh *runnerHandler) {
+		rh.authenticator = authenticator
+		rh.policy = policy
+	}
+}
+
+// ListenerAuth returns a http.Handler which serves requests with h, a router returned by NewRouter(), but authenticates
+// them with authenticator and authorizes them with policy instead of the authentication configured with WithAuth. If
+// authenticator is nil, requests are neither authenticated nor authorized. It allows serving the same router on several
+// listeners with different authentication.
+func ListenerAuth(h http.Handler, authenticator auth.Authenticator, policy auth.Policy) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), listenerAuthContextKey, &listenerAuth{authenticator, policy})
+		h.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// auth returns the authenticator and policy of r, which are those of the listener r was received on if set by
+// ListenerAuth(), and those configured with WithAuth otherwise.
+func (rh *runnerHandler) auth(r *http.Request) (auth.Authenticator, auth.Policy) {
+	if la, ok := r.Context().Value(listenerAuthContextKey).(*listenerAuth); ok {
+		return la.authenticator, la.policy
+	}
+	return rh.authenticator, rh.policy
+}
+
+// authMiddleware returns a http.Handler that authenticates r and calls next.ServeHTTP() with the client's identity added
+// to r and its logger. Requests which can't be authenticated are rejected with http.StatusUnauthorized. Requests are
+// passed on unauthenticated if no authentication is configured.
+func (rh *runnerHandler) authMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authenticator, _ := rh.auth(r)
+		if authenticator == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		identity, err := authenticator.Authenticate(r)
+		if err != nil {
+			reqLogger(r).WithField("error", err).Warn("Rejected unauthenticated request")
+			w.Header().Set("WWW-Authenticate", `Bearer realm="dcos-check-runner"`)
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), identityContextKey, identity)
+		ctx = context.WithValue(ctx, loggerContextKey, reqLogger(r).WithField("identity", identity))
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// reqIdentity returns the identity added to r by authMiddleware(), or "" if r was not authenticated.
+func reqIdentity(r *http.Request) string {
+	identity, _ := r.Context().Value(identityContextKey).(string)
+	return identity
+}
+
+// allowed returns whether the client of r is allowed to perform action on checks of checkType. As "node" is an alias of
+// "node-poststart", policies grant both with "node".
+func (rh *runnerHandler) allowed(r *http.Request, checkType, action string) bool {
+	authenticator, policy := rh.auth(r)
+	if authenticator == nil || policy == nil {
+		return true
+	}
+	if checkType == checkTypeNodePostStart {
+		checkType = checkTypeNode
+	}
+	return policy.Allowed(reqIdentity(r), checkType, action)
+}
+
+// authorize returns an httpError with http.StatusForbidden if the client of r is not allowed to perform action on checks
+// of checkType.
+func (rh *runnerHandler) authorize(r *http.Request, checkType, action string) *httpError {
+	if rh.allowed(r, checkType, action) {
+		return nil
+	}
+
+	reqLogger(r).WithFields(logrus.Fields{
+		"check_type": checkType,
+		"action":     action,
+	}).Warn("Rejected unauthorized request")
+	return &httpError{http.StatusForbidden, fmt.Sprintf("%s is not allowed to %s %s checks", reqIdentity(r), action, checkType)}
+}
@@ -0,0 +1,144 @@
+package api
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/dcos/dcos-check-runner/auth"
+	"github.com/sirupsen/logrus"
+)
+
+func TestAuth(t *testing.T) {
+	r, err := newTestRunner("master")
+	if err != nil {
+		t.Fatal(err)
+	}
+	authenticator := auth.NewTokenAuthenticator(map[string]string{
+		"admin-token":      "admin",
+		"prometheus-token": "prometheus",
+	})
+	policy := auth.Policy{
+		"admin":      {"*": {"*"}},
+		"prometheus": {"node": {auth.ActionList, auth.ActionRun}, "cluster": {auth.ActionList}},
+	}
+	s := httptest.NewServer(NewRouter(r, "", WithAuth(authenticator, policy)))
+	defer s.Close()
+
+	jsonHeaders := func(token string) map[string]string {
+		headers := map[string]string{"Content-Type": "application/json"}
+		if token != "" {
+			headers["Authorization"] = "Bearer " + token
+		}
+		return headers
+	}
+
+	for _, tt := range []struct {
+		name       string
+		method     string
+		path       string
+		body       string
+		token      string
+		statusCode int
+	}{
+		{"no credentials", "GET", "/node/", "", "", http.StatusUnauthorized},
+		{"invalid token", "GET", "/node/", "", "wrong", http.StatusUnauthorized},
+		{"list allowed", "GET", "/cluster/", "", "prometheus-token", http.StatusOK},
+		{"run allowed", "POST", "/node/", "{}", "prometheus-token", http.StatusOK},
+		{"run forbidden", "POST", "/cluster/", "{}", "prometheus-token", http.StatusForbidden},
+		{"stream forbidden", "GET", "/cluster/stream", "", "prometheus-token", http.StatusForbidden},
+		{"run forbidden async", "POST", "/runs/", `{"check_type": "cluster"}`, "prometheus-token", http.StatusForbidden},
+		{"run allowed async", "POST", "/runs/", `{"check_type": "node"}`, "prometheus-token", http.StatusAccepted},
+		{"wildcard", "POST", "/cluster/", "{}", "admin-token", http.StatusOK},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := getResponse(t, tt.method, s.URL+tt.path, jsonHeaders(tt.token), strings.NewReader(tt.body))
+			resp.Body.Close()
+			if resp.StatusCode != tt.statusCode {
+				t.Fatalf("expected status %d, got %d", tt.statusCode, resp.StatusCode)
+			}
+			if tt.statusCode == http.StatusUnauthorized && resp.Header.Get("WWW-Authenticate") == "" {
+				t.Fatal("expected a WWW-Authenticate header")
+			}
+		})
+	}
+}
+
+func TestAuthLogsIdentity(t *testing.T) {
+	var buf bytes.Buffer
+	defer logrus.SetOutput(logrus.StandardLogger().Out)
+	logrus.SetOutput(&buf)
+
+	r, err := newTestRunner("master")
+	if err != nil {
+		t.Fatal(err)
+	}
+	authenticator := auth.NewTokenAuthenticator(map[string]string{"s3cr3t": "prometheus"})
+	s := httptest.NewServer(NewRouter(r, "", WithAuth(authenticator, nil)))
+
+	// Without a policy, all authenticated clients are allowed.
+	resp := getResponse(t, "POST", s.URL+"/cluster/", map[string]string{"Authorization": "Bearer s3cr3t"}, nil)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, resp.StatusCode)
+	}
+
+	// Wait for the request to be logged.
+	s.Close()
+	if !strings.Contains(buf.String(), "identity=prometheus") {
+		t.Fatalf("expected the identity to be logged, got %s", buf.String())
+	}
+}
+
+func TestListenerAuth(t *testing.T) {
+	r, err := newTestRunner("master")
+	if err != nil {
+		t.Fatal(err)
+	}
+	authenticator := auth.NewTokenAuthenticator(map[string]string{"prometheus-token": "prometheus"})
+	policy := auth.Policy{"prometheus": {"*": {auth.ActionList}}}
+	router := NewRouter(r, "", WithAuth(authenticator, policy))
+
+	// The same router is served with its own authentication, without authentication, and with a different policy.
+	servers := map[string]*httptest.Server{
+		"default":  httptest.NewServer(router),
+		"trusted":  httptest.NewServer(ListenerAuth(router, nil, nil)),
+		"override": httptest.NewServer(ListenerAuth(router, authenticator, auth.Policy{"prometheus": {"*": {"*"}}})),
+	}
+	for _, s := range servers {
+		defer s.Close()
+	}
+
+	for _, tt := range []struct {
+		server     string
+		token      string
+		statusCode int
+	}{
+		{"default", "", http.StatusUnauthorized},
+		{"default", "prometheus-token", http.StatusForbidden},
+		{"trusted", "", http.StatusOK},
+		{"override", "", http.StatusUnauthorized},
+		{"override", "prometheus-token", http.StatusOK},
+	} {
+		headers := map[string]string{"Content-Type": "application/json"}
+		if tt.token != "" {
+			headers["Authorization"] = "Bearer " + tt.token
+		}
+		resp := getResponse(t, "POST", servers[tt.server].URL+"/cluster/", headers, strings.NewReader("{}"))
+		resp.Body.Close()
+		if resp.StatusCode != tt.statusCode {
+			t.Fatalf("%s listener with token %q: expected status %d, got %d", tt.server, tt.token, tt.statusCode, resp.StatusCode)
+		}
+	}
+
+	// Authentication can be added to a router without it.
+	s := httptest.NewServer(ListenerAuth(NewRouter(r, ""), authenticator, nil))
+	defer s.Close()
+	resp := getResponse(t, "GET", s.URL+"/cluster/", nil, nil)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("expected status %d, got %d", http.StatusUnauthorized, resp.StatusCode)
+	}
+}
@@ -0,0 +1,132 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+
+	"github.com/dcos/dcos-check-runner/auth"
+	"github.com/dcos/dcos-check-runner/runner"
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+)
+
+// getCheck responds with the definition of the check given by the check_type and check_name variables in the URI.
+func (rh *runnerHandler) getCheck(w http.ResponseWriter, r *http.Request) {
+	rs, httpErr := rh.runSingleCheck(r, auth.ActionList, true)
+	if httpErr != nil {
+		http.Error(w, httpErr.Error(), httpErr.statusCode)
+		return
+	}
+	writeJSONResponse(w, r, rs.Checks()[0])
+}
+
+// runCheck runs the check given by the check_type and check_name variables in the URI and responds with its result.
+// If the check could not be executed, the error is returned like for /{check_type}/.
+func (rh *runnerHandler) runCheck(w http.ResponseWriter, r *http.Request) {
+	rs, httpErr := rh.runSingleCheck(r, auth.ActionRun, false)
+	if httpErr != nil {
+		http.Error(w, httpErr.Error(), httpErr.statusCode)
+		return
+	}
+	if len(rs.Errors()) > 0 {
+		writeJSONResponse(w, r, rs)
+		return
+	}
+	writeJSONResponse(w, r, rs.Checks()[0])
+}
+
+// runSingleCheck runs or lists the check given by the URI, after authorizing action.
+func (rh *runnerHandler) runSingleCheck(r *http.Request, action string, list bool) (*runner.CombinedResponse, *httpError) {
+	checkType, httpErr := verifyCheckType(r)
+	if httpErr != nil {
+		return nil, httpErr
+	}
+
+	httpErr = rh.authorize(r, checkType, action)
+	if httpErr != nil {
+		return nil, httpErr
+	}
+
+	checkFunc, httpErr := rh.getCheckFuncFromReq(checkType)
+	if httpErr != nil {
+		return nil, httpErr
+	}
+
+	checkName := mux.Vars(r)["check_name"]
+	httpErr = rh.verifySelectedChecks(checkType, []string{checkName})
+	if httpErr != nil {
+		return nil, httpErr
+	}
+
+	rs, err := checkFunc(r.Context(), list, checkName)
+	if err != nil {
+		errMsg := "Error running checks"
+		if list {
+			errMsg = "Error listing checks"
+		}
+		return nil, runError(r, err, errMsg)
+	}
+
+	if len(rs.Checks()) == 0 && len(rs.Errors()) == 0 {
+		return nil, &httpError{http.StatusNotFound, fmt.Sprintf("check %s does not apply to role %s", checkName, rh.runner.Role())}
+	}
+	return rs, nil
+}
+
+// discovery is the response of the discovery endpoint.
+type discovery struct {
+	Role       string                   `json:"role"`
+	CheckTypes map[string]discoveryType `json:"check_types"`
+}
+
+// discoveryType describes a check type in the response of the discovery endpoint.
+type discoveryType struct {
+	Suite  string   `json:"suite"`
+	Checks []string `json:"checks"`
+
+	// Roles maps all checks of the check type, including those of other roles, to the roles they apply to. Checks
+	// without roles apply to all roles.
+	Roles map[string][]string `json:"roles"`
+}
+
+// discover responds with the node's role, and the check types with their suite, the checks which apply to the node's
+// role, and the roles of all their checks. Check types the client is not allowed to list are left out.
+func (rh *runnerHandler) discover(w http.ResponseWriter, r *http.Request) {
+	d := discovery{
+		Role:       rh.runner.Role(),
+		CheckTypes: make(map[string]discoveryType),
+	}
+	for checkType := range suites {
+		if !rh.allowed(r, checkType, auth.ActionList) {
+			continue
+		}
+
+		checkFunc, httpErr := rh.getCheckFuncFromReq(checkType)
+		if httpErr != nil {
+			http.Error(w, httpErr.Error(), httpErr.statusCode)
+			return
+		}
+		rs, err := checkFunc(r.Context(), true)
+		if err != nil {
+			errMsg := "Error listing checks"
+			reqLogger(r).Error(errors.Wrap(err, errMsg))
+			http.Error(w, errMsg, http.StatusInternalServerError)
+			return
+		}
+
+		checks := []string{}
+		for _, c := range rs.Checks() {
+			checks = append(checks, c.Name())
+		}
+		sort.Strings(checks)
+
+		roles := make(map[string][]string)
+		for name, c := range rh.runner.Checks(suites[checkType]) {
+			roles[name] = append([]string{}, c.Roles...)
+		}
+		d.CheckTypes[checkType] = discoveryType{Suite: suites[checkType], Checks: checks, Roles: roles}
+	}
+
+	writeJSONResponse(w, r, d)
+}
@@ -0,0 +1,198 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/dcos/dcos-check-runner/auth"
+)
+
+// decodeJSON decodes the JSON body of resp, failing the test if the status code is not statusCode.
+func decodeJSON(t *testing.T, resp *http.Response, statusCode int) map[string]interface{} {
+	defer resp.Body.Close()
+
+	if resp.StatusCode != statusCode {
+		t.Fatalf("expected status %d, got %d", statusCode, resp.StatusCode)
+	}
+	var body map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	return body
+}
+
+func TestCheckTypes(t *testing.T) {
+	s, err := newTestServer("master", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	t.Run("prestart checks", func(t *testing.T) {
+		checks := decodeJSON(t, getResponse(t, "GET", s.URL+"/node-prestart/", nil, nil), http.StatusOK)
+		if len(checks) != 1 || checks["node-check-prestart"] == nil {
+			t.Fatalf("unexpected prestart checks %v", checks)
+		}
+
+		result := decodeJSON(t, getResponse(t, "POST", s.URL+"/node-prestart/", nil, nil), http.StatusOK)
+		if len(result["checks"].(map[string]interface{})) != 1 {
+			t.Fatalf("unexpected prestart result %v", result)
+		}
+	})
+
+	t.Run("checks of another suite", func(t *testing.T) {
+		jsonHeaders := map[string]string{"Content-Type": "application/json"}
+		for _, req := range []struct {
+			method, path, body string
+		}{
+			{"GET", "/node-prestart/?check=node-check", ""},
+			{"POST", "/node-prestart/", `{"check": ["node-check"]}`},
+			{"POST", "/node-poststart/", `{"check": ["node-check-prestart"]}`},
+			{"POST", "/runs/", `{"check_type": "node-prestart", "check": ["node-check"]}`},
+			{"GET", "/node-prestart/stream?check=node-check", ""},
+			{"GET", "/cluster/?check=node-check", ""},
+		} {
+			resp := getResponse(t, req.method, s.URL+req.path, jsonHeaders, strings.NewReader(req.body))
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusNotFound {
+				t.Fatalf("%s %s %s: expected status %d, got %d", req.method, req.path, req.body, http.StatusNotFound, resp.StatusCode)
+			}
+		}
+	})
+
+	t.Run("node is an alias of node-poststart", func(t *testing.T) {
+		node := decodeJSON(t, getResponse(t, "GET", s.URL+"/node/", nil, nil), http.StatusOK)
+		poststart := decodeJSON(t, getResponse(t, "GET", s.URL+"/node-poststart/", nil, nil), http.StatusOK)
+		if !reflect.DeepEqual(node, poststart) {
+			t.Fatalf("expected the same checks for node and node-poststart, got %v and %v", node, poststart)
+		}
+	})
+}
+
+func TestSingleCheck(t *testing.T) {
+	s, err := newTestServer("master", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	t.Run("get check", func(t *testing.T) {
+		check := decodeJSON(t, getResponse(t, "GET", s.URL+"/cluster/cluster-check-1", nil, nil), http.StatusOK)
+		expected := map[string]interface{}{
+			"description": "Cluster check 1",
+			"cmd":         []interface{}{"echo", "cluster-check-1"},
+			"timeout":     "1s",
+		}
+		if !reflect.DeepEqual(check, expected) {
+			t.Fatalf("expected %v, got %v", expected, check)
+		}
+	})
+
+	t.Run("run check", func(t *testing.T) {
+		result := decodeJSON(t, getResponse(t, "POST", s.URL+"/node/node-check-master", nil, nil), http.StatusOK)
+		expected := map[string]interface{}{
+			"output": "node-check-master\n",
+			"status": float64(0),
+		}
+		if !reflect.DeepEqual(result, expected) {
+			t.Fatalf("expected %v, got %v", expected, result)
+		}
+	})
+
+	t.Run("missing check", func(t *testing.T) {
+		for _, method := range []string{"GET", "POST"} {
+			resp := getResponse(t, method, s.URL+"/cluster/nonexistent", nil, nil)
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusNotFound {
+				t.Fatalf("expected status %d, got %d", http.StatusNotFound, resp.StatusCode)
+			}
+		}
+	})
+
+	t.Run("check of another suite", func(t *testing.T) {
+		for _, path := range []string{"/node-prestart/node-check", "/node/node-check-prestart", "/cluster/node-check", "/node/cluster-check-1"} {
+			for _, method := range []string{"GET", "POST"} {
+				resp := getResponse(t, method, s.URL+path, nil, nil)
+				resp.Body.Close()
+				if resp.StatusCode != http.StatusNotFound {
+					t.Fatalf("%s %s: expected status %d, got %d", method, path, http.StatusNotFound, resp.StatusCode)
+				}
+			}
+		}
+	})
+
+	t.Run("check of another role", func(t *testing.T) {
+		resp := getResponse(t, "POST", s.URL+"/node/node-check-agent", nil, nil)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusNotFound {
+			t.Fatalf("expected status %d, got %d", http.StatusNotFound, resp.StatusCode)
+		}
+	})
+}
+
+func TestDiscovery(t *testing.T) {
+	s, err := newTestServer("master", "/system/checks")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	d := decodeJSON(t, getResponse(t, "GET", s.URL+"/system/checks/", nil, nil), http.StatusOK)
+
+	// The roles of checks which don't apply to the master are included.
+	poststartRoles := map[string]interface{}{
+		"node-check":        []interface{}{},
+		"node-check-master": []interface{}{"master"},
+		"node-check-agent":  []interface{}{"agent"},
+	}
+	expected := map[string]interface{}{
+		"role": "master",
+		"check_types": map[string]interface{}{
+			"node": map[string]interface{}{
+				"suite":  "node-poststart",
+				"checks": []interface{}{"node-check", "node-check-master"},
+				"roles":  poststartRoles,
+			},
+			"node-poststart": map[string]interface{}{
+				"suite":  "node-poststart",
+				"checks": []interface{}{"node-check", "node-check-master"},
+				"roles":  poststartRoles,
+			},
+			"node-prestart": map[string]interface{}{
+				"suite":  "node-prestart",
+				"checks": []interface{}{"node-check-prestart"},
+				"roles":  map[string]interface{}{"node-check-prestart": []interface{}{}},
+			},
+			"cluster": map[string]interface{}{
+				"suite":  "cluster",
+				"checks": []interface{}{"cluster-check-1", "cluster-check-2"},
+				"roles":  map[string]interface{}{"cluster-check-1": []interface{}{}, "cluster-check-2": []interface{}{}},
+			},
+		},
+	}
+	if !reflect.DeepEqual(d, expected) {
+		t.Fatalf("expected %v, got %v", expected, d)
+	}
+}
+
+func TestDiscoveryPolicy(t *testing.T) {
+	r, err := newTestRunner("master")
+	if err != nil {
+		t.Fatal(err)
+	}
+	authenticator := auth.NewTokenAuthenticator(map[string]string{"s3cr3t": "prometheus"})
+	policy := auth.Policy{"prometheus": {"node": {auth.ActionList}}}
+	s := httptest.NewServer(NewRouter(r, "", WithAuth(authenticator, policy)))
+	defer s.Close()
+
+	// The policy for node applies to its alias node-poststart, and check types which can't be listed are left out.
+	d := decodeJSON(t, getResponse(t, "GET", s.URL+"/", map[string]string{"Authorization": "Bearer s3cr3t"}, nil), http.StatusOK)
+	checkTypes := d["check_types"].(map[string]interface{})
+	if len(checkTypes) != 2 || checkTypes["node"] == nil || checkTypes["node-poststart"] == nil {
+		t.Fatalf("unexpected check types %v", checkTypes)
+	}
+}
@@ -0,0 +1,60 @@
+package api
+
+import (
+	"net/http"
+	"runtime"
+	"time"
+
+	"github.com/dcos/dcos-check-runner/config"
+)
+
+// health is the response of the /health endpoint.
+type health struct {
+	Healthy      bool       `json:"healthy"`
+	Error        string     `json:"error,omitempty"`
+	Role         string     `json:"role"`
+	ConfigLoaded *time.Time `json:"config_loaded,omitempty"`
+	ConfigHash   string     `json:"config_hash,omitempty"`
+}
+
+// version is the response of the /version endpoint.
+type version struct {
+	Version    string `json:"version"`
+	Commit     string `json:"commit,omitempty"`
+	GoVersion  string `json:"go_version"`
+	ConfigHash string `json:"config_hash,omitempty"`
+}
+
+// getHealth responds with the health of the check runner itself, which is healthy once its check configuration is
+// loaded and as long as its checks return in time, and the load time and SHA-256 hash of the configuration. It
+// responds with http.StatusServiceUnavailable if the check runner is not healthy.
+func (rh *runnerHandler) getHealth(w http.ResponseWriter, r *http.Request) {
+	h := health{
+		Healthy:    true,
+		Role:       rh.runner.Role(),
+		ConfigHash: rh.runner.ConfigHash(),
+	}
+	if err := rh.runner.Healthy(); err != nil {
+		h.Healthy = false
+		h.Error = err.Error()
+	}
+	if loaded := rh.runner.ConfigLoaded(); !loaded.IsZero() {
+		h.ConfigLoaded = &loaded
+	}
+
+	statusCode := http.StatusOK
+	if !h.Healthy {
+		statusCode = http.StatusServiceUnavailable
+	}
+	writeJSONResponseWithStatus(w, r, statusCode, h)
+}
+
+// getVersion responds with the version of the check runner and the SHA-256 hash of its check configuration.
+func (rh *runnerHandler) getVersion(w http.ResponseWriter, r *http.Request) {
+	writeJSONResponse(w, r, version{
+		Version:    config.Version,
+		Commit:     config.Commit,
+		GoVersion:  runtime.Version(),
+		ConfigHash: rh.runner.ConfigHash(),
+	})
+}
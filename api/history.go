@@ -0,0 +1,74 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/dcos/dcos-check-runner/auth"
+	"github.com/dcos/dcos-check-runner/history"
+	"github.com/pkg/errors"
+)
+
+// checkTypeHistory is the check type of the /history/ endpoint in auth policies.
+const checkTypeHistory = "history"
+
+// queryHistory responds with the recorded check results selected by the query parameters check, suite, since, until
+// and limit. since and until are RFC 3339 timestamps or durations before now, e.g. "2h".
+func (rh *runnerHandler) queryHistory(w http.ResponseWriter, r *http.Request) {
+	httpErr := rh.authorize(r, checkTypeHistory, auth.ActionList)
+	if httpErr != nil {
+		http.Error(w, httpErr.Error(), httpErr.statusCode)
+		return
+	}
+
+	q, httpErr := historyQueryFromParams(r)
+	if httpErr != nil {
+		http.Error(w, httpErr.Error(), httpErr.statusCode)
+		return
+	}
+
+	records, err := rh.history.Query(q)
+	if err != nil {
+		errMsg := "Error querying check history"
+		reqLogger(r).Error(errors.Wrap(err, errMsg))
+		http.Error(w, errMsg, http.StatusInternalServerError)
+		return
+	}
+
+	writeJSONResponse(w, r, records)
+}
+
+// historyQueryFromParams returns the history query from r's query parameters.
+func historyQueryFromParams(r *http.Request) (history.Query, *httpError) {
+	params := r.URL.Query()
+	q := history.Query{
+		Checks: checksFromQueryParams(r),
+		Suite:  params.Get("suite"),
+	}
+
+	now := time.Now()
+	if since := params.Get("since"); since != "" {
+		t, err := history.ParseTime(since, now)
+		if err != nil {
+			return q, &httpError{http.StatusBadRequest, fmt.Sprintf("invalid since: %s", err)}
+		}
+		q.Since = t
+	}
+	if until := params.Get("until"); until != "" {
+		t, err := history.ParseTime(until, now)
+		if err != nil {
+			return q, &httpError{http.StatusBadRequest, fmt.Sprintf("invalid until: %s", err)}
+		}
+		q.Until = t
+	}
+	if limit := params.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 0 {
+			return q, &httpError{http.StatusBadRequest, fmt.Sprintf("invalid limit: %s", limit)}
+		}
+		q.Limit = n
+	}
+	return q, nil
+}
@@ -0,0 +1,98 @@
+package api
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/dcos/dcos-check-runner/history"
+	"github.com/dcos/dcos-check-runner/runner"
+)
+
+func TestHistory(t *testing.T) {
+	dir, err := ioutil.TempDir("", "api-history-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	store, err := history.Open(dir, history.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	r, err := newTestRunner("master")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Observers = []runner.Observer{store}
+	s := httptest.NewServer(NewRouter(r, "", WithHistory(store)))
+	defer s.Close()
+
+	// Run node checks twice and cluster checks once to record results.
+	for _, resource := range []string{"/node/", "/node/", "/cluster/"} {
+		if resp := getResponse(t, "POST", s.URL+resource, nil, nil); resp.StatusCode != http.StatusOK {
+			t.Fatalf("expected status %d, got %d", http.StatusOK, resp.StatusCode)
+		}
+	}
+
+	query := func(t *testing.T, params string) []history.Record {
+		resp := getResponse(t, "GET", s.URL+"/history/"+params, nil, nil)
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("expected status %d, got %d", http.StatusOK, resp.StatusCode)
+		}
+		var records []history.Record
+		if err := json.NewDecoder(resp.Body).Decode(&records); err != nil {
+			t.Fatal(err)
+		}
+		return records
+	}
+
+	t.Run("by check", func(t *testing.T) {
+		records := query(t, "?check=node-check-master")
+		if len(records) != 2 {
+			t.Fatalf("expected 2 records, got %+v", records)
+		}
+		for _, r := range records {
+			if r.Check != "node-check-master" || r.Suite != runner.SuiteNodePostStart || r.Output == "" {
+				t.Fatalf("unexpected record %+v", r)
+			}
+		}
+	})
+
+	t.Run("by suite with limit", func(t *testing.T) {
+		records := query(t, "?suite=cluster&limit=1")
+		if len(records) != 1 || records[0].Suite != runner.SuiteCluster {
+			t.Fatalf("expected 1 cluster record, got %+v", records)
+		}
+	})
+
+	t.Run("since", func(t *testing.T) {
+		if records := query(t, "?since=1h"); len(records) == 0 {
+			t.Fatal("expected records within the last hour")
+		}
+		if records := query(t, "?since=2999-01-01T00:00:00Z"); records == nil || len(records) != 0 {
+			t.Fatalf("expected an empty list, got %+v", records)
+		}
+	})
+
+	t.Run("invalid parameters", func(t *testing.T) {
+		for _, params := range []string{"?since=yesterday", "?until=x", "?limit=-1"} {
+			if resp := getResponse(t, "GET", s.URL+"/history/"+params, nil, nil); resp.StatusCode != http.StatusBadRequest {
+				t.Fatalf("expected status %d for %s, got %d", http.StatusBadRequest, params, resp.StatusCode)
+			}
+		}
+	})
+
+	t.Run("history is disabled by default", func(t *testing.T) {
+		s := httptest.NewServer(NewRouter(r, ""))
+		defer s.Close()
+		if resp := getResponse(t, "GET", s.URL+"/history/", nil, nil); resp.StatusCode != http.StatusNotFound {
+			t.Fatalf("expected status %d, got %d", http.StatusNotFound, resp.StatusCode)
+		}
+	})
+}
@@ -0,0 +1,14 @@
+package api
+
+import (
+	"io"
+	"net/http"
+)
+
+//go:generate go run openapi_gen.go
+
+// getOpenAPISpec responds with the OpenAPI specification of the API in openapi.yaml.
+func (rh *runnerHandler) getOpenAPISpec(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/yaml")
+	io.WriteString(w, openAPISpec)
+}
@@ -0,0 +1,35 @@
+//go:build ignore
+// +build ignore
+
+// openapi_gen.go generates openapi_spec.go, which holds the OpenAPI specification in openapi.yaml served by the API.
+// Run it with go generate after changing openapi.yaml.
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"log"
+)
+
+func main() {
+	spec, err := ioutil.ReadFile("openapi.yaml")
+	if err != nil {
+		log.Fatal(err)
+	}
+	if bytes.ContainsRune(spec, '`') {
+		log.Fatal("openapi.yaml must not contain backquotes")
+	}
+
+	var b bytes.Buffer
+	fmt.Fprintln(&b, "// Code generated by openapi_gen.go from openapi.yaml. DO NOT EDIT.")
+	fmt.Fprintln(&b)
+	fmt.Fprintln(&b, "package api")
+	fmt.Fprintln(&b)
+	fmt.Fprintln(&b, "// openAPISpec is the OpenAPI specification of the API.")
+	fmt.Fprintf(&b, "const openAPISpec = `%s`\n", spec)
+
+	if err := ioutil.WriteFile("openapi_spec.go", b.Bytes(), 0644); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,519 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"mime"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dcos/dcos-check-runner/aggregate"
+	"github.com/dcos/dcos-check-runner/auth"
+	"github.com/dcos/dcos-check-runner/history"
+	"github.com/dcos/dcos-check-runner/runner"
+	"github.com/gorilla/mux"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// openAPIDoc is a parsed OpenAPI specification which validates responses against the schemas it defines. It supports
+// the subset of schemas used in openapi.yaml.
+type openAPIDoc struct {
+	root map[string]interface{}
+}
+
+func loadOpenAPIDoc(t *testing.T) *openAPIDoc {
+	var v interface{}
+	if err := yaml.Unmarshal([]byte(openAPISpec), &v); err != nil {
+		t.Fatal(err)
+	}
+	root, ok := jsonValue(v).(map[string]interface{})
+	if !ok {
+		t.Fatal("the OpenAPI specification is not an object")
+	}
+	return &openAPIDoc{root: root}
+}
+
+// jsonValue converts a value decoded from YAML to the types it would have when decoded from JSON.
+func jsonValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for key, value := range v {
+			m[fmt.Sprint(key)] = jsonValue(value)
+		}
+		return m
+	case []interface{}:
+		s := make([]interface{}, len(v))
+		for i, value := range v {
+			s[i] = jsonValue(value)
+		}
+		return s
+	case int:
+		return float64(v)
+	}
+	return v
+}
+
+// resolve returns the object v, following its reference if it is a reference object.
+func (d *openAPIDoc) resolve(v interface{}) (map[string]interface{}, error) {
+	obj, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("expected an object, got %v", v)
+	}
+	ref, ok := obj["$ref"].(string)
+	if !ok {
+		return obj, nil
+	}
+	if !strings.HasPrefix(ref, "#/") {
+		return nil, fmt.Errorf("unsupported reference %s", ref)
+	}
+
+	var target interface{} = d.root
+	for _, name := range strings.Split(strings.TrimPrefix(ref, "#/"), "/") {
+		m, ok := target.(map[string]interface{})
+		if !ok || m[name] == nil {
+			return nil, fmt.Errorf("unresolved reference %s", ref)
+		}
+		target = m[name]
+	}
+	return d.resolve(target)
+}
+
+// object returns the object at the given keys below obj.
+func object(obj map[string]interface{}, keys ...string) map[string]interface{} {
+	for _, key := range keys {
+		obj, _ = obj[key].(map[string]interface{})
+	}
+	return obj
+}
+
+// validateResponse validates resp against the response documented for the method of the path template.
+func (d *openAPIDoc) validateResponse(template, method string, resp *http.Response) error {
+	op := object(d.root, "paths", template, strings.ToLower(method))
+	if op == nil {
+		return fmt.Errorf("%s %s is not documented", method, template)
+	}
+	documented, ok := object(op, "responses")[strconv.Itoa(resp.StatusCode)]
+	if !ok {
+		return fmt.Errorf("status %d of %s %s is not documented", resp.StatusCode, method, template)
+	}
+	response, err := d.resolve(documented)
+	if err != nil {
+		return err
+	}
+
+	ct, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if err != nil {
+		return err
+	}
+	media := object(response, "content", ct)
+	if media == nil {
+		return fmt.Errorf("Content-Type %s of status %d of %s %s is not documented", ct, resp.StatusCode, method, template)
+	}
+	if ct != "application/json" {
+		return nil
+	}
+
+	var body interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return err
+	}
+	return d.validate(media["schema"], body, "body")
+}
+
+// validate returns an error if v doesn't match schema. at is the location of v used in errors.
+func (d *openAPIDoc) validate(schemaVal, v interface{}, at string) error {
+	schema, err := d.resolve(schemaVal)
+	if err != nil {
+		return err
+	}
+
+	if oneOf, ok := schema["oneOf"].([]interface{}); ok {
+		var (
+			matches int
+			errs    []string
+		)
+		for _, s := range oneOf {
+			if err := d.validate(s, v, at); err != nil {
+				errs = append(errs, err.Error())
+				continue
+			}
+			matches++
+		}
+		if matches != 1 {
+			return fmt.Errorf("%s matches %d schemas of oneOf: %s", at, matches, strings.Join(errs, "; "))
+		}
+		return nil
+	}
+
+	if v == nil {
+		if schema["nullable"] == true {
+			return nil
+		}
+		return fmt.Errorf("%s is null", at)
+	}
+
+	switch schema["type"] {
+	case "object":
+		obj, ok := v.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("%s is not an object: %v", at, v)
+		}
+		required, _ := schema["required"].([]interface{})
+		for _, name := range required {
+			if _, ok := obj[name.(string)]; !ok {
+				return fmt.Errorf("%s lacks the required property %s", at, name)
+			}
+		}
+		properties := object(schema, "properties")
+		for name, value := range obj {
+			if s, ok := properties[name]; ok {
+				if err := d.validate(s, value, at+"."+name); err != nil {
+					return err
+				}
+				continue
+			}
+			switch additional := schema["additionalProperties"].(type) {
+			case bool:
+				if !additional {
+					return fmt.Errorf("%s has the unexpected property %s", at, name)
+				}
+			case map[string]interface{}:
+				if err := d.validate(additional, value, at+"."+name); err != nil {
+					return err
+				}
+			}
+		}
+	case "array":
+		items, ok := v.([]interface{})
+		if !ok {
+			return fmt.Errorf("%s is not an array: %v", at, v)
+		}
+		if schema["items"] != nil {
+			for i, item := range items {
+				if err := d.validate(schema["items"], item, fmt.Sprintf("%s[%d]", at, i)); err != nil {
+					return err
+				}
+			}
+		}
+	case "string":
+		s, ok := v.(string)
+		if !ok {
+			return fmt.Errorf("%s is not a string: %v", at, v)
+		}
+		if schema["format"] == "date-time" {
+			if _, err := time.Parse(time.RFC3339Nano, s); err != nil {
+				return fmt.Errorf("%s is not a date-time: %s", at, err)
+			}
+		}
+	case "integer":
+		n, ok := v.(float64)
+		if !ok || n != math.Trunc(n) {
+			return fmt.Errorf("%s is not an integer: %v", at, v)
+		}
+		if min, ok := schema["minimum"].(float64); ok && n < min {
+			return fmt.Errorf("%s is less than %v: %v", at, min, n)
+		}
+		if max, ok := schema["maximum"].(float64); ok && n > max {
+			return fmt.Errorf("%s is greater than %v: %v", at, max, n)
+		}
+	case "boolean":
+		if _, ok := v.(bool); !ok {
+			return fmt.Errorf("%s is not a boolean: %v", at, v)
+		}
+	default:
+		return fmt.Errorf("unsupported schema type %v at %s", schema["type"], at)
+	}
+
+	if enum, ok := schema["enum"].([]interface{}); ok {
+		for _, e := range enum {
+			if reflect.DeepEqual(e, v) {
+				return nil
+			}
+		}
+		return fmt.Errorf("%s is not one of %v: %v", at, enum, v)
+	}
+	return nil
+}
+
+// newOpenAPITestRouter returns a router with all optional endpoints enabled. The aggregator checks a node served by
+// newTestServer(). The returned function cleans up the router's resources.
+func newOpenAPITestRouter(t *testing.T) (*mux.Router, func()) {
+	dir, err := ioutil.TempDir("", "api-openapi-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	store, err := history.Open(dir, history.Options{})
+	if err != nil {
+		os.RemoveAll(dir)
+		t.Fatal(err)
+	}
+	node, err := newTestServer("agent", "")
+	if err != nil {
+		store.Close()
+		os.RemoveAll(dir)
+		t.Fatal(err)
+	}
+	cleanup := func() {
+		node.Close()
+		store.Close()
+		os.RemoveAll(dir)
+	}
+
+	a := aggregate.NewAggregator(aggregate.StaticSource{{Host: strings.TrimPrefix(node.URL, "http://"), Role: "agent"}})
+	a.NodeURL = "http://{host}"
+
+	r, err := newTestRunner("master")
+	if err != nil {
+		cleanup()
+		t.Fatal(err)
+	}
+	r.Observers = []runner.Observer{store}
+	probes := map[string]Probe{
+		"ready":   {CheckType: "cluster"},
+		"invalid": {CheckType: "node", Checks: []string{"nonexistent"}},
+	}
+	return NewRouter(r, "", WithHistory(store), WithAggregator(a), WithProbes(probes)), cleanup
+}
+
+func TestOpenAPISpecGenerated(t *testing.T) {
+	spec, err := ioutil.ReadFile("openapi.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(spec) != openAPISpec {
+		t.Fatal("openapi_spec.go is out of date, run go generate")
+	}
+}
+
+func TestOpenAPISpecServed(t *testing.T) {
+	s, err := newTestServer("master", "/system/checks")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	resp := getResponse(t, "GET", s.URL+"/system/checks/openapi.yaml", nil, nil)
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, resp.StatusCode)
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != "application/yaml" {
+		t.Fatalf("unexpected Content-Type %s", ct)
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(body) != openAPISpec {
+		t.Fatal("expected the OpenAPI specification")
+	}
+}
+
+func TestOpenAPIReferences(t *testing.T) {
+	d := loadOpenAPIDoc(t)
+
+	var walk func(v interface{})
+	walk = func(v interface{}) {
+		switch v := v.(type) {
+		case map[string]interface{}:
+			if _, ok := v["$ref"]; ok {
+				if _, err := d.resolve(v); err != nil {
+					t.Error(err)
+				}
+				return
+			}
+			for _, value := range v {
+				walk(value)
+			}
+		case []interface{}:
+			for _, value := range v {
+				walk(value)
+			}
+		}
+	}
+	walk(d.root)
+}
+
+func TestOpenAPIRoutes(t *testing.T) {
+	d := loadOpenAPIDoc(t)
+	router, cleanup := newOpenAPITestRouter(t)
+	defer cleanup()
+
+	var registered []string
+	err := router.Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+		methods, err := route.GetMethods()
+		if err != nil {
+			// The route of the base URI's subrouter has no methods.
+			return nil
+		}
+		template, err := route.GetPathTemplate()
+		if err != nil {
+			return err
+		}
+		for _, method := range methods {
+			registered = append(registered, strings.ToLower(method)+" "+template)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var documented []string
+	for template, item := range object(d.root, "paths") {
+		for method := range item.(map[string]interface{}) {
+			if method != "parameters" {
+				documented = append(documented, method+" "+template)
+			}
+		}
+	}
+
+	sort.Strings(registered)
+	sort.Strings(documented)
+	if !reflect.DeepEqual(registered, documented) {
+		t.Fatalf("expected the documented routes %v, got %v", documented, registered)
+	}
+}
+
+func TestOpenAPIResponses(t *testing.T) {
+	d := loadOpenAPIDoc(t)
+	router, cleanup := newOpenAPITestRouter(t)
+	defer cleanup()
+	s := httptest.NewServer(router)
+	defer s.Close()
+
+	jsonHeaders := map[string]string{"Content-Type": "application/json"}
+	formHeaders := map[string]string{"Content-Type": "application/x-www-form-urlencoded"}
+
+	// validate sends a request, fails the test unless it is answered with statusCode and the response validates against
+	// the specification, and returns the response.
+	validate := func(t *testing.T, method, path, template string, headers map[string]string, body string, statusCode int) *http.Response {
+		var reqBody io.Reader
+		if body != "" {
+			reqBody = strings.NewReader(body)
+		}
+		resp := getResponse(t, method, s.URL+path, headers, reqBody)
+		defer resp.Body.Close()
+
+		if resp.StatusCode != statusCode {
+			t.Fatalf("expected status %d, got %d", statusCode, resp.StatusCode)
+		}
+		if err := d.validateResponse(template, method, resp); err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	for _, tt := range []struct {
+		name       string
+		method     string
+		path       string
+		template   string
+		headers    map[string]string
+		body       string
+		statusCode int
+	}{
+		{"discovery", "GET", "/", "/", nil, "", http.StatusOK},
+		{"specification", "GET", "/openapi.yaml", "/openapi.yaml", nil, "", http.StatusOK},
+		{"health", "GET", "/health", "/health", nil, "", http.StatusOK},
+		{"version", "GET", "/version", "/version", nil, "", http.StatusOK},
+		{"passing probe", "GET", "/probe/ready", "/probe/{name}", nil, "", http.StatusOK},
+		{"invalid probe", "GET", "/probe/invalid", "/probe/{name}", nil, "", http.StatusServiceUnavailable},
+		{"missing probe", "GET", "/probe/nonexistent", "/probe/{name}", nil, "", http.StatusNotFound},
+		{"list checks", "GET", "/node/", "/{check_type}/", nil, "", http.StatusOK},
+		{"list selected checks", "GET", "/cluster/?check=cluster-check-1", "/{check_type}/", nil, "", http.StatusOK},
+		{"list missing checks", "GET", "/cluster/?check=nonexistent", "/{check_type}/", nil, "", http.StatusNotFound},
+		{"list checks of another role", "GET", "/node/?check=node-check-agent", "/{check_type}/", nil, "", http.StatusOK},
+		{"list unknown check type", "GET", "/nonexistent/", "/{check_type}/", nil, "", http.StatusNotFound},
+		{"run checks", "POST", "/node-poststart/", "/{check_type}/", nil, "", http.StatusOK},
+		{"run checks with JSON body", "POST", "/cluster/", "/{check_type}/", jsonHeaders, `{"check": ["cluster-check-2"]}`, http.StatusOK},
+		{"run checks with form body", "POST", "/node-prestart/", "/{check_type}/", formHeaders, "check=node-check-prestart", http.StatusOK},
+		{"run checks of another role", "POST", "/node/", "/{check_type}/", jsonHeaders, `{"check": ["node-check-agent"]}`, http.StatusOK},
+		{"run checks with invalid body", "POST", "/node/", "/{check_type}/", jsonHeaders, `{"check": "node-check"}`, http.StatusBadRequest},
+		{"run checks with unsupported body", "POST", "/node/", "/{check_type}/", map[string]string{"Content-Type": "text/plain"}, "node-check", http.StatusUnsupportedMediaType},
+		{"stream checks", "GET", "/cluster/stream", "/{check_type}/stream", nil, "", http.StatusOK},
+		{"get check", "GET", "/cluster/cluster-check-1", "/{check_type}/{check_name}", nil, "", http.StatusOK},
+		{"get missing check", "GET", "/cluster/nonexistent", "/{check_type}/{check_name}", nil, "", http.StatusNotFound},
+		{"run check", "POST", "/node/node-check", "/{check_type}/{check_name}", nil, "", http.StatusOK},
+		{"run check of another role", "POST", "/node/node-check-agent", "/{check_type}/{check_name}", nil, "", http.StatusNotFound},
+		{"invalid run", "POST", "/runs/", "/runs/", jsonHeaders, `{"check": ["node-check"]}`, http.StatusBadRequest},
+		{"missing run", "GET", "/runs/nonexistent", "/runs/{id}", nil, "", http.StatusNotFound},
+		{"history", "GET", "/history/", "/history/", nil, "", http.StatusOK},
+		{"history with invalid limit", "GET", "/history/?limit=-1", "/history/", nil, "", http.StatusBadRequest},
+		{"list nodes", "GET", "/aggregate/", "/aggregate/", nil, "", http.StatusOK},
+		{"run node checks on all nodes", "POST", "/aggregate/", "/aggregate/", jsonHeaders, `{"check": ["node-check"]}`, http.StatusOK},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			validate(t, tt.method, tt.path, tt.template, tt.headers, tt.body, tt.statusCode)
+		})
+	}
+
+	t.Run("runs", func(t *testing.T) {
+		resp := validate(t, "POST", "/runs/", "/runs/", jsonHeaders, `{"check_type": "cluster"}`, http.StatusAccepted)
+		location := resp.Header.Get("Location")
+		waitForRun(t, s.URL+location)
+
+		validate(t, "GET", location, "/runs/{id}", nil, "", http.StatusOK)
+		validate(t, "DELETE", location, "/runs/{id}", nil, "", http.StatusConflict)
+	})
+
+	t.Run("stream events", func(t *testing.T) {
+		schemas := map[string]string{
+			"output": "StreamOutput",
+			"result": "StreamResult",
+			"done":   "StreamDone",
+			"error":  "StreamError",
+		}
+		events := readEvents(t, getResponse(t, "GET", s.URL+"/node/stream", nil, nil))
+		for _, e := range events {
+			schema := map[string]interface{}{"$ref": "#/components/schemas/" + schemas[e.event]}
+			var data interface{} = e.data
+			if err := d.validate(schema, data, e.event); err != nil {
+				t.Fatal(err)
+			}
+		}
+	})
+}
+
+func TestOpenAPIAuthResponses(t *testing.T) {
+	d := loadOpenAPIDoc(t)
+	r, err := newTestRunner("master")
+	if err != nil {
+		t.Fatal(err)
+	}
+	authenticator := auth.NewTokenAuthenticator(map[string]string{"s3cr3t": "prometheus"})
+	policy := auth.Policy{"prometheus": {"cluster": {auth.ActionList}}}
+	s := httptest.NewServer(NewRouter(r, "", WithAuth(authenticator, policy)))
+	defer s.Close()
+
+	for _, tt := range []struct {
+		name       string
+		headers    map[string]string
+		statusCode int
+	}{
+		{"unauthenticated", nil, http.StatusUnauthorized},
+		{"unauthorized", map[string]string{"Authorization": "Bearer s3cr3t"}, http.StatusForbidden},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := getResponse(t, "POST", s.URL+"/cluster/", tt.headers, nil)
+			defer resp.Body.Close()
+			if resp.StatusCode != tt.statusCode {
+				t.Fatalf("expected status %d, got %d", tt.statusCode, resp.StatusCode)
+			}
+			if err := d.validateResponse("/{check_type}/", "POST", resp); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
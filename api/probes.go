@@ -0,0 +1,201 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dcos/dcos-check-runner/output"
+	"github.com/dcos/dcos-check-runner/runner"
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+)
+
+const (
+	// DefaultProbeThreshold is the default combined status at or above which a probe fails, CRITICAL.
+	DefaultProbeThreshold = 2
+
+	// DefaultProbeCacheTTL is the default duration for which the result of a probe is reused.
+	DefaultProbeCacheTTL = 5 * time.Second
+)
+
+// Probe configures a probe endpoint, which runs checks and responds with 200 OK or 503 Service Unavailable depending
+// on their combined status, for orchestrators' liveness and readiness probes.
+type Probe struct {
+	// CheckType is the check type of the checks to run.
+	CheckType string
+
+	// Checks are the names of the checks to run. All checks of CheckType are run if it is empty.
+	Checks []string
+
+	// Threshold is the combined status at or above which the probe fails. It defaults to DefaultProbeThreshold.
+	// Checks which could not be found or executed count as UNKNOWN.
+	Threshold int
+
+	// CacheTTL is the duration for which a result is reused, so that frequent probes don't run the checks each time.
+	// It defaults to DefaultProbeCacheTTL.
+	CacheTTL time.Duration
+}
+
+// LoadProbes reads probes from a JSON file mapping probe names to probes, like
+// {"ready": {"check_type": "node-poststart", "checks": ["..."], "threshold": 2, "cache_ttl": "5s"}}.
+func LoadProbes(path string) (map[string]Probe, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to open probe config file")
+	}
+	defer f.Close()
+
+	var config map[string]struct {
+		CheckType string   `json:"check_type"`
+		Checks    []string `json:"checks"`
+		Threshold *int     `json:"threshold"`
+		CacheTTL  string   `json:"cache_ttl"`
+	}
+	if err := json.NewDecoder(f).Decode(&config); err != nil {
+		return nil, errors.Wrap(err, "unable to decode probe config file")
+	}
+
+	probes := make(map[string]Probe, len(config))
+	for name, c := range config {
+		if _, ok := suites[c.CheckType]; !ok {
+			return nil, errors.Errorf("invalid check type %q of probe %s", c.CheckType, name)
+		}
+		p := Probe{CheckType: c.CheckType, Checks: c.Checks}
+		if c.Threshold != nil {
+			if *c.Threshold < 1 || *c.Threshold > 3 {
+				return nil, errors.Errorf("invalid threshold %d of probe %s, must be between 1 and 3", *c.Threshold, name)
+			}
+			p.Threshold = *c.Threshold
+		}
+		if c.CacheTTL != "" {
+			p.CacheTTL, err = time.ParseDuration(c.CacheTTL)
+			if err != nil {
+				return nil, errors.Wrapf(err, "invalid cache TTL of probe %s", name)
+			}
+		}
+		probes[name] = p
+	}
+	return probes, nil
+}
+
+// WithProbes enables the /probe/{name} endpoints for probes. Like /health and /version, they don't require
+// authentication, as orchestrators usually can't authenticate their probes.
+func WithProbes(probes map[string]Probe) Option {
+	return func(rh *runnerHandler) {
+		rh.probes = make(map[string]*probe, len(probes))
+		for name, p := range probes {
+			if p.Threshold == 0 {
+				p.Threshold = DefaultProbeThreshold
+			}
+			if p.CacheTTL == 0 {
+				p.CacheTTL = DefaultProbeCacheTTL
+			}
+			rh.probes[name] = &probe{Probe: p, now: time.Now}
+		}
+	}
+}
+
+// probe is a Probe with its cached result.
+type probe struct {
+	Probe
+
+	// now returns the current time. It is replaced in tests.
+	now func() time.Time
+
+	// mu is held while the checks run, so that concurrent probes wait for and share the result.
+	mu     sync.Mutex
+	result *probeResult
+}
+
+// probeResult is the result of running the checks of a probe.
+type probeResult struct {
+	time   time.Time
+	status int
+
+	// body lists the combined status and the checks which are not OK.
+	body string
+}
+
+// run returns the cached result of p, or runs its checks with checkFunc if it expired.
+func (p *probe) run(ctx context.Context, checkFunc func(context.Context, bool, ...string) (*runner.CombinedResponse, error)) (*probeResult, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := p.now()
+	if p.result != nil && now.Sub(p.result.time) < p.CacheTTL {
+		return p.result, nil
+	}
+
+	rs, err := checkFunc(ctx, false, p.Checks...)
+	if err != nil {
+		return nil, err
+	}
+
+	var lines []string
+	status := rs.Status()
+	for _, c := range rs.Checks() {
+		if c.Status() != 0 {
+			lines = append(lines, fmt.Sprintf("%s: %s", c.Name(), output.StatusName(c.Status())))
+		}
+	}
+	for name, e := range rs.Errors() {
+		status = 3
+		lines = append(lines, fmt.Sprintf("%s: %s", name, e))
+	}
+	sort.Strings(lines)
+
+	p.result = &probeResult{
+		time:   now,
+		status: status,
+		body:   strings.Join(append([]string{output.StatusName(status)}, lines...), "\n") + "\n",
+	}
+	return p.result, nil
+}
+
+// runProbe runs the checks of the probe given by the name variable in the URI, or reuses their cached result, and
+// responds with http.StatusServiceUnavailable if their combined status reaches the probe's threshold.
+func (rh *runnerHandler) runProbe(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+	p, ok := rh.probes[name]
+	if !ok {
+		http.Error(w, fmt.Sprintf("unknown probe: %s", name), http.StatusNotFound)
+		return
+	}
+
+	checkFunc, httpErr := rh.getCheckFuncFromReq(p.CheckType)
+	if httpErr != nil {
+		http.Error(w, httpErr.Error(), httpErr.statusCode)
+		return
+	}
+
+	// Misconfigured probes fail instead of passing without running their checks.
+	httpErr = rh.verifySelectedChecks(p.CheckType, p.Checks)
+	if httpErr != nil {
+		reqLogger(r).Errorf("Invalid probe %s: %s", name, httpErr)
+		http.Error(w, httpErr.Error(), http.StatusServiceUnavailable)
+		return
+	}
+
+	result, err := p.run(r.Context(), checkFunc)
+	if err != nil {
+		errMsg := "Error running checks"
+		reqLogger(r).Error(errors.Wrapf(err, "%s of probe %s", errMsg, name))
+		http.Error(w, errMsg, http.StatusServiceUnavailable)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-cache")
+	if result.status >= p.Threshold {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	io.WriteString(w, result.body)
+}
@@ -0,0 +1,229 @@
+package api
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/dcos/dcos-check-runner/auth"
+	"github.com/dcos/dcos-check-runner/config"
+	"github.com/dcos/dcos-check-runner/runner"
+)
+
+// readBody returns the body of resp, failing the test if the status code is not statusCode.
+func readBody(t *testing.T, resp *http.Response, statusCode int) string {
+	defer resp.Body.Close()
+
+	if resp.StatusCode != statusCode {
+		t.Fatalf("expected status %d, got %d", statusCode, resp.StatusCode)
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(body)
+}
+
+func TestProbes(t *testing.T) {
+	r, err := newTestRunner("master")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The test checks echo their name, which maps to their exit code.
+	statuses := map[string]int{"cluster-check-1": 0, "cluster-check-2": 1}
+	r.Executor = runner.ExecutorFunc(func(ctx context.Context, cmd []string) ([]byte, []byte, int, error) {
+		return []byte(cmd[1]), nil, statuses[cmd[1]], nil
+	})
+
+	probes := map[string]Probe{
+		"ready":  {CheckType: "cluster", CacheTTL: time.Nanosecond},
+		"strict": {CheckType: "cluster", Threshold: 1, CacheTTL: time.Nanosecond},
+		"single": {CheckType: "cluster", Checks: []string{"cluster-check-1"}, CacheTTL: time.Nanosecond},
+	}
+	s := httptest.NewServer(NewRouter(r, "", WithProbes(probes)))
+	defer s.Close()
+
+	probe := func(t *testing.T, name string, statusCode int) string {
+		return readBody(t, getResponse(t, "GET", s.URL+"/probe/"+name, nil, nil), statusCode)
+	}
+
+	t.Run("below threshold", func(t *testing.T) {
+		if body := probe(t, "ready", http.StatusOK); body != "WARNING\ncluster-check-2: WARNING\n" {
+			t.Fatalf("unexpected body %q", body)
+		}
+	})
+
+	t.Run("at threshold", func(t *testing.T) {
+		if body := probe(t, "strict", http.StatusServiceUnavailable); body != "WARNING\ncluster-check-2: WARNING\n" {
+			t.Fatalf("unexpected body %q", body)
+		}
+	})
+
+	t.Run("selected checks", func(t *testing.T) {
+		if body := probe(t, "single", http.StatusOK); body != "OK\n" {
+			t.Fatalf("unexpected body %q", body)
+		}
+	})
+
+	t.Run("above threshold", func(t *testing.T) {
+		statuses["cluster-check-2"] = 2
+		defer func() { statuses["cluster-check-2"] = 1 }()
+		if body := probe(t, "ready", http.StatusServiceUnavailable); body != "CRITICAL\ncluster-check-2: CRITICAL\n" {
+			t.Fatalf("unexpected body %q", body)
+		}
+	})
+
+	t.Run("unknown probe", func(t *testing.T) {
+		probe(t, "nonexistent", http.StatusNotFound)
+	})
+}
+
+func TestProbeCache(t *testing.T) {
+	now := time.Now()
+	p := &probe{
+		Probe: Probe{CheckType: "cluster", Threshold: 2, CacheTTL: time.Minute},
+		now:   func() time.Time { return now },
+	}
+
+	r, err := newTestRunner("master")
+	if err != nil {
+		t.Fatal(err)
+	}
+	runs := 0
+	checkFunc := func(ctx context.Context, list bool, checks ...string) (*runner.CombinedResponse, error) {
+		runs++
+		return r.Cluster(ctx, list, checks...)
+	}
+
+	for i := 0; i < 3; i++ {
+		if _, err := p.run(context.Background(), checkFunc); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if runs != 1 {
+		t.Fatalf("expected the checks to run once within the cache TTL, ran %d times", runs)
+	}
+
+	now = now.Add(time.Minute)
+	if _, err := p.run(context.Background(), checkFunc); err != nil {
+		t.Fatal(err)
+	}
+	if runs != 2 {
+		t.Fatalf("expected the checks to run again after the cache TTL, ran %d times", runs)
+	}
+
+	// Failed runs are not cached.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	now = now.Add(time.Minute)
+	if _, err := p.run(ctx, checkFunc); err == nil {
+		t.Fatal("expected a canceled run to fail")
+	}
+	if _, err := p.run(context.Background(), checkFunc); err != nil {
+		t.Fatal(err)
+	}
+	if runs != 4 {
+		t.Fatalf("expected the checks to run again after a failed run, ran %d times", runs)
+	}
+}
+
+func TestLoadProbes(t *testing.T) {
+	dir, err := ioutil.TempDir("", "api-probes-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	load := func(config string) (map[string]Probe, error) {
+		path := filepath.Join(dir, "probes.json")
+		if err := ioutil.WriteFile(path, []byte(config), 0600); err != nil {
+			t.Fatal(err)
+		}
+		return LoadProbes(path)
+	}
+
+	probes, err := load(`{
+		"ready": {"check_type": "node-poststart", "checks": ["node-check"], "threshold": 1, "cache_ttl": "10s"},
+		"live": {"check_type": "node-prestart"}
+	}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]Probe{
+		"ready": {CheckType: "node-poststart", Checks: []string{"node-check"}, Threshold: 1, CacheTTL: 10 * time.Second},
+		"live":  {CheckType: "node-prestart"},
+	}
+	if !reflect.DeepEqual(probes, expected) {
+		t.Fatalf("expected %+v, got %+v", expected, probes)
+	}
+
+	for _, config := range []string{
+		`{"ready": {"check_type": "nonexistent"}}`,
+		`{"ready": {"check_type": "node", "threshold": 0}}`,
+		`{"ready": {"check_type": "node", "threshold": 4}}`,
+		`{"ready": {"check_type": "node", "cache_ttl": "soon"}}`,
+		`[]`,
+	} {
+		if _, err := load(config); err == nil {
+			t.Fatalf("expected an error for %s", config)
+		}
+	}
+}
+
+func TestHealthAndVersion(t *testing.T) {
+	r, err := newTestRunner("master")
+	if err != nil {
+		t.Fatal(err)
+	}
+	authenticator := auth.NewTokenAuthenticator(map[string]string{"s3cr3t": "admin"})
+	probes := map[string]Probe{"ready": {CheckType: "cluster"}}
+	s := httptest.NewServer(NewRouter(r, "", WithAuth(authenticator, nil), WithProbes(probes)))
+	defer s.Close()
+
+	// The endpoints don't require authentication.
+	health := decodeJSON(t, getResponse(t, "GET", s.URL+"/health", nil, nil), http.StatusOK)
+	if health["healthy"] != true || health["role"] != "master" || health["config_hash"] != r.ConfigHash() {
+		t.Fatalf("unexpected health %v", health)
+	}
+	loaded, err := time.Parse(time.RFC3339Nano, health["config_loaded"].(string))
+	if err != nil || !loaded.Equal(r.ConfigLoaded()) {
+		t.Fatalf("unexpected config load time %v", health["config_loaded"])
+	}
+
+	version := decodeJSON(t, getResponse(t, "GET", s.URL+"/version", nil, nil), http.StatusOK)
+	expected := map[string]interface{}{
+		"version":     config.Version,
+		"go_version":  runtime.Version(),
+		"config_hash": r.ConfigHash(),
+	}
+	if !reflect.DeepEqual(version, expected) {
+		t.Fatalf("expected %v, got %v", expected, version)
+	}
+
+	readBody(t, getResponse(t, "GET", s.URL+"/probe/ready", nil, nil), http.StatusOK)
+
+	// Other endpoints still do.
+	readBody(t, getResponse(t, "GET", s.URL+"/cluster/", nil, nil), http.StatusUnauthorized)
+}
+
+func TestUnhealthy(t *testing.T) {
+	r, err := runner.NewRunner("master")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := httptest.NewServer(NewRouter(r, ""))
+	defer s.Close()
+
+	health := decodeJSON(t, getResponse(t, "GET", s.URL+"/health", nil, nil), http.StatusServiceUnavailable)
+	expected := map[string]interface{}{"healthy": false, "error": "check configuration is not loaded", "role": "master"}
+	if !reflect.DeepEqual(health, expected) {
+		t.Fatalf("expected %v, got %v", expected, health)
+	}
+}
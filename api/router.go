@@ -7,26 +7,72 @@ import (
 	"mime"
 	"net/http"
 
+	"github.com/dcos/dcos-check-runner/aggregate"
+	"github.com/dcos/dcos-check-runner/auth"
+	"github.com/dcos/dcos-check-runner/history"
 	"github.com/dcos/dcos-check-runner/runner"
 	"github.com/gorilla/mux"
 	"github.com/pkg/errors"
 )
 
+// Option configures optional features of the API router.
+type Option func(*runnerHandler)
+
+// WithAggregator enables the /aggregate/ endpoint, which runs node checks on all nodes of the cluster.
+func WithAggregator(a *aggregate.Aggregator) Option {
+	return func(rh *runnerHandler) {
+		rh.aggregator = a
+	}
+}
+
+// WithHistory enables the /history/ endpoint, which queries the recorded check results in store.
+func WithHistory(store *history.Store) Option {
+	return func(rh *runnerHandler) {
+		rh.history = store
+	}
+}
+
 // NewRouter returns an API router for runner.
-func NewRouter(runner *runner.Runner, baseURI string) *mux.Router {
+func NewRouter(runner *runner.Runner, baseURI string, opts ...Option) *mux.Router {
 	router := mux.NewRouter().StrictSlash(true)
-	rh := runnerHandler{runner: runner}
+	rh := runnerHandler{runner: runner, runs: newRunStore()}
+	for _, opt := range opts {
+		opt(&rh)
+	}
 
 	base := router.PathPrefix(baseURI).Subrouter()
-	base.Handle("/{check_type}/", withMiddlewares(http.HandlerFunc(rh.listChecks))).Methods("GET")
-	base.Handle("/{check_type}/", withMiddlewares(http.HandlerFunc(rh.runChecks))).Methods("POST")
+	base.Handle("/", rh.withMiddlewares(http.HandlerFunc(rh.discover))).Methods("GET")
+	base.Handle("/openapi.yaml", rh.withMiddlewares(http.HandlerFunc(rh.getOpenAPISpec))).Methods("GET")
+	base.Handle("/health", rh.withPublicMiddlewares(http.HandlerFunc(rh.getHealth))).Methods("GET")
+	base.Handle("/version", rh.withPublicMiddlewares(http.HandlerFunc(rh.getVersion))).Methods("GET")
+	if rh.probes != nil {
+		base.Handle("/probe/{name}", rh.withPublicMiddlewares(http.HandlerFunc(rh.runProbe))).Methods("GET")
+	}
+	if rh.aggregator != nil {
+		base.Handle("/aggregate/", rh.withMiddlewares(http.HandlerFunc(rh.listNodes))).Methods("GET")
+		base.Handle("/aggregate/", rh.withMiddlewares(http.HandlerFunc(rh.runAggregate))).Methods("POST")
+	}
+	if rh.history != nil {
+		base.Handle("/history/", rh.withMiddlewares(http.HandlerFunc(rh.queryHistory))).Methods("GET")
+	}
+	base.Handle("/runs/", rh.withMiddlewares(http.HandlerFunc(rh.createRun))).Methods("POST")
+	base.Handle("/runs/{id}", rh.withMiddlewares(http.HandlerFunc(rh.getRun))).Methods("GET")
+	base.Handle("/runs/{id}", rh.withMiddlewares(http.HandlerFunc(rh.cancelRun))).Methods("DELETE")
+	base.Handle("/{check_type}/stream", rh.withMiddlewares(http.HandlerFunc(rh.streamChecks))).Methods("GET")
+	base.Handle("/{check_type}/", rh.withMiddlewares(http.HandlerFunc(rh.listChecks))).Methods("GET")
+	base.Handle("/{check_type}/", rh.withMiddlewares(http.HandlerFunc(rh.runChecks))).Methods("POST")
+	base.Handle("/{check_type}/{check_name}", rh.withMiddlewares(http.HandlerFunc(rh.getCheck))).Methods("GET")
+	base.Handle("/{check_type}/{check_name}", rh.withMiddlewares(http.HandlerFunc(rh.runCheck))).Methods("POST")
 
 	return router
 }
 
-func withMiddlewares(h http.Handler) http.Handler {
-	middlewares := [...]func(http.Handler) http.Handler{
+// withMiddlewares wraps h with the middlewares of the API. The request logger is added first, so that all other
+// middlewares can use it.
+func (rh *runnerHandler) withMiddlewares(h http.Handler) http.Handler {
+	middlewares := []func(http.Handler) http.Handler{
 		logRequestResponseMiddleware,
+		rh.authMiddleware,
 		loggerMiddleware,
 	}
 
@@ -36,8 +82,21 @@ func withMiddlewares(h http.Handler) http.Handler {
 	return h
 }
 
+// withPublicMiddlewares wraps h with the middlewares of the API except authentication, for endpoints which are served
+// to unauthenticated clients.
+func (rh *runnerHandler) withPublicMiddlewares(h http.Handler) http.Handler {
+	return loggerMiddleware(logRequestResponseMiddleware(h))
+}
+
 type runnerHandler struct {
-	runner *runner.Runner
+	runner     *runner.Runner
+	aggregator *aggregate.Aggregator
+	history    *history.Store
+	runs       *runStore
+	probes     map[string]*probe
+
+	authenticator auth.Authenticator
+	policy        auth.Policy
 }
 
 func (rh *runnerHandler) listChecks(w http.ResponseWriter, r *http.Request) {
@@ -47,6 +106,12 @@ func (rh *runnerHandler) listChecks(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	httpErr = rh.authorize(r, checkType, auth.ActionList)
+	if httpErr != nil {
+		http.Error(w, httpErr.Error(), httpErr.statusCode)
+		return
+	}
+
 	checkFunc, httpErr := rh.getCheckFuncFromReq(checkType)
 	if httpErr != nil {
 		http.Error(w, httpErr.Error(), httpErr.statusCode)
@@ -79,6 +144,12 @@ func (rh *runnerHandler) runChecks(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	httpErr = rh.authorize(r, checkType, auth.ActionRun)
+	if httpErr != nil {
+		http.Error(w, httpErr.Error(), httpErr.statusCode)
+		return
+	}
+
 	checkFunc, httpErr := rh.getCheckFuncFromReq(checkType)
 	if httpErr != nil {
 		http.Error(w, httpErr.Error(), httpErr.statusCode)
@@ -99,9 +170,8 @@ func (rh *runnerHandler) runChecks(w http.ResponseWriter, r *http.Request) {
 
 	rs, err := checkFunc(r.Context(), false, checks...)
 	if err != nil {
-		errMsg := "Error running checks"
-		reqLogger(r).Error(errors.Wrap(err, errMsg))
-		http.Error(w, errMsg, http.StatusInternalServerError)
+		httpErr = runError(r, err, "Error running checks")
+		http.Error(w, httpErr.Error(), httpErr.statusCode)
 		return
 	}
 
@@ -131,26 +201,41 @@ func (rh *runnerHandler) getCheckFuncFromReq(r *http.Request) (func(context.Cont
 }
 */
 
+// Check types of the API.
+const (
+	checkTypeNode          = "node"
+	checkTypeNodePreStart  = "node-prestart"
+	checkTypeNodePostStart = "node-poststart"
+	checkTypeCluster       = "cluster"
+)
+
+// suites maps the check types of the API to the runner's check suites. "node" predates the prestart checks of the API
+// and is kept as an alias of "node-poststart".
+var suites = map[string]string{
+	checkTypeNode:          runner.SuiteNodePostStart,
+	checkTypeNodePreStart:  runner.SuiteNodePreStart,
+	checkTypeNodePostStart: runner.SuiteNodePostStart,
+	checkTypeCluster:       runner.SuiteCluster,
+}
+
 func (rh *runnerHandler) getCheckFuncFromReq(checkType string) (func(context.Context, bool, ...string) (*runner.CombinedResponse, error), *httpError) {
-	switch checkType {
-	case "node":
+	switch suites[checkType] {
+	case runner.SuiteNodePreStart:
+		return rh.runner.PreStart, nil
+	case runner.SuiteNodePostStart:
 		return rh.runner.PostStart, nil
-	case "cluster":
+	case runner.SuiteCluster:
 		return rh.runner.Cluster, nil
 	}
 	return nil, &httpError{http.StatusNotFound, fmt.Sprintf("unrecognized check type: %s", checkType)}
 }
 
 func (rh *runnerHandler) verifySelectedChecks(checkType string, selectedChecks []string) *httpError {
-	var checksMap map[string]*runner.Check
-	switch checkType {
-	case "node":
-		checksMap = rh.runner.NodeChecks.Checks
-	case "cluster":
-		checksMap = rh.runner.ClusterChecks
-	default:
+	suite, ok := suites[checkType]
+	if !ok {
 		return &httpError{http.StatusNotFound, fmt.Sprintf("unrecognized check type: %s", checkType)}
 	}
+	checksMap := rh.runner.Checks(suite)
 
 	missingChecks := []string{}
 	for _, c := range selectedChecks {
@@ -172,14 +257,10 @@ func verifyCheckType(r *http.Request) (string, *httpError) {
 		return "", &httpError{http.StatusInternalServerError, ""}
 	}
 
-	switch checkType {
-	case "node":
-		return checkType, nil
-	case "cluster":
-		return checkType, nil
-	default:
+	if _, ok := suites[checkType]; !ok {
 		return "", &httpError{http.StatusNotFound, fmt.Sprintf("unrecognized check type: %s", checkType)}
 	}
+	return checkType, nil
 }
 
 // checksFromBody returns a slice of the check names from r's body.
@@ -272,6 +353,11 @@ func checksFromQueryParams(r *http.Request) []string {
 
 // writeJSONResponse writes the JSON encoding of bodyObj to w.
 func writeJSONResponse(w http.ResponseWriter, r *http.Request, bodyObj interface{}) {
+	writeJSONResponseWithStatus(w, r, http.StatusOK, bodyObj)
+}
+
+// writeJSONResponseWithStatus writes the JSON encoding of bodyObj to w with the given status code.
+func writeJSONResponseWithStatus(w http.ResponseWriter, r *http.Request, statusCode int, bodyObj interface{}) {
 	body, err := json.Marshal(bodyObj)
 	if err != nil {
 		reqLogger(r).Error(errors.Wrap(err, "failed to serialize JSON response"))
@@ -280,9 +366,21 @@ func writeJSONResponse(w http.ResponseWriter, r *http.Request, bodyObj interface
 	}
 
 	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(statusCode)
 	w.Write(body)
 }
 
+// runError logs err returned by running checks and returns the httpError to respond with. Runs which were refused or
+// canceled because the check runner is shutting down are answered with http.StatusServiceUnavailable.
+func runError(r *http.Request, err error, errMsg string) *httpError {
+	if err == runner.ErrShutdown {
+		reqLogger(r).Warn(errMsg + ": " + err.Error())
+		return &httpError{http.StatusServiceUnavailable, "Check runner is shutting down"}
+	}
+	reqLogger(r).Error(errors.Wrap(err, errMsg))
+	return &httpError{http.StatusInternalServerError, errMsg}
+}
+
 type httpError struct {
 	statusCode int
 	err        string
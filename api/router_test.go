@@ -2,6 +2,7 @@ package api
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -529,6 +530,34 @@ func TestAPIErrors(t *testing.T) {
 	})
 }
 
+func TestShuttingDown(t *testing.T) {
+	r, err := newTestRunner("master")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := httptest.NewServer(NewRouter(r, ""))
+	defer s.Close()
+
+	if err := r.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	doc := loadOpenAPIDoc(t)
+	resp := getResponse(t, "POST", s.URL+"/cluster/", nil, nil)
+	if err := doc.validateResponse("/{check_type}/", "POST", resp); err != nil {
+		t.Fatal(err)
+	}
+	if body := readBody(t, resp, http.StatusServiceUnavailable); body != "Check runner is shutting down\n" {
+		t.Fatalf("unexpected body %q", body)
+	}
+
+	resp = getResponse(t, "POST", s.URL+"/cluster/cluster-check-1", nil, nil)
+	readBody(t, resp, http.StatusServiceUnavailable)
+
+	// Checks can still be listed.
+	readBody(t, getResponse(t, "GET", s.URL+"/cluster/", nil, nil), http.StatusOK)
+}
+
 // interfaceSlice returns a []interface{} initialized from strings.
 func interfaceSlice(strings []string) []interface{} {
 	interfaces := make([]interface{}, len(strings))
@@ -540,6 +569,15 @@ func interfaceSlice(strings []string) []interface{} {
 
 // newTestServer returns a *http.Server initialized with test check config.
 func newTestServer(role string, baseURI string) (*httptest.Server, error) {
+	r, err := newTestRunner(role)
+	if err != nil {
+		return nil, err
+	}
+	return httptest.NewServer(NewRouter(r, baseURI)), nil
+}
+
+// newTestRunner returns a *runner.Runner initialized with test check config.
+func newTestRunner(role string) (*runner.Runner, error) {
 	r, err := runner.NewRunner(role)
 	if err != nil {
 		return nil, err
@@ -577,9 +615,13 @@ func newTestServer(role string, baseURI string) (*httptest.Server, error) {
 					"timeout":     "1s",
 					"roles":       []string{"agent"},
 				},
+				"node-check-prestart": map[string]interface{}{
+					"description": "Node check prestart",
+					"cmd":         []string{"echo", "node-check-prestart"},
+					"timeout":     "1s",
+				},
 			},
-			// The API doesn't provide prestart checks, so we don't need to define any.
-			"prestart":  []string{},
+			"prestart":  []string{"node-check-prestart"},
 			"poststart": []string{"node-check", "node-check-master", "node-check-agent"},
 		},
 	})
@@ -591,7 +633,7 @@ func newTestServer(role string, baseURI string) (*httptest.Server, error) {
 		return nil, err
 	}
 
-	return httptest.NewServer(NewRouter(r, baseURI)), nil
+	return r, nil
 }
 
 // getResponse executes a request and returns the response, failing the test if there is an error.
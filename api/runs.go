@@ -0,0 +1,372 @@
+package api
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/dcos/dcos-check-runner/auth"
+	"github.com/dcos/dcos-check-runner/runner"
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+)
+
+const (
+	// DefaultRunsMaxFinished is the default number of finished runs which are retained.
+	DefaultRunsMaxFinished = 100
+
+	// DefaultRunsMaxAge is the default duration for which finished runs are retained.
+	DefaultRunsMaxAge = time.Hour
+)
+
+// States of a run.
+const (
+	runStateRunning  = "running"
+	runStateFinished = "finished"
+	runStateCanceled = "canceled"
+	runStateFailed   = "failed"
+)
+
+// WithRunRetention sets how many finished runs are retained and for how long. Running runs are always retained.
+func WithRunRetention(maxFinished int, maxAge time.Duration) Option {
+	return func(rh *runnerHandler) {
+		rh.runs.maxFinished = maxFinished
+		rh.runs.maxAge = maxAge
+	}
+}
+
+// runCheck is the result of a check of a run.
+type runCheck struct {
+	Status   int    `json:"status"`
+	Output   string `json:"output"`
+	Duration string `json:"duration,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// run is an asynchronous run of checks. It implements runner.StreamHandler to record the checks' results as they
+// finish.
+type run struct {
+	mu sync.Mutex
+
+	ID        string              `json:"id"`
+	CheckType string              `json:"check_type"`
+	State     string              `json:"state"`
+	Created   time.Time           `json:"created"`
+	Finished  *time.Time          `json:"finished,omitempty"`
+	Total     int                 `json:"total"`
+	Completed int                 `json:"completed"`
+	Status    *int                `json:"status,omitempty"`
+	Error     string              `json:"error,omitempty"`
+	Checks    map[string]runCheck `json:"checks"`
+
+	cancel   context.CancelFunc
+	canceled bool
+	done     chan struct{}
+}
+
+func (rn *run) CheckOutput(check, line string) {}
+
+func (rn *run) CheckResult(check string, resp *runner.Response, err error) {
+	rn.mu.Lock()
+	defer rn.mu.Unlock()
+
+	// The results of a finished run don't change anymore.
+	if rn.Finished != nil {
+		return
+	}
+	rn.Completed++
+	if err != nil {
+		// Checks which could not be found or executed are UNKNOWN.
+		rn.Checks[check] = runCheck{Status: 3, Error: err.Error()}
+		return
+	}
+	rn.Checks[check] = runCheck{
+		Status:   resp.Status(),
+		Output:   resp.Output(),
+		Duration: resp.Duration().String(),
+	}
+}
+
+// finish records the outcome of the run.
+func (rn *run) finish(rs *runner.CombinedResponse, err error) {
+	rn.mu.Lock()
+	defer rn.mu.Unlock()
+
+	now := time.Now()
+	rn.Finished = &now
+	switch {
+	case rn.canceled:
+		rn.State = runStateCanceled
+	case err == runner.ErrShutdown:
+		rn.State = runStateFailed
+		rn.Error = "Check runner is shutting down"
+	case err != nil:
+		rn.State = runStateFailed
+		rn.Error = "Error running checks"
+	default:
+		rn.State = runStateFinished
+		status := rs.Status()
+		rn.Status = &status
+	}
+	close(rn.done)
+}
+
+// finishedAt returns the time the run finished, or false if it is running.
+func (rn *run) finishedAt() (time.Time, bool) {
+	rn.mu.Lock()
+	defer rn.mu.Unlock()
+
+	if rn.Finished == nil {
+		return time.Time{}, false
+	}
+	return *rn.Finished, true
+}
+
+func (rn *run) MarshalJSON() ([]byte, error) {
+	rn.mu.Lock()
+	defer rn.mu.Unlock()
+
+	type runJSON run
+	return json.Marshal((*runJSON)(rn))
+}
+
+// runStore keeps the runs and prunes finished runs beyond its retention limits.
+type runStore struct {
+	maxFinished int
+	maxAge      time.Duration
+
+	mu   sync.Mutex
+	runs map[string]*run
+}
+
+func newRunStore() *runStore {
+	return &runStore{
+		maxFinished: DefaultRunsMaxFinished,
+		maxAge:      DefaultRunsMaxAge,
+		runs:        make(map[string]*run),
+	}
+}
+
+// add adds rn to the store.
+func (s *runStore) add(rn *run) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.prune()
+	s.runs[rn.ID] = rn
+}
+
+// get returns the run with the given ID, or nil if there is none.
+func (s *runStore) get(id string) *run {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.prune()
+	return s.runs[id]
+}
+
+// prune removes finished runs which are older than maxAge, and the oldest finished runs beyond maxFinished. s.mu must
+// be held.
+func (s *runStore) prune() {
+	type finishedRun struct {
+		id       string
+		finished time.Time
+	}
+
+	var finished []finishedRun
+	for id, rn := range s.runs {
+		t, ok := rn.finishedAt()
+		if !ok {
+			continue
+		}
+		if s.maxAge > 0 && time.Since(t) > s.maxAge {
+			delete(s.runs, id)
+			continue
+		}
+		finished = append(finished, finishedRun{id, t})
+	}
+
+	if s.maxFinished < 0 || len(finished) <= s.maxFinished {
+		return
+	}
+	sort.Slice(finished, func(i, j int) bool { return finished[i].finished.After(finished[j].finished) })
+	for _, f := range finished[s.maxFinished:] {
+		delete(s.runs, f.id)
+	}
+}
+
+// createRun starts running the checks of the check type and checks given in the request body in the background, and
+// responds with the new run. The run is not bound to the request, so it continues when the client disconnects.
+func (rh *runnerHandler) createRun(w http.ResponseWriter, r *http.Request) {
+	checkType, checks, httpErr := runFromBody(r)
+	if httpErr != nil {
+		http.Error(w, httpErr.Error(), httpErr.statusCode)
+		return
+	}
+
+	httpErr = rh.authorize(r, checkType, auth.ActionRun)
+	if httpErr != nil {
+		http.Error(w, httpErr.Error(), httpErr.statusCode)
+		return
+	}
+
+	checkFunc, httpErr := rh.getCheckFuncFromReq(checkType)
+	if httpErr != nil {
+		http.Error(w, httpErr.Error(), httpErr.statusCode)
+		return
+	}
+
+	httpErr = rh.verifySelectedChecks(checkType, checks)
+	if httpErr != nil {
+		http.Error(w, httpErr.Error(), httpErr.statusCode)
+		return
+	}
+
+	// List the checks first to report the progress of the run.
+	listed, err := checkFunc(r.Context(), true, checks...)
+	if err != nil {
+		errMsg := "Error listing checks"
+		reqLogger(r).Error(errors.Wrap(err, errMsg))
+		http.Error(w, errMsg, http.StatusInternalServerError)
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	rn := &run{
+		ID:        newRunID(),
+		CheckType: checkType,
+		State:     runStateRunning,
+		Created:   time.Now(),
+		Total:     len(listed.Checks()),
+		Checks:    make(map[string]runCheck),
+		cancel:    cancel,
+		done:      make(chan struct{}),
+	}
+	rh.runs.add(rn)
+
+	logger := reqLogger(r)
+	go func() {
+		defer cancel()
+		rs, err := rh.runner.Stream(ctx, suites[checkType], rn, checks...)
+		switch {
+		case err == context.Canceled || err == runner.ErrShutdown:
+			// The run was canceled by a client or by the shutdown of the check runner.
+			logger.Infof("Stopped run %s: %s", rn.ID, err)
+		case err != nil:
+			logger.Error(errors.Wrapf(err, "Error running checks of run %s", rn.ID))
+		}
+		rn.finish(rs, err)
+	}()
+
+	w.Header().Set("Location", r.URL.Path+rn.ID)
+	writeJSONResponseWithStatus(w, r, http.StatusAccepted, rn)
+}
+
+// getRun responds with the progress and the results of the checks of a run.
+func (rh *runnerHandler) getRun(w http.ResponseWriter, r *http.Request) {
+	rn, httpErr := rh.runFromReq(r)
+	if httpErr != nil {
+		http.Error(w, httpErr.Error(), httpErr.statusCode)
+		return
+	}
+
+	httpErr = rh.authorize(r, rn.CheckType, auth.ActionList)
+	if httpErr != nil {
+		http.Error(w, httpErr.Error(), httpErr.statusCode)
+		return
+	}
+	writeJSONResponse(w, r, rn)
+}
+
+// cancelRun cancels a running run and responds with it after its checks were stopped.
+func (rh *runnerHandler) cancelRun(w http.ResponseWriter, r *http.Request) {
+	rn, httpErr := rh.runFromReq(r)
+	if httpErr != nil {
+		http.Error(w, httpErr.Error(), httpErr.statusCode)
+		return
+	}
+
+	httpErr = rh.authorize(r, rn.CheckType, auth.ActionRun)
+	if httpErr != nil {
+		http.Error(w, httpErr.Error(), httpErr.statusCode)
+		return
+	}
+
+	rn.mu.Lock()
+	if rn.Finished != nil {
+		rn.mu.Unlock()
+		http.Error(w, fmt.Sprintf("run %s is already %s", rn.ID, rn.State), http.StatusConflict)
+		return
+	}
+	rn.canceled = true
+	rn.mu.Unlock()
+	rn.cancel()
+
+	select {
+	case <-rn.done:
+	case <-r.Context().Done():
+		return
+	}
+	writeJSONResponse(w, r, rn)
+}
+
+// runFromReq returns the run given by the id variable in the URI.
+func (rh *runnerHandler) runFromReq(r *http.Request) (*run, *httpError) {
+	id := mux.Vars(r)["id"]
+	rn := rh.runs.get(id)
+	if rn == nil {
+		return nil, &httpError{http.StatusNotFound, fmt.Sprintf("run not found: %s", id)}
+	}
+	return rn, nil
+}
+
+// runFromBody returns the check type and the check names of a new run from r's body, which is decoded according to
+// its Content-Type header.
+func runFromBody(r *http.Request) (string, []string, *httpError) {
+	ct, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil {
+		return "", nil, &httpError{http.StatusBadRequest, fmt.Sprintf("unable to parse Content-Type: %s", err)}
+	}
+
+	var body struct {
+		CheckType string   `json:"check_type"`
+		Checks    []string `json:"check"`
+	}
+	switch ct {
+	case "application/x-www-form-urlencoded":
+		if err := r.ParseForm(); err != nil {
+			return "", nil, &httpError{http.StatusBadRequest, err.Error()}
+		}
+		body.CheckType = r.PostForm.Get("check_type")
+		body.Checks = r.PostForm["check"]
+	case "application/json":
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			return "", nil, &httpError{http.StatusBadRequest, err.Error()}
+		}
+	default:
+		return "", nil, &httpError{http.StatusUnsupportedMediaType, fmt.Sprintf("unsupported Content-Type: %s", ct)}
+	}
+
+	if body.CheckType == "" {
+		return "", nil, &httpError{http.StatusBadRequest, "check_type is required"}
+	}
+	if body.Checks == nil {
+		body.Checks = []string{}
+	}
+	return body.CheckType, body.Checks, nil
+}
+
+// newRunID returns a random run ID.
+func newRunID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 16)
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,206 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dcos/dcos-check-runner/runner"
+)
+
+// decodeRun decodes the run in the body of resp.
+func decodeRun(t *testing.T, resp *http.Response) map[string]interface{} {
+	defer resp.Body.Close()
+
+	var rn map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&rn); err != nil {
+		t.Fatal(err)
+	}
+	return rn
+}
+
+// createRun creates a run with body and returns its location.
+func createRun(t *testing.T, url, body string) string {
+	resp := getResponse(t, "POST", url+"/runs/", map[string]string{"Content-Type": "application/json"}, strings.NewReader(body))
+	if resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("expected status %d, got %d", http.StatusAccepted, resp.StatusCode)
+	}
+	rn := decodeRun(t, resp)
+	if rn["state"] != "running" && rn["state"] != "finished" {
+		t.Fatalf("unexpected state of new run: %v", rn)
+	}
+	location := resp.Header.Get("Location")
+	if location != "/runs/"+rn["id"].(string) {
+		t.Fatalf("unexpected Location %s of run %s", location, rn["id"])
+	}
+	return url + location
+}
+
+// waitForRun polls the run at url until it is no longer running and returns it.
+func waitForRun(t *testing.T, url string) map[string]interface{} {
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		resp := getResponse(t, "GET", url, nil, nil)
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("expected status %d, got %d", http.StatusOK, resp.StatusCode)
+		}
+		if rn := decodeRun(t, resp); rn["state"] != "running" {
+			return rn
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("run did not finish")
+	return nil
+}
+
+func TestRuns(t *testing.T) {
+	s, err := newTestServer("master", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	t.Run("run node checks", func(t *testing.T) {
+		rn := waitForRun(t, createRun(t, s.URL, `{"check_type": "node"}`))
+		if rn["state"] != "finished" || rn["status"].(float64) != 0 || rn["finished"] == nil {
+			t.Fatalf("unexpected run %v", rn)
+		}
+		// The check for agents is not run on a master.
+		if rn["total"].(float64) != 2 || rn["completed"].(float64) != 2 {
+			t.Fatalf("expected 2 of 2 checks to be completed, got %v", rn)
+		}
+		checks := rn["checks"].(map[string]interface{})
+		for _, name := range []string{"node-check", "node-check-master"} {
+			check, ok := checks[name].(map[string]interface{})
+			if !ok {
+				t.Fatalf("expected result of %s, got %v", name, checks)
+			}
+			if check["status"].(float64) != 0 || check["output"] != name+"\n" {
+				t.Fatalf("unexpected result of %s: %v", name, check)
+			}
+		}
+	})
+
+	t.Run("run selected cluster checks", func(t *testing.T) {
+		rn := waitForRun(t, createRun(t, s.URL, `{"check_type": "cluster", "check": ["cluster-check-2"]}`))
+		checks := rn["checks"].(map[string]interface{})
+		if rn["total"].(float64) != 1 || len(checks) != 1 || checks["cluster-check-2"] == nil {
+			t.Fatalf("unexpected run %v", rn)
+		}
+	})
+
+	t.Run("invalid runs", func(t *testing.T) {
+		for body, statusCode := range map[string]int{
+			`{}`:                    http.StatusBadRequest,
+			`{"check_type": "foo"}`: http.StatusNotFound,
+			`{"check_type": "node", "check": ["missing"]}`:  http.StatusNotFound,
+			`{"check_type": "node", "check": "node-check"}`: http.StatusBadRequest,
+		} {
+			resp := getResponse(t, "POST", s.URL+"/runs/", map[string]string{"Content-Type": "application/json"}, strings.NewReader(body))
+			if resp.StatusCode != statusCode {
+				t.Fatalf("expected status %d for %s, got %d", statusCode, body, resp.StatusCode)
+			}
+		}
+	})
+
+	t.Run("unknown run", func(t *testing.T) {
+		for _, method := range []string{"GET", "DELETE"} {
+			if resp := getResponse(t, method, s.URL+"/runs/nonexistent", nil, nil); resp.StatusCode != http.StatusNotFound {
+				t.Fatalf("expected status %d, got %d", http.StatusNotFound, resp.StatusCode)
+			}
+		}
+	})
+}
+
+func TestCancelRun(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("TestCancelRun was skipped on Windows")
+	}
+
+	r, err := runner.NewRunner("master")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := `
+{
+  "cluster_checks": {
+    "fast": {"cmd": ["echo", "fast"], "timeout": "1s"},
+    "slow": {"cmd": ["sleep", "10"], "timeout": "20s"}
+  }
+}`
+	if err := r.Load(strings.NewReader(cfg)); err != nil {
+		t.Fatal(err)
+	}
+	s := httptest.NewServer(NewRouter(r, ""))
+	defer s.Close()
+
+	url := createRun(t, s.URL, `{"check_type": "cluster"}`)
+
+	// Wait for the fast check to complete.
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		rn := decodeRun(t, getResponse(t, "GET", url, nil, nil))
+		if rn["completed"].(float64) == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("fast check did not complete: %v", rn)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	start := time.Now()
+	resp := getResponse(t, "DELETE", url, nil, nil)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, resp.StatusCode)
+	}
+	rn := decodeRun(t, resp)
+	if time.Since(start) > 5*time.Second {
+		t.Fatal("canceling the run did not stop its checks")
+	}
+	if rn["state"] != "canceled" || rn["status"] != nil {
+		t.Fatalf("unexpected canceled run %v", rn)
+	}
+	checks := rn["checks"].(map[string]interface{})
+	if fast := checks["fast"].(map[string]interface{}); fast["status"].(float64) != 0 {
+		t.Fatalf("expected the result of the fast check to be kept, got %v", fast)
+	}
+
+	// The run doesn't change anymore once it was canceled, e.g. by the result of the killed slow check.
+	time.Sleep(100 * time.Millisecond)
+	if after := decodeRun(t, getResponse(t, "GET", url, nil, nil)); !reflect.DeepEqual(after, rn) {
+		t.Fatalf("expected the canceled run %v not to change, got %v", rn, after)
+	}
+
+	// Finished runs can't be canceled.
+	if resp := getResponse(t, "DELETE", url, nil, nil); resp.StatusCode != http.StatusConflict {
+		t.Fatalf("expected status %d, got %d", http.StatusConflict, resp.StatusCode)
+	}
+}
+
+func TestRunRetention(t *testing.T) {
+	r, err := newTestRunner("master")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := httptest.NewServer(NewRouter(r, "", WithRunRetention(1, time.Hour)))
+	defer s.Close()
+
+	first := createRun(t, s.URL, `{"check_type": "cluster"}`)
+	waitForRun(t, first)
+	second := createRun(t, s.URL, `{"check_type": "cluster"}`)
+	waitForRun(t, second)
+
+	// Only the most recently finished run is retained.
+	if resp := getResponse(t, "GET", first, nil, nil); resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected status %d, got %d", http.StatusNotFound, resp.StatusCode)
+	}
+	if resp := getResponse(t, "GET", second, nil, nil); resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, resp.StatusCode)
+	}
+}
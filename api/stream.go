@@ -0,0 +1,124 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/dcos/dcos-check-runner/auth"
+	"github.com/dcos/dcos-check-runner/runner"
+)
+
+// streamChecks runs the checks selected by the check query parameters and streams their output as Server-Sent Events.
+// An "output" event is sent for each line of output, a "result" event when a check finished, and a final "done" event
+// with the combined status.
+func (rh *runnerHandler) streamChecks(w http.ResponseWriter, r *http.Request) {
+	checkType, httpErr := verifyCheckType(r)
+	if httpErr != nil {
+		http.Error(w, httpErr.Error(), httpErr.statusCode)
+		return
+	}
+
+	httpErr = rh.authorize(r, checkType, auth.ActionRun)
+	if httpErr != nil {
+		http.Error(w, httpErr.Error(), httpErr.statusCode)
+		return
+	}
+
+	checks := checksFromQueryParams(r)
+	httpErr = rh.verifySelectedChecks(checkType, checks)
+	if httpErr != nil {
+		http.Error(w, httpErr.Error(), httpErr.statusCode)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		reqLogger(r).Error("response writer does not support flushing")
+		http.Error(w, "Streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	// Disable response buffering of nginx, e.g. in Admin Router.
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	events := &eventWriter{w: w, flusher: flusher}
+	// The response writer must not be used anymore once the handler returned.
+	defer events.close()
+	rs, err := rh.runner.Stream(r.Context(), suites[checkType], events, checks...)
+	if err != nil {
+		events.send("error", map[string]string{"error": runError(r, err, "Error running checks").Error()})
+		return
+	}
+	events.send("done", map[string]int{"status": rs.Status()})
+}
+
+// streamOutput is the data of an "output" event.
+type streamOutput struct {
+	Check string `json:"check"`
+	Line  string `json:"line"`
+}
+
+// streamResult is the data of a "result" event.
+type streamResult struct {
+	Check    string `json:"check"`
+	Status   int    `json:"status"`
+	Output   string `json:"output"`
+	Duration string `json:"duration,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// eventWriter writes Server-Sent Events. It implements runner.StreamHandler.
+type eventWriter struct {
+	mu      sync.Mutex
+	w       http.ResponseWriter
+	flusher http.Flusher
+	closed  bool
+}
+
+func (e *eventWriter) CheckOutput(check, line string) {
+	e.send("output", streamOutput{Check: check, Line: line})
+}
+
+func (e *eventWriter) CheckResult(check string, resp *runner.Response, err error) {
+	if err != nil {
+		// Checks which could not be found or executed are UNKNOWN.
+		e.send("result", streamResult{Check: check, Status: 3, Error: err.Error()})
+		return
+	}
+	e.send("result", streamResult{
+		Check:    check,
+		Status:   resp.Status(),
+		Output:   resp.Output(),
+		Duration: resp.Duration().String(),
+	})
+}
+
+// send writes an event with the JSON encoding of data and flushes it to the client. Write errors are ignored, as the
+// checks are canceled with the request's context when the client disconnects. Events are dropped once e is closed.
+func (e *eventWriter) send(event string, data interface{}) {
+	body, err := json.Marshal(data)
+	if err != nil {
+		return
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.closed {
+		return
+	}
+	fmt.Fprintf(e.w, "event: %s\ndata: %s\n\n", event, body)
+	e.flusher.Flush()
+}
+
+// close stops sending events.
+func (e *eventWriter) close() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.closed = true
+}
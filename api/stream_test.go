@@ -0,0 +1,165 @@
+package api
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dcos/dcos-check-runner/runner"
+)
+
+// sseEvent is a Server-Sent Event.
+type sseEvent struct {
+	event string
+	data  map[string]interface{}
+}
+
+// readEvents reads the Server-Sent Events of resp until the stream ends.
+func readEvents(t *testing.T, resp *http.Response) []sseEvent {
+	defer resp.Body.Close()
+
+	var (
+		events []sseEvent
+		event  sseEvent
+	)
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "event: "):
+			event.event = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &event.data); err != nil {
+				t.Fatal(err)
+			}
+		case line == "":
+			events = append(events, event)
+			event = sseEvent{}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return events
+}
+
+func TestStream(t *testing.T) {
+	s, err := newTestServer("master", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	t.Run("stream node checks", func(t *testing.T) {
+		resp := getResponse(t, "GET", s.URL+"/node/stream", nil, nil)
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("expected status %d, got %d", http.StatusOK, resp.StatusCode)
+		}
+		if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+			t.Fatalf("expected Content-Type text/event-stream, got %s", ct)
+		}
+
+		events := readEvents(t, resp)
+		outputs := make(map[string]string)
+		results := make(map[string]map[string]interface{})
+		for i, e := range events {
+			switch e.event {
+			case "output":
+				check := e.data["check"].(string)
+				if _, ok := results[check]; ok {
+					t.Fatalf("output of %s after its result", check)
+				}
+				outputs[check] = e.data["line"].(string)
+			case "result":
+				results[e.data["check"].(string)] = e.data
+			case "done":
+				if i != len(events)-1 {
+					t.Fatal("expected done to be the last event")
+				}
+				if status := e.data["status"].(float64); status != 0 {
+					t.Fatalf("expected status 0, got %v", status)
+				}
+			default:
+				t.Fatalf("unexpected event %s", e.event)
+			}
+		}
+
+		// The check for agents is not run on a master.
+		expectedOutputs := map[string]string{"node-check": "node-check", "node-check-master": "node-check-master"}
+		if len(outputs) != len(expectedOutputs) || len(results) != len(expectedOutputs) {
+			t.Fatalf("expected output and results of %v, got %v and %v", expectedOutputs, outputs, results)
+		}
+		for check, line := range expectedOutputs {
+			if outputs[check] != line {
+				t.Fatalf("expected output %s of %s, got %s", line, check, outputs[check])
+			}
+			if results[check]["status"].(float64) != 0 || results[check]["duration"] == "" {
+				t.Fatalf("unexpected result of %s: %v", check, results[check])
+			}
+		}
+		if events[len(events)-1].event != "done" {
+			t.Fatal("expected a done event")
+		}
+	})
+
+	t.Run("stream selected cluster checks", func(t *testing.T) {
+		events := readEvents(t, getResponse(t, "GET", s.URL+"/cluster/stream?check=cluster-check-2", nil, nil))
+		if len(events) != 3 || events[0].event != "output" || events[1].event != "result" || events[2].event != "done" {
+			t.Fatalf("unexpected events %+v", events)
+		}
+		if events[1].data["check"] != "cluster-check-2" {
+			t.Fatalf("unexpected result %v", events[1].data)
+		}
+	})
+
+	t.Run("missing checks", func(t *testing.T) {
+		if resp := getResponse(t, "GET", s.URL+"/cluster/stream?check=nonexistent", nil, nil); resp.StatusCode != http.StatusNotFound {
+			t.Fatalf("expected status %d, got %d", http.StatusNotFound, resp.StatusCode)
+		}
+	})
+
+	t.Run("unrecognized check type", func(t *testing.T) {
+		if resp := getResponse(t, "GET", s.URL+"/foo/stream", nil, nil); resp.StatusCode != http.StatusNotFound {
+			t.Fatalf("expected status %d, got %d", http.StatusNotFound, resp.StatusCode)
+		}
+	})
+}
+
+func TestStreamDisconnect(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("test checks require sh")
+	}
+
+	r, err := runner.NewRunner("master")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := `{"cluster_checks": {"slow": {"cmd": ["sh", "-c", "echo one; sleep 1; echo two"], "timeout": "5s"}}}`
+	if err := r.Load(strings.NewReader(cfg)); err != nil {
+		t.Fatal(err)
+	}
+	s := httptest.NewServer(NewRouter(r, ""))
+	defer s.Close()
+
+	// Disconnect after the first event, while the check is running.
+	for i := 0; i < 5; i++ {
+		resp := getResponse(t, "GET", s.URL+"/cluster/stream", nil, nil)
+		line, err := bufio.NewReader(resp.Body).ReadString('\n')
+		if err != nil || line != "event: output\n" {
+			t.Fatalf("unexpected first line %q, error %v", line, err)
+		}
+		resp.Body.Close()
+	}
+
+	// The server must keep running once the checks of the disconnected streams returned.
+	time.Sleep(1500 * time.Millisecond)
+	events := readEvents(t, getResponse(t, "GET", s.URL+"/cluster/stream", nil, nil))
+	if len(events) != 4 || events[3].event != "done" {
+		t.Fatalf("unexpected events %+v", events)
+	}
+}
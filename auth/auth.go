@@ -0,0 +1,55 @@
+// Package auth authenticates requests to the check runner HTTP API and authorizes them with a policy.
+package auth
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ErrNoCredentials is returned by authenticators when a request carries no credentials they understand.
+var ErrNoCredentials = errors.New("no credentials")
+
+// Authenticator determines the identity of the client which sent a request.
+type Authenticator interface {
+	// Authenticate returns the identity of the client which sent r. ErrNoCredentials is returned if r carries no
+	// credentials the authenticator understands.
+	Authenticate(r *http.Request) (string, error)
+}
+
+// Chain is an Authenticator which tries each of its authenticators in turn and returns the first identity found.
+type Chain []Authenticator
+
+// Authenticate returns the identity found by the first authenticator which accepts r's credentials. If none does, the
+// first error other than ErrNoCredentials is returned, or ErrNoCredentials.
+func (c Chain) Authenticate(r *http.Request) (string, error) {
+	var firstErr error
+	for _, a := range c {
+		identity, err := a.Authenticate(r)
+		if err == nil {
+			return identity, nil
+		}
+		if err != ErrNoCredentials && firstErr == nil {
+			firstErr = err
+		}
+	}
+	if firstErr != nil {
+		return "", firstErr
+	}
+	return "", ErrNoCredentials
+}
+
+// bearerToken returns the token from r's Authorization header, given with the "Bearer" scheme or as "token=<token>"
+// like DC/OS clients send it, or "" if there is none.
+func bearerToken(r *http.Request) string {
+	header := strings.TrimSpace(r.Header.Get("Authorization"))
+	if strings.HasPrefix(header, "token=") {
+		return strings.TrimPrefix(header, "token=")
+	}
+	parts := strings.SplitN(header, " ", 2)
+	if len(parts) != 2 || !strings.EqualFold(parts[0], "Bearer") {
+		return ""
+	}
+	return strings.TrimSpace(parts[1])
+}
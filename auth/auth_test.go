@@ -0,0 +1,295 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// signJWT returns a JWT with header and claims signed with key.
+func signJWT(t *testing.T, key *rsa.PrivateKey, header, claims map[string]interface{}) string {
+	encode := func(v interface{}) string {
+		data, err := json.Marshal(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return base64.RawURLEncoding.EncodeToString(data)
+	}
+
+	signed := encode(header) + "." + encode(claims)
+	digest := sha256.Sum256([]byte(signed))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+// requestWithAuthorization returns a request with the given Authorization header.
+func requestWithAuthorization(t *testing.T, authorization string) *http.Request {
+	r, err := http.NewRequest("GET", "/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if authorization != "" {
+		r.Header.Set("Authorization", authorization)
+	}
+	return r
+}
+
+func TestJWTAuthenticator(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir, err := ioutil.TempDir("", "auth")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	pubDER, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyFile := filepath.Join(dir, "jwt.pub")
+	if err := ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pubDER}), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	a, err := LoadJWTAuthenticator(keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Unix(1551441600, 0)
+	a.now = func() time.Time { return now }
+
+	rs256 := map[string]interface{}{"alg": "RS256", "typ": "JWT"}
+	exp := now.Add(time.Hour).Unix()
+
+	for _, tt := range []struct {
+		name          string
+		authorization string
+		identity      string
+		err           string
+	}{
+		{
+			name:          "uid claim",
+			authorization: "Bearer " + signJWT(t, key, rs256, map[string]interface{}{"uid": "dcos_check_runner", "exp": exp}),
+			identity:      "dcos_check_runner",
+		},
+		{
+			name:          "DC/OS token scheme",
+			authorization: "token=" + signJWT(t, key, rs256, map[string]interface{}{"uid": "dcos_check_runner", "exp": exp}),
+			identity:      "dcos_check_runner",
+		},
+		{
+			name:          "sub claim",
+			authorization: "Bearer " + signJWT(t, key, rs256, map[string]interface{}{"sub": "alice", "exp": exp}),
+			identity:      "alice",
+		},
+		{
+			name:          "no token",
+			authorization: "",
+			err:           ErrNoCredentials.Error(),
+		},
+		{
+			name:          "not a JWT",
+			authorization: "Bearer static-token",
+			err:           ErrNoCredentials.Error(),
+		},
+		{
+			name:          "wrong key",
+			authorization: "Bearer " + signJWT(t, otherKey, rs256, map[string]interface{}{"uid": "mallory"}),
+			err:           "invalid JWT signature",
+		},
+		{
+			name:          "unsupported algorithm",
+			authorization: "Bearer " + signJWT(t, key, map[string]interface{}{"alg": "none"}, map[string]interface{}{"uid": "mallory"}),
+			err:           `unsupported JWT algorithm "none"`,
+		},
+		{
+			name:          "expired",
+			authorization: "Bearer " + signJWT(t, key, rs256, map[string]interface{}{"uid": "alice", "exp": now.Unix()}),
+			err:           "JWT is expired",
+		},
+		{
+			name:          "no expiration",
+			authorization: "Bearer " + signJWT(t, key, rs256, map[string]interface{}{"uid": "alice"}),
+			err:           "JWT has no exp claim",
+		},
+		{
+			name:          "not valid yet",
+			authorization: "Bearer " + signJWT(t, key, rs256, map[string]interface{}{"uid": "alice", "nbf": exp, "exp": exp}),
+			err:           "JWT is not valid yet",
+		},
+		{
+			name:          "no identity",
+			authorization: "Bearer " + signJWT(t, key, rs256, map[string]interface{}{"exp": exp}),
+			err:           "JWT has no uid or sub claim",
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			identity, err := a.Authenticate(requestWithAuthorization(t, tt.authorization))
+			if tt.err != "" {
+				if err == nil || err.Error() != tt.err {
+					t.Fatalf("expected error %q, got %v", tt.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if identity != tt.identity {
+				t.Fatalf("expected identity %s, got %s", tt.identity, identity)
+			}
+		})
+	}
+}
+
+func TestParseRSAPublicKey(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pkcs1 := pem.EncodeToMemory(&pem.Block{Type: "RSA PUBLIC KEY", Bytes: x509.MarshalPKCS1PublicKey(&key.PublicKey)})
+	pub, err := ParseRSAPublicKey(pkcs1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pub.N.Cmp(key.N) != 0 {
+		t.Fatal("unexpected public key")
+	}
+
+	if _, err := ParseRSAPublicKey([]byte("not a key")); err == nil {
+		t.Fatal("expected an error for invalid PEM data")
+	}
+}
+
+func TestTokenAuthenticator(t *testing.T) {
+	dir, err := ioutil.TempDir("", "auth")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	tokenFile := filepath.Join(dir, "tokens")
+	tokens := "# monitoring\nprometheus s3cr3t\n\nci   0th3r-s3cr3t\n"
+	if err := ioutil.WriteFile(tokenFile, []byte(tokens), 0600); err != nil {
+		t.Fatal(err)
+	}
+	a, err := LoadTokenFile(tokenFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for authorization, identity := range map[string]string{
+		"Bearer s3cr3t":       "prometheus",
+		"bearer 0th3r-s3cr3t": "ci",
+	} {
+		got, err := a.Authenticate(requestWithAuthorization(t, authorization))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != identity {
+			t.Fatalf("expected identity %s, got %s", identity, got)
+		}
+	}
+
+	if _, err := a.Authenticate(requestWithAuthorization(t, "Bearer wrong")); err == nil || err == ErrNoCredentials {
+		t.Fatalf("expected an invalid token error, got %v", err)
+	}
+	if _, err := a.Authenticate(requestWithAuthorization(t, "Basic dXNlcjpwYXNz")); err != ErrNoCredentials {
+		t.Fatalf("expected ErrNoCredentials, got %v", err)
+	}
+
+	if err := ioutil.WriteFile(tokenFile, []byte("prometheus\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadTokenFile(tokenFile); err == nil {
+		t.Fatal("expected an error for a line without a token")
+	}
+}
+
+func TestChain(t *testing.T) {
+	tokens := NewTokenAuthenticator(map[string]string{"s3cr3t": "prometheus"})
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := Chain{NewJWTAuthenticator(&key.PublicKey), tokens}
+
+	identity, err := c.Authenticate(requestWithAuthorization(t, "Bearer s3cr3t"))
+	if err != nil || identity != "prometheus" {
+		t.Fatalf("expected identity prometheus, got %s, %v", identity, err)
+	}
+
+	if _, err := c.Authenticate(requestWithAuthorization(t, "")); err != ErrNoCredentials {
+		t.Fatalf("expected ErrNoCredentials, got %v", err)
+	}
+	if _, err := c.Authenticate(requestWithAuthorization(t, "Bearer wrong")); err == nil || err.Error() != "invalid token" {
+		t.Fatalf("expected invalid token error, got %v", err)
+	}
+}
+
+func TestPolicy(t *testing.T) {
+	dir, err := ioutil.TempDir("", "auth")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	policyFile := filepath.Join(dir, "policy.json")
+	policy := `{
+  "admin": {"*": ["*"]},
+  "prometheus": {"node": ["list", "run"]},
+  "*": {"*": ["list"]}
+}`
+	if err := ioutil.WriteFile(policyFile, []byte(policy), 0644); err != nil {
+		t.Fatal(err)
+	}
+	p, err := LoadPolicy(policyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		identity, checkType, action string
+		allowed                     bool
+	}{
+		{"admin", "cluster", ActionRun, true},
+		{"prometheus", "node", ActionRun, true},
+		{"prometheus", "cluster", ActionRun, false},
+		{"prometheus", "cluster", ActionList, true},
+		{"alice", "node", ActionList, true},
+		{"alice", "node", ActionRun, false},
+	} {
+		if allowed := p.Allowed(tt.identity, tt.checkType, tt.action); allowed != tt.allowed {
+			t.Fatalf("expected %s to be allowed to %s %s checks: %t, got %t", tt.identity, tt.action, tt.checkType, tt.allowed, allowed)
+		}
+	}
+
+	if err := ioutil.WriteFile(policyFile, []byte(`{"alice": {"node": ["delete"]}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadPolicy(policyFile); err == nil {
+		t.Fatal("expected an error for an unknown action")
+	}
+}
@@ -0,0 +1,160 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// JWTAuthenticator authenticates requests with a JSON Web Token in the Authorization header, signed with RS256 like the
+// tokens of DC/OS service accounts. The identity is taken from the "uid" claim, or the "sub" claim if there is none.
+type JWTAuthenticator struct {
+	key *rsa.PublicKey
+
+	// now returns the current time. It is replaced in tests.
+	now func() time.Time
+}
+
+// NewJWTAuthenticator returns a JWTAuthenticator which verifies tokens with key.
+func NewJWTAuthenticator(key *rsa.PublicKey) *JWTAuthenticator {
+	return &JWTAuthenticator{key: key, now: time.Now}
+}
+
+// LoadJWTAuthenticator returns a JWTAuthenticator which verifies tokens with the PEM encoded RSA public key in the file
+// at path.
+func LoadJWTAuthenticator(path string) (*JWTAuthenticator, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to read JWT public key")
+	}
+	key, err := ParseRSAPublicKey(data)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid JWT public key %s", path)
+	}
+	return NewJWTAuthenticator(key), nil
+}
+
+// ParseRSAPublicKey parses a PEM encoded RSA public key in PKIX or PKCS #1 form, or the public key of a certificate.
+func ParseRSAPublicKey(data []byte) (*rsa.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM data found")
+	}
+
+	var pub interface{}
+	switch block.Type {
+	case "PUBLIC KEY":
+		key, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		pub = key
+	case "RSA PUBLIC KEY":
+		return x509.ParsePKCS1PublicKey(block.Bytes)
+	case "CERTIFICATE":
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		pub = cert.PublicKey
+	default:
+		return nil, errors.Errorf("unexpected PEM block type %s", block.Type)
+	}
+
+	key, ok := pub.(*rsa.PublicKey)
+	if !ok {
+		return nil, errors.Errorf("expected an RSA public key, got %T", pub)
+	}
+	return key, nil
+}
+
+// Authenticate returns the identity from the JWT in r's Authorization header. ErrNoCredentials is returned if there is
+// no bearer token or it is not a JWT.
+func (a *JWTAuthenticator) Authenticate(r *http.Request) (string, error) {
+	token := bearerToken(r)
+	if token == "" || strings.Count(token, ".") != 2 {
+		return "", ErrNoCredentials
+	}
+	return a.Verify(token)
+}
+
+// jwtHeader is the header of a JWT.
+type jwtHeader struct {
+	Alg string `json:"alg"`
+}
+
+// jwtClaims are the claims of a JWT used for authentication.
+type jwtClaims struct {
+	UID       string   `json:"uid"`
+	Subject   string   `json:"sub"`
+	ExpiresAt *float64 `json:"exp"`
+	NotBefore *float64 `json:"nbf"`
+}
+
+// Verify verifies the signature and the expiration time of token and returns its identity. Tokens without an
+// expiration time are rejected, as they would be valid forever.
+func (a *JWTAuthenticator) Verify(token string) (string, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return "", errors.New("malformed JWT")
+	}
+
+	var header jwtHeader
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return "", errors.Wrap(err, "invalid JWT header")
+	}
+	if header.Alg != "RS256" {
+		return "", errors.Errorf("unsupported JWT algorithm %q", header.Alg)
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return "", errors.Wrap(err, "invalid JWT signature")
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(a.key, crypto.SHA256, digest[:], sig); err != nil {
+		return "", errors.New("invalid JWT signature")
+	}
+
+	var claims jwtClaims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return "", errors.Wrap(err, "invalid JWT claims")
+	}
+	now := float64(a.now().Unix())
+	if claims.ExpiresAt == nil {
+		return "", errors.New("JWT has no exp claim")
+	}
+	if now >= *claims.ExpiresAt {
+		return "", errors.New("JWT is expired")
+	}
+	if claims.NotBefore != nil && now < *claims.NotBefore {
+		return "", errors.New("JWT is not valid yet")
+	}
+
+	if claims.UID != "" {
+		return claims.UID, nil
+	}
+	if claims.Subject != "" {
+		return claims.Subject, nil
+	}
+	return "", errors.New("JWT has no uid or sub claim")
+}
+
+// decodeSegment decodes the base64url encoded JSON of a JWT segment into v.
+func decodeSegment(segment string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
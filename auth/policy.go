@@ -0,0 +1,72 @@
+package auth
+
+import (
+	"encoding/json"
+	"io/ioutil"
+
+	"github.com/pkg/errors"
+)
+
+// Actions which a policy allows on a check type.
+const (
+	// ActionList lists checks or reads their results.
+	ActionList = "list"
+
+	// ActionRun runs checks.
+	ActionRun = "run"
+)
+
+// Wildcard matches any identity or check type in a policy.
+const Wildcard = "*"
+
+// Policy maps identities to the actions they are allowed to perform per check type, e.g.
+//
+//	{"dcos_check_runner": {"node": ["list", "run"], "*": ["list"]}}
+//
+// The identity and check type "*" match any identity and check type.
+type Policy map[string]map[string][]string
+
+// LoadPolicy reads a JSON encoded policy from the file at path.
+func LoadPolicy(path string) (Policy, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to read policy file")
+	}
+
+	var p Policy
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, errors.Wrapf(err, "invalid policy file %s", path)
+	}
+	if err := p.validate(); err != nil {
+		return nil, errors.Wrapf(err, "invalid policy file %s", path)
+	}
+	return p, nil
+}
+
+// validate returns an error if p contains unknown actions.
+func (p Policy) validate() error {
+	for identity, checkTypes := range p {
+		for checkType, actions := range checkTypes {
+			for _, action := range actions {
+				if action != ActionList && action != ActionRun && action != Wildcard {
+					return errors.Errorf("unknown action %q for identity %s and check type %s", action, identity, checkType)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// Allowed returns whether identity is allowed to perform action on checks of checkType.
+func (p Policy) Allowed(identity, checkType, action string) bool {
+	for _, id := range []string{identity, Wildcard} {
+		for _, ct := range []string{checkType, Wildcard} {
+			for _, a := range p[id][ct] {
+				if a == action || a == Wildcard {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
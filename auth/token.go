@@ -0,0 +1,85 @@
+package auth
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"crypto/subtle"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// TokenAuthenticator authenticates requests with static bearer tokens.
+type TokenAuthenticator struct {
+	// tokens are the SHA-256 hashes of the tokens and their identities. Hashes have the same length, so they can be
+	// compared in constant time.
+	tokens []staticToken
+}
+
+type staticToken struct {
+	hash     [sha256.Size]byte
+	identity string
+}
+
+// NewTokenAuthenticator returns a TokenAuthenticator for the given map of tokens to identities.
+func NewTokenAuthenticator(tokens map[string]string) *TokenAuthenticator {
+	a := &TokenAuthenticator{}
+	for token, identity := range tokens {
+		a.tokens = append(a.tokens, staticToken{sha256.Sum256([]byte(token)), identity})
+	}
+	return a
+}
+
+// LoadTokenFile returns a TokenAuthenticator for the tokens in the file at path. Each line of the file holds an
+// identity followed by its token, separated by whitespace. Empty lines and lines starting with # are ignored.
+func LoadTokenFile(path string) (*TokenAuthenticator, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to read token file")
+	}
+
+	tokens := make(map[string]string)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return nil, errors.Errorf("%s:%d: expected an identity and a token", path, n)
+		}
+		if _, ok := tokens[fields[1]]; ok {
+			return nil, errors.Errorf("%s:%d: duplicate token", path, n)
+		}
+		tokens[fields[1]] = fields[0]
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrap(err, "unable to read token file")
+	}
+	return NewTokenAuthenticator(tokens), nil
+}
+
+// Authenticate returns the identity of the bearer token in r's Authorization header. ErrNoCredentials is returned if
+// there is no bearer token.
+func (a *TokenAuthenticator) Authenticate(r *http.Request) (string, error) {
+	token := bearerToken(r)
+	if token == "" {
+		return "", ErrNoCredentials
+	}
+
+	hash := sha256.Sum256([]byte(token))
+	identity := ""
+	for _, t := range a.tokens {
+		if subtle.ConstantTimeCompare(hash[:], t.hash[:]) == 1 {
+			identity = t.identity
+		}
+	}
+	if identity == "" {
+		return "", errors.New("invalid token")
+	}
+	return identity, nil
+}
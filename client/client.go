@@ -0,0 +1,248 @@
+// Package client provides a client for the check runner HTTP API.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Check types accepted by the API. CheckTypeNode is an alias of CheckTypeNodePostStart.
+const (
+	CheckTypeNode          = "node"
+	CheckTypeNodePreStart  = "node-prestart"
+	CheckTypeNodePostStart = "node-poststart"
+	CheckTypeCluster       = "cluster"
+)
+
+// CheckDefinition describes a check as returned when listing checks.
+type CheckDefinition struct {
+	Description string   `json:"description"`
+	Cmd         []string `json:"cmd"`
+	Timeout     string   `json:"timeout"`
+	Type        string   `json:"type,omitempty"`
+	Provider    string   `json:"provider,omitempty"`
+}
+
+// CheckResult is the result of a single check.
+type CheckResult struct {
+	Output string `json:"output"`
+	Status int    `json:"status"`
+
+	// State is the state of the check across runs. It is only set by servers tracking check states.
+	State *CheckState `json:"state,omitempty"`
+}
+
+// CheckState describes how the status of a check developed over past runs.
+type CheckState struct {
+	Status              int        `json:"status"`
+	PreviousStatus      *int       `json:"previous_status,omitempty"`
+	Since               time.Time  `json:"since"`
+	FirstFailed         *time.Time `json:"first_failed,omitempty"`
+	LastOK              *time.Time `json:"last_ok,omitempty"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+}
+
+// Result is the result of running checks. Status is the combined status, the highest status of all checks.
+type Result struct {
+	Status int                     `json:"status"`
+	Checks map[string]*CheckResult `json:"checks"`
+}
+
+// Error is returned when the API responds with an error. This is either a non-200 response, or a 200 response
+// reporting that checks could not be found or executed, in which case Checks lists the affected checks.
+type Error struct {
+	StatusCode int
+	Status     string
+	Message    string
+	Checks     []string
+}
+
+func (e *Error) Error() string {
+	if e.StatusCode != http.StatusOK {
+		return fmt.Sprintf("%s: %s", e.Status, e.Message)
+	}
+	if len(e.Checks) > 0 {
+		return fmt.Sprintf("%s %s", e.Message, strings.Join(e.Checks, ", "))
+	}
+	return e.Message
+}
+
+// Client is a client for the check runner HTTP API.
+type Client struct {
+	// HTTPClient is used for requests to the API. For Unix socket addresses it is set up to dial the socket.
+	HTTPClient *http.Client
+
+	// Token is sent as bearer token in the Authorization header of requests, if set.
+	Token string
+
+	baseURL string
+}
+
+// New returns a *Client for the API at address, which is either an http(s) URL or unix:// followed by the path of a
+// Unix socket. baseURI is the server's --base-uri, it may also be given as the path of an http(s) address.
+func New(address, baseURI string) (*Client, error) {
+	u, err := url.Parse(address)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid address %s", address)
+	}
+
+	c := &Client{HTTPClient: &http.Client{}}
+	switch u.Scheme {
+	case "http", "https":
+		c.baseURL = strings.TrimSuffix(u.Scheme+"://"+u.Host+u.Path, "/")
+	case "unix":
+		socket := u.Path
+		if socket == "" {
+			return nil, errors.Errorf("invalid address %s, missing socket path", address)
+		}
+		c.HTTPClient.Transport = &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, "unix", socket)
+			},
+		}
+		// The host is ignored by the dialer, but required for a valid request URL.
+		c.baseURL = "http://unix"
+	default:
+		return nil, errors.Errorf("invalid address %s, expected an http, https or unix URL", address)
+	}
+
+	if baseURI = strings.Trim(baseURI, "/"); baseURI != "" {
+		c.baseURL += "/" + baseURI
+	}
+	return c, nil
+}
+
+// ListChecks returns the definitions of the checks of checkType. If checks are given, only those are listed.
+func (c *Client) ListChecks(ctx context.Context, checkType string, checks ...string) (map[string]*CheckDefinition, error) {
+	u := c.checkTypeURL(checkType)
+	if len(checks) > 0 {
+		u += "?" + url.Values{"check": checks}.Encode()
+	}
+	req, err := http.NewRequest("GET", u, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := c.do(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(body, &raw); err != nil {
+		return nil, errors.Wrap(err, "unable to decode response")
+	}
+	if err := decodeError(raw); err != nil {
+		return nil, err
+	}
+
+	definitions := make(map[string]*CheckDefinition)
+	if err := json.Unmarshal(body, &definitions); err != nil {
+		return nil, errors.Wrap(err, "unable to decode checks")
+	}
+	return definitions, nil
+}
+
+// RunChecks runs the checks of checkType and returns their results. If checks are given, only those are run.
+func (c *Client) RunChecks(ctx context.Context, checkType string, checks ...string) (*Result, error) {
+	var reqBody []byte
+	if len(checks) > 0 {
+		var err error
+		reqBody, err = json.Marshal(map[string][]string{"check": checks})
+		if err != nil {
+			return nil, err
+		}
+	}
+	req, err := http.NewRequest("POST", c.checkTypeURL(checkType), bytes.NewReader(reqBody))
+	if err != nil {
+		return nil, err
+	}
+	if reqBody != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	body, err := c.do(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(body, &raw); err != nil {
+		return nil, errors.Wrap(err, "unable to decode response")
+	}
+	if err := decodeError(raw); err != nil {
+		return nil, err
+	}
+
+	var result Result
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, errors.Wrap(err, "unable to decode checks")
+	}
+	return &result, nil
+}
+
+func (c *Client) checkTypeURL(checkType string) string {
+	return c.baseURL + "/" + url.PathEscape(checkType) + "/"
+}
+
+// do sends req and returns the response body. Non-200 responses are returned as *Error. Errors sending the request
+// are returned unwrapped as *url.Error, so callers can tell unreachable servers from failed requests.
+func (c *Client) do(ctx context.Context, req *http.Request) ([]byte, error) {
+	client := c.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	if c.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to read response")
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, &Error{
+			StatusCode: resp.StatusCode,
+			Status:     resp.Status,
+			Message:    strings.TrimSpace(string(body)),
+		}
+	}
+	return body, nil
+}
+
+// decodeError returns an *Error if raw is an error response of the runner, which reports checks that could not be
+// found or executed with a 200 status.
+func decodeError(raw map[string]json.RawMessage) error {
+	msg, ok := raw["error"]
+	if !ok {
+		return nil
+	}
+
+	e := &Error{StatusCode: http.StatusOK, Status: "200 OK"}
+	if err := json.Unmarshal(msg, &e.Message); err != nil {
+		// A check named "error" rather than an error response.
+		return nil
+	}
+	if checks, ok := raw["checks"]; ok {
+		json.Unmarshal(checks, &e.Checks)
+	}
+	return e
+}
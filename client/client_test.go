@@ -0,0 +1,242 @@
+package client_test
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/dcos/dcos-check-runner/api"
+	"github.com/dcos/dcos-check-runner/auth"
+	"github.com/dcos/dcos-check-runner/client"
+	"github.com/dcos/dcos-check-runner/runner"
+	"github.com/pkg/errors"
+)
+
+const testConfig = `{
+  "cluster_checks": {
+    "cluster-check": {
+      "description": "Cluster check",
+      "cmd": ["echo", "cluster-check"],
+      "timeout": "1s"
+    }
+  },
+  "node_checks": {
+    "checks": {
+      "node-check": {
+        "description": "Node check",
+        "cmd": ["echo", "node-check"],
+        "timeout": "1s"
+      },
+      "node-check-broken": {
+        "description": "Node check which can't be executed",
+        "cmd": ["./nonexistent-command"],
+        "timeout": "1s"
+      }
+    },
+    "poststart": ["node-check", "node-check-broken"]
+  }
+}`
+
+func newTestHandler(t *testing.T, baseURI string) http.Handler {
+	r, err := runner.NewRunner("master")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Load(strings.NewReader(testConfig)); err != nil {
+		t.Fatal(err)
+	}
+	return api.NewRouter(r, baseURI)
+}
+
+func TestClient(t *testing.T) {
+	s := httptest.NewServer(newTestHandler(t, "/system/checks"))
+	defer s.Close()
+
+	c, err := client.New(s.URL, "/system/checks/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	t.Run("list checks", func(t *testing.T) {
+		checks, err := c.ListChecks(ctx, client.CheckTypeCluster)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected := map[string]*client.CheckDefinition{
+			"cluster-check": {Description: "Cluster check", Cmd: []string{"echo", "cluster-check"}, Timeout: "1s"},
+		}
+		if !reflect.DeepEqual(checks, expected) {
+			t.Fatalf("expected %+v, got %+v", expected, checks)
+		}
+	})
+
+	t.Run("list selected checks", func(t *testing.T) {
+		checks, err := c.ListChecks(ctx, client.CheckTypeNode, "node-check")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(checks) != 1 || checks["node-check"] == nil {
+			t.Fatalf("expected only node-check, got %+v", checks)
+		}
+	})
+
+	t.Run("run selected checks", func(t *testing.T) {
+		result, err := c.RunChecks(ctx, client.CheckTypeNode, "node-check")
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected := &client.Result{
+			Status: 0,
+			Checks: map[string]*client.CheckResult{"node-check": {Output: "node-check\n", Status: 0}},
+		}
+		if runtime.GOOS == "windows" {
+			expected.Checks["node-check"].Output = "node-check\r\n"
+		}
+		if !reflect.DeepEqual(result, expected) {
+			t.Fatalf("expected %+v, got %+v", expected, result)
+		}
+	})
+
+	t.Run("checks fail to execute", func(t *testing.T) {
+		_, err := c.RunChecks(ctx, client.CheckTypeNode)
+		apiErr, ok := errors.Cause(err).(*client.Error)
+		if !ok {
+			t.Fatalf("expected *client.Error, got %#v", err)
+		}
+		if apiErr.StatusCode != http.StatusOK || apiErr.Message != "One or more requested checks failed to execute." || len(apiErr.Checks) != 1 {
+			t.Fatalf("unexpected error %+v", apiErr)
+		}
+	})
+
+	t.Run("missing checks", func(t *testing.T) {
+		_, err := c.RunChecks(ctx, client.CheckTypeNode, "nonexistent")
+		apiErr, ok := errors.Cause(err).(*client.Error)
+		if !ok {
+			t.Fatalf("expected *client.Error, got %#v", err)
+		}
+		if apiErr.StatusCode != http.StatusNotFound || apiErr.Error() != "404 Not Found: missing checks: [nonexistent]" {
+			t.Fatalf("unexpected error %+v", apiErr)
+		}
+	})
+
+	t.Run("wrong base URI", func(t *testing.T) {
+		c, err := client.New(s.URL+"/other", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = c.ListChecks(ctx, client.CheckTypeNode)
+		if apiErr, ok := errors.Cause(err).(*client.Error); !ok || apiErr.StatusCode != http.StatusNotFound {
+			t.Fatalf("expected 404 error, got %#v", err)
+		}
+	})
+}
+
+func TestClientUnixSocket(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Unix sockets are not supported")
+	}
+
+	dir, err := ioutil.TempDir("", "client-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	socket := filepath.Join(dir, "api.sock")
+	l, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &http.Server{Handler: newTestHandler(t, "")}
+	go s.Serve(l)
+	defer s.Close()
+
+	c, err := client.New("unix://"+socket, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := c.RunChecks(context.Background(), client.CheckTypeCluster)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Status != 0 || result.Checks["cluster-check"] == nil {
+		t.Fatalf("unexpected result %+v", result)
+	}
+}
+
+func TestNew(t *testing.T) {
+	for _, address := range []string{"", "localhost:8000", "ftp://localhost", "unix://"} {
+		if _, err := client.New(address, ""); err == nil {
+			t.Fatalf("expected error for address %q", address)
+		}
+	}
+}
+
+func TestClientCheckStates(t *testing.T) {
+	r, err := runner.NewRunner("master")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Load(strings.NewReader(testConfig)); err != nil {
+		t.Fatal(err)
+	}
+	r.States, err = runner.NewStateTracker("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := httptest.NewServer(api.NewRouter(r, ""))
+	defer s.Close()
+
+	c, err := client.New(s.URL, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 1; i <= 2; i++ {
+		result, err := c.RunChecks(context.Background(), client.CheckTypeCluster)
+		if err != nil {
+			t.Fatal(err)
+		}
+		state := result.Checks["cluster-check"].State
+		if state == nil || state.Status != 0 || state.LastOK == nil || state.FirstFailed != nil || state.ConsecutiveFailures != 0 {
+			t.Fatalf("unexpected state after run %d: %+v", i, state)
+		}
+	}
+}
+
+func TestClientToken(t *testing.T) {
+	r, err := runner.NewRunner("master")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Load(strings.NewReader(testConfig)); err != nil {
+		t.Fatal(err)
+	}
+	authenticator := auth.NewTokenAuthenticator(map[string]string{"s3cr3t": "ci"})
+	s := httptest.NewServer(api.NewRouter(r, "", api.WithAuth(authenticator, nil)))
+	defer s.Close()
+
+	c, err := client.New(s.URL, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = c.RunChecks(context.Background(), client.CheckTypeCluster)
+	if clientErr, ok := err.(*client.Error); !ok || clientErr.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("expected an unauthorized error, got %v", err)
+	}
+
+	c.Token = "s3cr3t"
+	if _, err := c.RunChecks(context.Background(), client.CheckTypeCluster); err != nil {
+		t.Fatal(err)
+	}
+}
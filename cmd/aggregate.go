@@ -0,0 +1,104 @@
+package cmd
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/dcos/dcos-check-runner/aggregate"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var aggregateCmd = &cobra.Command{
+	Use:   "aggregate [check...]",
+	Short: "Run node checks on all cluster nodes",
+	Long: `Run node checks on all cluster nodes through their check runner HTTP API and print the per-node results
+and the cluster-wide status. Nodes are discovered with --nodes, --nodes-file and --mesos-state.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		a, err := newAggregator()
+		if err != nil {
+			logrus.Fatal(err)
+		}
+		if a == nil {
+			logrus.Fatal("no node source configured, use --nodes, --nodes-file or --mesos-state")
+		}
+
+		result, err := a.Run(context.Background(), args...)
+		if err != nil {
+			logrus.Fatalf("unable to run node checks: %s", err)
+		}
+
+		printJSON(result)
+		os.Exit(result.Status)
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(aggregateCmd)
+	addNodeSourceFlags(aggregateCmd)
+}
+
+// addNodeSourceFlags adds the flags configuring node discovery for aggregated node checks to cmd.
+func addNodeSourceFlags(cmd *cobra.Command) {
+	cmd.PersistentFlags().StringSliceVar(&defaultConfig.FlagNodes, "nodes", nil,
+		"Hosts of nodes to run node checks on")
+	cmd.PersistentFlags().StringVar(&defaultConfig.FlagNodesFile, "nodes-file", "",
+		"File listing the hosts of nodes to run node checks on, one per line, optionally followed by the node's role")
+	cmd.PersistentFlags().StringVar(&defaultConfig.FlagMesosState, "mesos-state", "",
+		"URL or file of the Mesos master state used to discover agents, e.g. http://leader.mesos:5050/state")
+	cmd.PersistentFlags().StringVar(&defaultConfig.FlagNodeURL, "node-url", aggregate.DefaultNodeURL,
+		"Base URL of a node's check runner API, {host} is replaced with the node's host")
+	cmd.PersistentFlags().StringVar(&defaultConfig.FlagNodeTimeout, "node-timeout", aggregate.DefaultTimeout.String(),
+		"Timeout for running the checks of a single node")
+	cmd.PersistentFlags().StringVar(&defaultConfig.FlagNodeTokenFile, "node-token-file", "",
+		"File containing a bearer token to authenticate with at the nodes, read for each run")
+	cmd.PersistentFlags().StringVar(&defaultConfig.FlagNodeTLSCA, "node-tls-ca", "",
+		"PEM encoded CA certificates to verify nodes served over https with, instead of the system's")
+	cmd.PersistentFlags().StringVar(&defaultConfig.FlagNodeTLSClientCert, "node-tls-client-cert", "",
+		"PEM encoded client certificate to authenticate with at nodes served over https")
+	cmd.PersistentFlags().StringVar(&defaultConfig.FlagNodeTLSClientKey, "node-tls-client-key", "",
+		"PEM encoded private key of --node-tls-client-cert")
+}
+
+// newAggregator returns an *aggregate.Aggregator for the configured node sources, or nil if none are configured.
+func newAggregator() (*aggregate.Aggregator, error) {
+	var sources aggregate.MultiSource
+	if len(defaultConfig.FlagNodes) > 0 {
+		var nodes aggregate.StaticSource
+		for _, host := range defaultConfig.FlagNodes {
+			nodes = append(nodes, aggregate.Node{Host: host})
+		}
+		sources = append(sources, nodes)
+	}
+	if defaultConfig.FlagNodesFile != "" {
+		sources = append(sources, aggregate.FileSource(defaultConfig.FlagNodesFile))
+	}
+	if defaultConfig.FlagMesosState != "" {
+		sources = append(sources, aggregate.MesosStateSource{Location: defaultConfig.FlagMesosState})
+	}
+	if len(sources) == 0 {
+		return nil, nil
+	}
+
+	timeout, err := time.ParseDuration(defaultConfig.FlagNodeTimeout)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid node timeout")
+	}
+
+	a := aggregate.NewAggregator(sources)
+	a.NodeURL = defaultConfig.FlagNodeURL
+	a.Timeout = timeout
+	a.TokenFile = defaultConfig.FlagNodeTokenFile
+	if defaultConfig.FlagNodeTLSCA != "" || defaultConfig.FlagNodeTLSClientCert != "" || defaultConfig.FlagNodeTLSClientKey != "" {
+		tlsConfig, err := newClientTLSConfig(defaultConfig.FlagNodeTLSCA, defaultConfig.FlagNodeTLSClientCert,
+			defaultConfig.FlagNodeTLSClientKey)
+		if err != nil {
+			return nil, err
+		}
+		a.Client = &http.Client{Transport: &http.Transport{TLSClientConfig: tlsConfig}}
+	}
+	return a, nil
+}
@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"github.com/dcos/dcos-check-runner/api"
+	"github.com/dcos/dcos-check-runner/auth"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// addAuthFlags adds the flags configuring authentication and authorization of the HTTP API to cmd.
+func addAuthFlags(cmd *cobra.Command) {
+	cmd.PersistentFlags().StringVar(&defaultConfig.FlagAuthJWTPublicKey, "auth-jwt-public-key", "",
+		"Require requests to carry a JWT signed with RS256 by the PEM encoded RSA public key in the given file")
+	cmd.PersistentFlags().StringVar(&defaultConfig.FlagAuthTokenFile, "auth-token-file", "",
+		"Require requests to carry one of the bearer tokens in the given file, one \"<identity> <token>\" per line")
+	cmd.PersistentFlags().StringVar(&defaultConfig.FlagAuthPolicyFile, "auth-policy-file", "",
+		"JSON file mapping identities to the actions they are allowed to perform per check type")
+}
+
+// newAuthOption returns an api.Option enabling the authentication and authorization configured by the auth flags, or
+// nil if no authentication is configured.
+func newAuthOption() (api.Option, error) {
+	authenticators, err := newAuthenticators()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(authenticators) == 0 {
+		if defaultConfig.FlagAuthPolicyFile != "" {
+			return nil, errors.New("--auth-policy-file requires --auth-jwt-public-key or --auth-token-file")
+		}
+		return nil, nil
+	}
+
+	var policy auth.Policy
+	if defaultConfig.FlagAuthPolicyFile != "" {
+		p, err := auth.LoadPolicy(defaultConfig.FlagAuthPolicyFile)
+		if err != nil {
+			return nil, err
+		}
+		policy = p
+	}
+	return api.WithAuth(authenticators, policy), nil
+}
+
+// newAuthenticators returns the authenticators configured by the auth flags.
+func newAuthenticators() (auth.Chain, error) {
+	var authenticators auth.Chain
+	if defaultConfig.FlagAuthJWTPublicKey != "" {
+		a, err := auth.LoadJWTAuthenticator(defaultConfig.FlagAuthJWTPublicKey)
+		if err != nil {
+			return nil, err
+		}
+		authenticators = append(authenticators, a)
+	}
+	if defaultConfig.FlagAuthTokenFile != "" {
+		a, err := auth.LoadTokenFile(defaultConfig.FlagAuthTokenFile)
+		if err != nil {
+			return nil, err
+		}
+		authenticators = append(authenticators, a)
+	}
+	return authenticators, nil
+}
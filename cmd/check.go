@@ -19,10 +19,19 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"os/signal"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"syscall"
 
+	"github.com/dcos/dcos-check-runner/output"
 	"github.com/dcos/dcos-check-runner/runner"
+	"github.com/dcos/dcos-check-runner/sshexec"
+	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
+	"golang.org/x/crypto/ssh/terminal"
 )
 
 const (
@@ -31,7 +40,16 @@ const (
 	checkTypeNodePostStart = "node-poststart"
 )
 
-var list bool
+var (
+	list         bool
+	outputFormat string
+	textfileDir  string
+
+	sshTarget             string
+	sshKeyFile            string
+	sshKnownHostsFile     string
+	sshInsecureIgnoreHost bool
+)
 
 // checkCmd represents the check command
 var checkCmd = &cobra.Command{
@@ -39,68 +57,247 @@ var checkCmd = &cobra.Command{
 	Short: "Execute a DC/OS check",
 	Long:  `A DC/OS check can be one of the following types: cluster, node-prestart, node-poststart`,
 	Run: func(cmd *cobra.Command, args []string) {
-		var selectiveChecks []string
 		if len(args) == 0 {
 			cmd.Usage()
 			return
-		} else if len(args) > 1 {
-			selectiveChecks = args[1:]
 		}
 
-		r, err := runner.NewRunner(defaultConfig.FlagRole)
+		// Exit only after the deferred cleanup of runCheck, e.g. closing the SSH connection and the history store.
+		code, err := runCheck(args[0], args[1:])
 		if err != nil {
 			logrus.Fatal(err)
 		}
+		os.Exit(code)
+	},
+}
 
-		if err := r.LoadFromFile(checkCfgFile); err != nil {
-			logrus.Fatal(err)
+// runCheck runs the checks of checkType, or selectiveChecks of them if given, writes their output and returns the
+// exit code.
+func runCheck(checkType string, selectiveChecks []string) (int, error) {
+	if err := validateOutputFormat(outputFormat); err != nil {
+		return 0, err
+	}
+
+	r, err := runner.NewRunner(defaultConfig.FlagRole)
+	if err != nil {
+		return 0, err
+	}
+
+	cfgFiles, err := checkConfigFiles()
+	if err != nil {
+		return 0, err
+	}
+	if err := r.LoadFromFiles(cfgFiles...); err != nil {
+		return 0, err
+	}
+
+	if sshTarget != "" {
+		executor, err := newSSHExecutor(r)
+		if err != nil {
+			return 0, err
 		}
+		defer executor.Close()
+		r.Executor = executor
 
-		// Set up environment for running check commands.
-		for k, v := range r.CheckEnv {
-			os.Setenv(k, v)
+		selectiveChecks, err = remoteChecks(r, checkType, selectiveChecks)
+		if err != nil {
+			return 0, err
 		}
+	}
 
-		ctx, cancel := context.WithCancel(context.Background())
-		defer cancel()
+	if defaultConfig.FlagStateFile != "" {
+		r.States, err = runner.NewStateTracker(defaultConfig.FlagStateFile)
+		if err != nil {
+			return 0, err
+		}
+	}
 
-		var rs *runner.CombinedResponse
+	store, err := openHistory()
+	if err != nil {
+		return 0, err
+	}
+	if store != nil {
+		defer store.Close()
+		r.Observers = append(r.Observers, store)
+	}
 
-		switch args[0] {
-		case checkTypeCluster:
-			rs, err = r.Cluster(ctx, list, selectiveChecks...)
-			if err != nil {
-				logrus.Fatalf("unable to execute cluster checks: %s", err)
-			}
-		case checkTypeNodePreStart:
-			rs, err = r.PreStart(ctx, list, selectiveChecks...)
-			if err != nil {
-				logrus.Fatalf("unable to execute prestart checks: %s", err)
-			}
-		case checkTypeNodePostStart:
-			rs, err = r.PostStart(ctx, list, selectiveChecks...)
-			if err != nil {
-				logrus.Fatalf("unable to execute poststart checks: %s", err)
-			}
-		default:
-			logrus.Fatalf("invalid check type %s", args[0])
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Check commands run in their own process group, so they don't receive signals sent to the terminal's
+	// foreground process group. Cancel the run instead, which kills them.
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGTERM, os.Interrupt)
+	go func() {
+		<-signals
+		cancel()
+	}()
+
+	var rs *runner.CombinedResponse
+
+	switch checkType {
+	case checkTypeCluster:
+		rs, err = r.Cluster(ctx, list, selectiveChecks...)
+		if err != nil {
+			return 0, errors.Wrap(err, "unable to execute cluster checks")
 		}
+	case checkTypeNodePreStart:
+		rs, err = r.PreStart(ctx, list, selectiveChecks...)
+		if err != nil {
+			return 0, errors.Wrap(err, "unable to execute prestart checks")
+		}
+	case checkTypeNodePostStart:
+		rs, err = r.PostStart(ctx, list, selectiveChecks...)
+		if err != nil {
+			return 0, errors.Wrap(err, "unable to execute poststart checks")
+		}
+	default:
+		return 0, errors.Errorf("invalid check type %s", checkType)
+	}
 
-		os.Exit(emitOutput(rs))
-	},
+	return rs.Status(), emitOutput(rs, checkType)
 }
 
 func init() {
 	RootCmd.AddCommand(checkCmd)
 
 	checkCmd.PersistentFlags().BoolVar(&list, "list", false, "List runner")
+	checkCmd.PersistentFlags().StringVarP(&outputFormat, "output", "o", "json",
+		fmt.Sprintf("Output format, one of json, %s", strings.Join(output.Formats(), ", ")))
+	checkCmd.PersistentFlags().StringVar(&textfileDir, "textfile-dir", "",
+		"node_exporter textfile collector directory to write prom-textfile output to instead of stdout")
+	addHistoryFlags(checkCmd)
+	checkCmd.PersistentFlags().StringVar(&defaultConfig.FlagStateFile, "state-file", "",
+		"Track check states across runs in the given file and include them in the output")
+	checkCmd.PersistentFlags().StringVar(&sshTarget, "ssh", "",
+		"Run check commands on a remote node over SSH, given as [user@]host[:port]")
+	checkCmd.PersistentFlags().StringVar(&sshKeyFile, "ssh-key", "",
+		"Private key file for SSH authentication, in addition to keys from SSH_AUTH_SOCK")
+	checkCmd.PersistentFlags().StringVar(&sshKnownHostsFile, "ssh-known-hosts", "",
+		"known_hosts file used to verify the remote host key (default is ~/.ssh/known_hosts)")
+	checkCmd.PersistentFlags().BoolVar(&sshInsecureIgnoreHost, "ssh-insecure-ignore-host-key", false,
+		"Don't verify the remote host key")
+}
+
+// newSSHExecutor connects to the node given by the --ssh flag and returns an executor running check commands there
+// with the check environment from the config.
+func newSSHExecutor(r *runner.Runner) (*sshexec.Executor, error) {
+	var defaultUser string
+	if u, err := user.Current(); err == nil {
+		defaultUser = u.Username
+	}
+	sshUser, addr, err := sshexec.ParseTarget(sshTarget, defaultUser)
+	if err != nil {
+		return nil, err
+	}
+
+	knownHostsFile := sshKnownHostsFile
+	if knownHostsFile == "" && !sshInsecureIgnoreHost {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, err
+		}
+		knownHostsFile = filepath.Join(home, ".ssh", "known_hosts")
+	}
+
+	sshConfig, closeAgent, err := sshexec.NewClientConfig(sshexec.Options{
+		User:                  sshUser,
+		KeyFile:               sshKeyFile,
+		KnownHostsFile:        knownHostsFile,
+		InsecureIgnoreHostKey: sshInsecureIgnoreHost,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// The SSH agent is only needed for the handshake.
+	defer closeAgent()
+
+	executor, err := sshexec.Dial(addr, sshConfig)
+	if err != nil {
+		return nil, err
+	}
+	executor.Env = r.CheckEnv
+	return executor, nil
 }
 
-func emitOutput(rc *runner.CombinedResponse) int {
+// remoteChecks returns the checks of checkType which can be run on a remote node. Built-in and provider checks run
+// inside the check runner process, so they would check the local host instead of the remote node. They are skipped
+// with a warning unless explicitly selected, in which case an error is returned.
+func remoteChecks(r *runner.Runner, checkType string, selectiveChecks []string) ([]string, error) {
+	checks := r.NodeChecks.Checks
+	var names []string
+	switch checkType {
+	case checkTypeCluster:
+		checks = r.ClusterChecks
+		for name := range checks {
+			names = append(names, name)
+		}
+	case checkTypeNodePreStart:
+		names = r.NodeChecks.PreStart
+	case checkTypeNodePostStart:
+		names = r.NodeChecks.PostStart
+	default:
+		return nil, fmt.Errorf("invalid check type %s", checkType)
+	}
+
+	if len(selectiveChecks) > 0 {
+		for _, name := range selectiveChecks {
+			if c, ok := checks[name]; ok && (c.Type != "" || c.Provider != "") {
+				return nil, fmt.Errorf("check %s runs in-process and can't be run over SSH", name)
+			}
+		}
+		return selectiveChecks, nil
+	}
+
+	var remote []string
+	for _, name := range names {
+		if c, ok := checks[name]; ok && (c.Type != "" || c.Provider != "") {
+			logrus.Warnf("Skipping check %s, which runs in-process and can't be run over SSH", name)
+			continue
+		}
+		remote = append(remote, name)
+	}
+	if len(names) > 0 && len(remote) == 0 {
+		return nil, fmt.Errorf("none of the %s checks can be run over SSH", checkType)
+	}
+	return remote, nil
+}
+
+// validateOutputFormat returns an error if format is not supported by emitOutput.
+func validateOutputFormat(format string) error {
+	if textfileDir != "" && format != "prom-textfile" {
+		return fmt.Errorf("--textfile-dir requires --output prom-textfile")
+	}
+	if format == "json" {
+		return nil
+	}
+	for _, f := range output.Formats() {
+		if f == format {
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid output format %s, expected one of json, %s", format, strings.Join(output.Formats(), ", "))
+}
+
+// emitOutput writes rc in the output format, either to stdout or to the textfile directory.
+func emitOutput(rc *runner.CombinedResponse, checkType string) error {
+	if outputFormat != "json" {
+		opts := output.Options{
+			Color: terminal.IsTerminal(int(os.Stdout.Fd())),
+			Name:  checkType,
+		}
+		if textfileDir != "" {
+			path := filepath.Join(textfileDir, "dcos-check-runner-"+checkType+".prom")
+			return output.WriteFile(path, outputFormat, rc, opts)
+		}
+		return output.Write(os.Stdout, outputFormat, rc, opts)
+	}
+
 	body, err := json.MarshalIndent(rc, "", "  ")
 	if err != nil {
-		logrus.Fatal(err)
+		return err
 	}
 	fmt.Println(string(body))
-	return rc.Status()
+	return nil
 }
@@ -0,0 +1,124 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/dcos/dcos-check-runner/history"
+	"github.com/dcos/dcos-check-runner/output"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var (
+	historySuite  string
+	historySince  string
+	historyUntil  string
+	historyLimit  int
+	historyOutput string
+)
+
+var historyCmd = &cobra.Command{
+	Use:   "history [check...]",
+	Short: "Show recorded check results",
+	Long: `Show the check results recorded in --history-dir by the check and http-server commands, oldest first.
+--since and --until take RFC 3339 timestamps or durations before now, e.g. 2h.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if defaultConfig.FlagHistoryDir == "" {
+			logrus.Fatal("--history-dir is required")
+		}
+
+		q := history.Query{Checks: args, Suite: historySuite, Limit: historyLimit}
+		now := time.Now()
+		if historySince != "" {
+			t, err := history.ParseTime(historySince, now)
+			if err != nil {
+				logrus.Fatal(err)
+			}
+			q.Since = t
+		}
+		if historyUntil != "" {
+			t, err := history.ParseTime(historyUntil, now)
+			if err != nil {
+				logrus.Fatal(err)
+			}
+			q.Until = t
+		}
+
+		records, err := history.QueryDir(defaultConfig.FlagHistoryDir, q)
+		if err != nil {
+			logrus.Fatal(err)
+		}
+
+		switch historyOutput {
+		case "json":
+			printJSON(records)
+		case "table":
+			tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+			fmt.Fprintln(tw, "TIME\tSUITE\tCHECK\tSTATUS\tDURATION\tOUTPUT")
+			for _, r := range records {
+				status, out := output.StatusName(r.Status), r.Output
+				if r.Error != "" {
+					status, out = output.StatusName(output.StatusError), r.Error
+				}
+				fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", r.Time.Local().Format(time.RFC3339), r.Suite, r.Check,
+					status, r.Duration, firstLine(out))
+			}
+			tw.Flush()
+		default:
+			logrus.Fatalf("invalid output format %s, expected json or table", historyOutput)
+		}
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(historyCmd)
+
+	historyCmd.PersistentFlags().StringVar(&defaultConfig.FlagHistoryDir, "history-dir", "",
+		"Directory the check results are recorded in")
+	historyCmd.PersistentFlags().StringVar(&historySuite, "suite", "",
+		"Only show results of the given check type: cluster, node-prestart or node-poststart")
+	historyCmd.PersistentFlags().StringVar(&historySince, "since", "", "Only show results since the given time")
+	historyCmd.PersistentFlags().StringVar(&historyUntil, "until", "", "Only show results before the given time")
+	historyCmd.PersistentFlags().IntVar(&historyLimit, "limit", 0, "Only show the given number of most recent results")
+	historyCmd.PersistentFlags().StringVarP(&historyOutput, "output", "o", "table", "Output format, one of json, table")
+}
+
+// addHistoryFlags adds the flags configuring the history of check results to cmd.
+func addHistoryFlags(cmd *cobra.Command) {
+	cmd.PersistentFlags().StringVar(&defaultConfig.FlagHistoryDir, "history-dir", "",
+		"Record check results in the given directory")
+	cmd.PersistentFlags().IntVar(&defaultConfig.FlagHistoryMaxSize, "history-max-size", history.DefaultMaxSize>>20,
+		"Maximum size of the recorded check results in MiB")
+	cmd.PersistentFlags().StringVar(&defaultConfig.FlagHistoryMaxAge, "history-max-age", history.DefaultMaxAge.String(),
+		"Maximum age of the recorded check results")
+}
+
+// openHistory opens the history store configured by the history flags, or returns nil if it is not configured.
+func openHistory() (*history.Store, error) {
+	if defaultConfig.FlagHistoryDir == "" {
+		return nil, nil
+	}
+
+	maxAge, err := time.ParseDuration(defaultConfig.FlagHistoryMaxAge)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid history max age")
+	}
+	return history.Open(defaultConfig.FlagHistoryDir, history.Options{
+		MaxSize: int64(defaultConfig.FlagHistoryMaxSize) << 20,
+		MaxAge:  maxAge,
+	})
+}
+
+// firstLine returns the first line of s.
+func firstLine(s string) string {
+	for i, c := range s {
+		if c == '\n' || c == '\r' {
+			return s[:i]
+		}
+	}
+	return s
+}
@@ -1,15 +1,21 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"net"
 	"net/http"
 	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
 
-	"github.com/coreos/go-systemd/activation"
 	"github.com/dcos/dcos-check-runner/api"
+	"github.com/dcos/dcos-check-runner/listen"
 	"github.com/dcos/dcos-check-runner/runner"
-	"github.com/pkg/errors"
+	"github.com/dcos/dcos-check-runner/systemd"
+	"github.com/dcos/dcos-go/dcos"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 )
@@ -23,31 +29,156 @@ var httpServerCmd = &cobra.Command{
 			logrus.Fatal(err)
 		}
 
-		if err := r.LoadFromFile(checkCfgFile); err != nil {
+		cfgFiles, err := checkConfigFiles()
+		if err != nil {
+			logrus.Fatal(err)
+		}
+		if err := r.LoadFromFiles(cfgFiles...); err != nil {
+			logrus.Fatal(err)
+		}
+
+		// Check states are always tracked in memory, and persisted if a state file is given.
+		r.States, err = runner.NewStateTracker(defaultConfig.FlagStateFile)
+		if err != nil {
+			logrus.Fatal(err)
+		}
+
+		runsMaxAge, err := time.ParseDuration(defaultConfig.FlagRunsMaxAge)
+		if err != nil {
+			logrus.Fatalf("invalid runs max age: %s", err)
+		}
+		routerOpts := []api.Option{api.WithRunRetention(defaultConfig.FlagRunsMaxFinished, runsMaxAge)}
+		authOpt, err := newAuthOption()
+		if err != nil {
+			logrus.Fatal(err)
+		}
+		if authOpt != nil {
+			routerOpts = append(routerOpts, authOpt)
+		}
+
+		if defaultConfig.FlagProbeConfig != "" {
+			probes, err := api.LoadProbes(defaultConfig.FlagProbeConfig)
+			if err != nil {
+				logrus.Fatal(err)
+			}
+			routerOpts = append(routerOpts, api.WithProbes(probes))
+		}
+
+		a, err := newAggregator()
+		if err != nil {
+			logrus.Fatal(err)
+		}
+		if a != nil {
+			if defaultConfig.FlagRole != dcos.RoleMaster {
+				logrus.Fatalf("node check aggregation is only available on %s nodes", dcos.RoleMaster)
+			}
+			routerOpts = append(routerOpts, api.WithAggregator(a))
+		}
+
+		store, err := openHistory()
+		if err != nil {
+			logrus.Fatal(err)
+		}
+		if store != nil {
+			r.Observers = append(r.Observers, store)
+			routerOpts = append(routerOpts, api.WithHistory(store))
+		}
+
+		notifier, err := newNotifier()
+		if err != nil {
+			logrus.Fatal(err)
+		}
+		if notifier != nil {
+			r.Observers = append(r.Observers, notifier)
+		}
+
+		// Notifications are only sent if the server was started by systemd with NOTIFY_SOCKET set.
+		sd := systemd.NewNotifier()
+		r.Observers = append(r.Observers, sd)
+
+		router := api.NewRouter(r, defaultConfig.FlagBaseURI, routerOpts...)
+
+		tlsConfig, err := newTLSConfig()
+		if err != nil {
+			logrus.Fatal(err)
+		}
+
+		listenConfigs, err := newListenConfigs()
+		if err != nil {
+			logrus.Fatal(err)
+		}
+		listeners, err := openListeners(listenConfigs, tlsConfig)
+		if err != nil {
 			logrus.Fatal(err)
 		}
 
-		// Set up environment for running check commands.
-		for k, v := range r.CheckEnv {
-			os.Setenv(k, v)
+		drainTimeout, err := time.ParseDuration(defaultConfig.FlagDrainTimeout)
+		if err != nil {
+			logrus.Fatalf("invalid drain timeout: %s", err)
 		}
 
-		router := api.NewRouter(r, defaultConfig.FlagBaseURI)
-		var serveErr error
-		if defaultConfig.FlagSystemdSocket {
-			listener, err := getSystemdSocket()
+		// Each listener is served by its own server, as they may authenticate requests differently.
+		var (
+			servers []*http.Server
+			addrs   []string
+		)
+		serveErr := make(chan error, len(listeners))
+		for _, l := range listeners {
+			handler, err := newListenerHandler(router, l.Config)
 			if err != nil {
 				logrus.Fatal(err)
 			}
-			logrus.Infof("Listening at %s", listener.Addr().String())
-			serveErr = http.Serve(listener, router)
-		} else {
-			addr := fmt.Sprintf("%s:%d", defaultConfig.FlagHost, defaultConfig.FlagPort)
-			logrus.Infof("Listening at %s", addr)
-			serveErr = http.ListenAndServe(addr, router)
+			server := &http.Server{Handler: handler}
+			servers = append(servers, server)
+			addrs = append(addrs, l.Addr().String())
+
+			msg := fmt.Sprintf("Listening at %s", l.Addr().String())
+			if tlsConfig != nil && l.Config.Type != listen.TypeUnix {
+				msg += " with TLS"
+			}
+			if l.Config.Auth == listen.AuthNone {
+				msg += " without authentication"
+			}
+			logrus.Info(msg)
+
+			go func(l net.Listener) {
+				serveErr <- server.Serve(l)
+			}(l.Listener)
+		}
+
+		sd.Status(fmt.Sprintf("Listening at %s", strings.Join(addrs, ", ")))
+		sd.Ready()
+		watchdogCtx, stopWatchdog := context.WithCancel(context.Background())
+		go sd.Watchdog(watchdogCtx, r.Healthy)
+
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, syscall.SIGTERM, os.Interrupt, syscall.SIGHUP)
+	loop:
+		for {
+			select {
+			case err := <-serveErr:
+				logrus.Fatal(err)
+			case sig := <-signals:
+				if sig == syscall.SIGHUP {
+					reload(r, sd)
+					continue
+				}
+				logrus.Infof("Received %s, shutting down", sig)
+				break loop
+			}
 		}
+		signal.Stop(signals)
 
-		logrus.Fatal(serveErr)
+		sd.Stopping()
+		code := shutdown(servers, r, drainTimeout)
+		stopWatchdog()
+		if notifier != nil {
+			notifier.Close()
+		}
+		if store != nil {
+			store.Close()
+		}
+		os.Exit(code)
 	},
 }
 
@@ -55,20 +186,84 @@ func init() {
 	RootCmd.AddCommand(httpServerCmd)
 	httpServerCmd.PersistentFlags().StringVarP(&defaultConfig.FlagHost, "host", "a", "0.0.0.0", "Server's host")
 	httpServerCmd.PersistentFlags().IntVarP(&defaultConfig.FlagPort, "port", "p", 8000, "Server's TCP port")
-	httpServerCmd.PersistentFlags().BoolVar(&defaultConfig.FlagSystemdSocket, "systemd-socket", false, "Listen on systemd socket")
+	httpServerCmd.PersistentFlags().BoolVar(&defaultConfig.FlagSystemdSocket, "systemd-socket", false, "Listen on systemd sockets")
+	httpServerCmd.PersistentFlags().StringVar(&defaultConfig.FlagListenConfig, "listen-config", "",
+		"JSON file configuring TCP, Unix domain socket and systemd socket listeners, instead of --host, --port and --systemd-socket")
+	httpServerCmd.PersistentFlags().StringVar(&defaultConfig.FlagDrainTimeout, "drain-timeout", "30s",
+		"Maximum time to wait for running checks on shutdown before canceling them")
 	httpServerCmd.PersistentFlags().StringVar(&defaultConfig.FlagBaseURI, "base-uri", "", "Server's base URI")
+	httpServerCmd.PersistentFlags().IntVar(&defaultConfig.FlagRunsMaxFinished, "runs-max-finished", api.DefaultRunsMaxFinished,
+		"Maximum number of finished asynchronous runs to retain")
+	httpServerCmd.PersistentFlags().StringVar(&defaultConfig.FlagRunsMaxAge, "runs-max-age", api.DefaultRunsMaxAge.String(),
+		"Maximum age of retained finished asynchronous runs")
+	httpServerCmd.PersistentFlags().StringVar(&defaultConfig.FlagProbeConfig, "probe-config", "",
+		"JSON file configuring probe endpoints, which respond with 503 if their checks fail")
+	addTLSFlags(httpServerCmd)
+	addAuthFlags(httpServerCmd)
+	addNodeSourceFlags(httpServerCmd)
+	addHistoryFlags(httpServerCmd)
+	addWebhookFlags(httpServerCmd)
+	httpServerCmd.PersistentFlags().StringVar(&defaultConfig.FlagStateFile, "state-file", "",
+		"Persist check states across restarts in the given file")
 }
 
-func getSystemdSocket() (net.Listener, error) {
-	listeners, err := activation.Listeners()
+// reload reloads the check configuration of r, notifying systemd while it reloads. If the configuration is invalid, the
+// previous configuration is kept.
+func reload(r *runner.Runner, sd *systemd.Notifier) {
+	sd.Reloading()
+	defer sd.Ready()
+
+	// Files added to or removed from the config directory are picked up.
+	cfgFiles, err := checkConfigFiles()
+	if err == nil {
+		logrus.Infof("Reloading check configuration from %s", strings.Join(cfgFiles, ", "))
+		err = r.ReloadFromFiles(cfgFiles...)
+	}
 	if err != nil {
-		return nil, errors.Wrap(err, "Error getting systemd socket")
+		logrus.Errorf("Unable to reload check configuration, keeping the previous configuration: %s", err)
+		sd.Status(fmt.Sprintf("Unable to reload check configuration: %s", err))
+		return
+	}
+	logrus.Infof("Reloaded check configuration %s", r.ConfigHash())
+}
+
+// shutdown stops servers and r from accepting new requests and runs, and waits up to timeout for the requests and
+// runs in progress to finish. Runs which don't finish in time are canceled. It returns the exit code of the server,
+// which is 1 if draining timed out.
+func shutdown(servers []*http.Server, r *runner.Runner, timeout time.Duration) int {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	// Runs are drained concurrently with the requests, because the requests of synchronous runs wait for them.
+	drained := make(chan error, 1)
+	go func() {
+		drained <- r.Shutdown(ctx)
+	}()
+
+	serversErr := make(chan error, len(servers))
+	for _, server := range servers {
+		go func(server *http.Server) {
+			err := server.Shutdown(ctx)
+			if err != nil {
+				server.Close()
+			}
+			serversErr <- err
+		}(server)
+	}
+
+	code := 0
+	for range servers {
+		if err := <-serversErr; err != nil && code == 0 {
+			logrus.Warnf("Closing open connections: %s", err)
+			code = 1
+		}
 	}
-	if len(listeners) != 1 {
-		return nil, errors.New(fmt.Sprintf("Expected 1 systemd socket, found %d", len(listeners)))
+	if err := <-drained; err != nil {
+		logrus.Warnf("Canceled running checks after %s: %s", timeout, err)
+		code = 1
 	}
-	if listeners[0] == nil {
-		return nil, errors.New("No systemd socket found")
+	if code == 0 {
+		logrus.Info("Shutdown complete")
 	}
-	return listeners[0], nil
+	return code
 }
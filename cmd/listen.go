@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+
+	"github.com/coreos/go-systemd/activation"
+	"github.com/dcos/dcos-check-runner/api"
+	"github.com/dcos/dcos-check-runner/auth"
+	"github.com/dcos/dcos-check-runner/listen"
+	"github.com/pkg/errors"
+)
+
+// newListenConfigs returns the listeners configured by --listen-config, or by --systemd-socket, --host and --port.
+func newListenConfigs() ([]listen.Config, error) {
+	if defaultConfig.FlagListenConfig != "" {
+		if defaultConfig.FlagSystemdSocket {
+			return nil, errors.New("--systemd-socket can't be combined with --listen-config, use a systemd listener instead")
+		}
+		return listen.LoadConfigs(defaultConfig.FlagListenConfig)
+	}
+	if defaultConfig.FlagSystemdSocket {
+		return []listen.Config{{Type: listen.TypeSystemd}}, nil
+	}
+	return []listen.Config{{Type: listen.TypeTCP, Address: fmt.Sprintf("%s:%d", defaultConfig.FlagHost, defaultConfig.FlagPort)}}, nil
+}
+
+// openListeners opens the listeners configured by configs. TCP and systemd listeners serve TLS if tlsConfig is not nil,
+// Unix domain sockets are protected by their file permissions instead.
+func openListeners(configs []listen.Config, tlsConfig *tls.Config) ([]listen.Listener, error) {
+	var systemdListeners map[string][]net.Listener
+	for _, c := range configs {
+		if c.Type == listen.TypeSystemd {
+			var err error
+			systemdListeners, err = activation.ListenersWithNames()
+			if err != nil {
+				return nil, errors.Wrap(err, "Error getting systemd sockets")
+			}
+			break
+		}
+	}
+
+	listeners, err := listen.Open(configs, systemdListeners)
+	if err != nil {
+		return nil, err
+	}
+	if tlsConfig != nil {
+		for i, l := range listeners {
+			if l.Config.Type != listen.TypeUnix {
+				listeners[i].Listener = tls.NewListener(l.Listener, tlsConfig)
+			}
+		}
+	}
+	return listeners, nil
+}
+
+// newListenerHandler returns the handler serving router on a listener configured by c, with the listener's
+// authentication.
+func newListenerHandler(router http.Handler, c listen.Config) (http.Handler, error) {
+	if c.Auth == listen.AuthNone {
+		return api.ListenerAuth(router, nil, nil), nil
+	}
+	if c.AuthPolicyFile == "" {
+		return router, nil
+	}
+
+	authenticators, err := newAuthenticators()
+	if err != nil {
+		return nil, err
+	}
+	if len(authenticators) == 0 {
+		return nil, errors.New("auth_policy_file of listeners requires --auth-jwt-public-key or --auth-token-file")
+	}
+	policy, err := auth.LoadPolicy(c.AuthPolicyFile)
+	if err != nil {
+		return nil, err
+	}
+	return api.ListenerAuth(router, authenticators, policy), nil
+}
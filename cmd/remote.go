@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/dcos/dcos-check-runner/client"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var (
+	remoteAddress    string
+	remoteTokenFile  string
+	remoteCAFile     string
+	remoteClientCert string
+	remoteClientKey  string
+)
+
+// remoteCmd runs checks through the HTTP API of a running check runner.
+var remoteCmd = &cobra.Command{
+	Use:   "remote <check-type> [check...]",
+	Short: "Execute DC/OS checks through a check runner HTTP server",
+	Long: `Execute or list checks through the HTTP API of a running check runner. The check type is one of node or cluster.
+The server is given with --address as an http(s) URL or as unix:// followed by the path of a Unix socket.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) == 0 {
+			cmd.Usage()
+			return
+		}
+
+		c, err := client.New(remoteAddress, defaultConfig.FlagBaseURI)
+		if err != nil {
+			logrus.Fatal(err)
+		}
+		if remoteCAFile != "" || remoteClientCert != "" || remoteClientKey != "" {
+			tlsConfig, err := newClientTLSConfig(remoteCAFile, remoteClientCert, remoteClientKey)
+			if err != nil {
+				logrus.Fatal(err)
+			}
+			c.HTTPClient = &http.Client{Transport: &http.Transport{TLSClientConfig: tlsConfig}}
+		}
+		if remoteTokenFile != "" {
+			token, err := ioutil.ReadFile(remoteTokenFile)
+			if err != nil {
+				logrus.Fatalf("unable to read token: %s", err)
+			}
+			c.Token = strings.TrimSpace(string(token))
+		}
+
+		ctx := context.Background()
+		if list {
+			checks, err := c.ListChecks(ctx, args[0], args[1:]...)
+			if err != nil {
+				logrus.Fatalf("unable to list %s checks: %s", args[0], err)
+			}
+			printJSON(checks)
+			return
+		}
+
+		result, err := c.RunChecks(ctx, args[0], args[1:]...)
+		if err != nil {
+			logrus.Fatalf("unable to execute %s checks: %s", args[0], err)
+		}
+		printJSON(result)
+		os.Exit(result.Status)
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(remoteCmd)
+
+	remoteCmd.PersistentFlags().StringVar(&remoteAddress, "address", "http://127.0.0.1:8000",
+		"Address of the check runner HTTP server, an http(s) URL or unix:///path/to/socket")
+	remoteCmd.PersistentFlags().StringVar(&defaultConfig.FlagBaseURI, "base-uri", "", "Server's base URI")
+	remoteCmd.PersistentFlags().StringVar(&remoteTokenFile, "token-file", "",
+		"File containing a bearer token to authenticate with, e.g. a JWT or a static token")
+	remoteCmd.PersistentFlags().StringVar(&remoteCAFile, "tls-ca", "",
+		"PEM encoded CA certificates to verify an https server with, instead of the system's")
+	remoteCmd.PersistentFlags().StringVar(&remoteClientCert, "tls-client-cert", "",
+		"PEM encoded client certificate to authenticate with over https")
+	remoteCmd.PersistentFlags().StringVar(&remoteClientKey, "tls-client-key", "",
+		"PEM encoded private key of --tls-client-cert")
+	remoteCmd.PersistentFlags().BoolVar(&list, "list", false, "List checks instead of executing them")
+}
+
+// printJSON prints the indented JSON encoding of v.
+func printJSON(v interface{}) {
+	body, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		logrus.Fatal(err)
+	}
+	fmt.Println(string(body))
+}
@@ -20,6 +20,7 @@ import (
 	"runtime"
 
 	"github.com/dcos/dcos-check-runner/config"
+	"github.com/dcos/dcos-check-runner/runner"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -27,10 +28,13 @@ import (
 
 var defaultCheckConfig = "/opt/mesosphere/etc/dcos-check-config.json"
 var defaultCheckConfigWindows = "\\DCOS\\check-runner\\config\\dcos-check-config.json"
+var defaultCheckConfigDir = "/opt/mesosphere/etc/dcos-check-config.d"
+var defaultCheckConfigDirWindows = "\\DCOS\\check-runner\\config\\dcos-check-config.d"
 
 var (
 	version       bool
-	checkCfgFile  string
+	checkCfgFiles []string
+	checkCfgDir   string
 	cfgFile       string
 	defaultConfig = &config.Config{}
 )
@@ -66,10 +70,13 @@ func init() {
 
 	if runtime.GOOS == "windows" {
 		defaultCheckConfig = os.Getenv("SYSTEMDRIVE") + defaultCheckConfigWindows
+		defaultCheckConfigDir = os.Getenv("SYSTEMDRIVE") + defaultCheckConfigDirWindows
 	}
 
-	RootCmd.PersistentFlags().StringVar(&checkCfgFile, "check-config", defaultCheckConfig,
-		"Path to check configuration file")
+	RootCmd.PersistentFlags().StringSliceVar(&checkCfgFiles, "check-config", []string{defaultCheckConfig},
+		"Path to check configuration file, may be repeated to merge several files in order")
+	RootCmd.PersistentFlags().StringVar(&checkCfgDir, "check-config-dir", defaultCheckConfigDir,
+		"Directory of check configuration files ending in .json, merged in name order after --check-config")
 	RootCmd.PersistentFlags().BoolVar(&version, "version", false, "Print dcos-check-runner version")
 	RootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is /opt/mesosphere/etc/dcos-check-runner.yaml)")
 	RootCmd.PersistentFlags().BoolVar(&defaultConfig.FlagVerbose, "verbose", defaultConfig.FlagVerbose,
@@ -95,3 +102,9 @@ func initConfig() {
 		}
 	}
 }
+
+// checkConfigFiles returns the check configuration files given with --check-config followed by those in
+// --check-config-dir, in the order in which they are merged.
+func checkConfigFiles() ([]string, error) {
+	return runner.ConfigFiles(checkCfgFiles, checkCfgDir)
+}
@@ -0,0 +1,34 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/dcos/dcos-check-runner/runner"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var showConfigCmd = &cobra.Command{
+	Use:   "show-config",
+	Short: "Print the effective check configuration",
+	Long: `Print the effective check configuration merged from the files given with --check-config and the files in
+--check-config-dir, after validating it.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		cfgFiles, err := checkConfigFiles()
+		if err != nil {
+			logrus.Fatal(err)
+		}
+		logrus.Infof("Merging check configuration from %s", strings.Join(cfgFiles, ", "))
+
+		r := &runner.Runner{}
+		if err := r.LoadFromFiles(cfgFiles...); err != nil {
+			logrus.Fatal(err)
+		}
+		fmt.Println(string(r.Config()))
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(showConfigCmd)
+}
@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"io/ioutil"
+
+	"github.com/dcos/dcos-check-runner/servertls"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// addTLSFlags adds the flags configuring TLS for the HTTP server to cmd.
+func addTLSFlags(cmd *cobra.Command) {
+	cmd.PersistentFlags().StringVar(&defaultConfig.FlagTLSCert, "tls-cert", "",
+		"Serve HTTPS with the PEM encoded certificate chain in the given file, which is reloaded when it changes")
+	cmd.PersistentFlags().StringVar(&defaultConfig.FlagTLSKey, "tls-key", "",
+		"PEM encoded private key of --tls-cert")
+	cmd.PersistentFlags().StringVar(&defaultConfig.FlagTLSClientCA, "tls-client-ca", "",
+		"Require client certificates issued by the PEM encoded CA certificates in the given file")
+	cmd.PersistentFlags().StringSliceVar(&defaultConfig.FlagTLSClientSubjects, "tls-client-subject", nil,
+		"Only allow client certificates with the given common name or subject, e.g. CN=dcos-check-runner,O=Mesosphere")
+}
+
+// newTLSConfig returns the TLS configuration configured by the TLS flags, or nil if TLS is not configured.
+func newTLSConfig() (*tls.Config, error) {
+	if defaultConfig.FlagTLSCert == "" && defaultConfig.FlagTLSKey == "" {
+		if defaultConfig.FlagTLSClientCA != "" || len(defaultConfig.FlagTLSClientSubjects) > 0 {
+			return nil, errors.New("client certificates require --tls-cert and --tls-key")
+		}
+		return nil, nil
+	}
+	return servertls.NewConfig(servertls.Options{
+		CertFile:       defaultConfig.FlagTLSCert,
+		KeyFile:        defaultConfig.FlagTLSKey,
+		ClientCAFile:   defaultConfig.FlagTLSClientCA,
+		ClientSubjects: defaultConfig.FlagTLSClientSubjects,
+	})
+}
+
+// newClientTLSConfig returns a TLS configuration for clients which verifies servers with the CA certificates in caFile,
+// or the system's if it is empty, and authenticates with the client certificate in certFile and keyFile, if given.
+func newClientTLSConfig(caFile, certFile, keyFile string) (*tls.Config, error) {
+	cfg := &tls.Config{}
+	if caFile != "" {
+		pem, err := ioutil.ReadFile(caFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "unable to read CA certificates")
+		}
+		cfg.RootCAs = x509.NewCertPool()
+		if !cfg.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, errors.Errorf("no certificates found in %s", caFile)
+		}
+	}
+	if certFile != "" || keyFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "unable to load client certificate")
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+	return cfg, nil
+}
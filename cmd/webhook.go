@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+
+	"github.com/dcos/dcos-check-runner/webhook"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// addWebhookFlags adds the flags configuring webhook notifications to cmd.
+func addWebhookFlags(cmd *cobra.Command) {
+	cmd.PersistentFlags().StringSliceVar(&defaultConfig.FlagWebhookURLs, "webhook-url", nil,
+		"Post an event to the given URL when a check transitions between statuses")
+	cmd.PersistentFlags().StringVar(&defaultConfig.FlagWebhookSecretFile, "webhook-secret-file", "",
+		"File containing the secret used to sign webhook events with HMAC-SHA256")
+	cmd.PersistentFlags().IntVar(&defaultConfig.FlagWebhookDebounce, "webhook-debounce", 1,
+		"Number of consecutive runs a check must be in a new status before the transition is notified")
+	cmd.PersistentFlags().StringVar(&defaultConfig.FlagWebhookQueueDir, "webhook-queue-dir", "",
+		"Directory webhook events are queued in until they are delivered")
+	cmd.PersistentFlags().IntVar(&defaultConfig.FlagWebhookQueueSize, "webhook-queue-size", webhook.DefaultMaxQueued,
+		"Maximum number of webhook events queued per URL")
+}
+
+// newNotifier returns a *webhook.Notifier configured by the webhook flags, or nil if no webhook URL is configured.
+func newNotifier() (*webhook.Notifier, error) {
+	if len(defaultConfig.FlagWebhookURLs) == 0 {
+		return nil, nil
+	}
+	if defaultConfig.FlagWebhookQueueDir == "" {
+		return nil, errors.New("--webhook-queue-dir is required with --webhook-url")
+	}
+
+	var secret []byte
+	if defaultConfig.FlagWebhookSecretFile != "" {
+		s, err := ioutil.ReadFile(defaultConfig.FlagWebhookSecretFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "unable to read webhook secret")
+		}
+		secret = bytes.TrimSpace(s)
+	}
+
+	node, err := os.Hostname()
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to get hostname")
+	}
+
+	return webhook.NewNotifier(webhook.Config{
+		URLs:      defaultConfig.FlagWebhookURLs,
+		Secret:    secret,
+		Debounce:  defaultConfig.FlagWebhookDebounce,
+		QueueDir:  defaultConfig.FlagWebhookQueueDir,
+		MaxQueued: defaultConfig.FlagWebhookQueueSize,
+		Node:      node,
+		Role:      defaultConfig.FlagRole,
+	})
+}
@@ -8,6 +8,9 @@ import (
 var (
 	// Version of dcos-check-runner code.
 	Version = "0.1.0"
+
+	// Commit of dcos-check-runner code, set at build time.
+	Commit = ""
 )
 
 // Config structure is a main config object
@@ -20,6 +23,52 @@ type Config struct {
 	FlagPort          int    `json:"port"`
 	FlagBaseURI       string `json:"base-uri"`
 	FlagSystemdSocket bool   `json:"systemd-socket"`
+	FlagListenConfig  string `json:"listen-config"`
+	FlagDrainTimeout  string `json:"drain-timeout"`
+
+	// TLS
+	FlagTLSCert           string   `json:"tls-cert"`
+	FlagTLSKey            string   `json:"tls-key"`
+	FlagTLSClientCA       string   `json:"tls-client-ca"`
+	FlagTLSClientSubjects []string `json:"tls-client-subject"`
+
+	// authentication and authorization of the HTTP API
+	FlagAuthJWTPublicKey string `json:"auth-jwt-public-key"`
+	FlagAuthTokenFile    string `json:"auth-token-file"`
+	FlagAuthPolicyFile   string `json:"auth-policy-file"`
+
+	// aggregation of node checks across the cluster
+	FlagNodes             []string `json:"nodes"`
+	FlagNodesFile         string   `json:"nodes-file"`
+	FlagMesosState        string   `json:"mesos-state"`
+	FlagNodeURL           string   `json:"node-url"`
+	FlagNodeTimeout       string   `json:"node-timeout"`
+	FlagNodeTokenFile     string   `json:"node-token-file"`
+	FlagNodeTLSCA         string   `json:"node-tls-ca"`
+	FlagNodeTLSClientCert string   `json:"node-tls-client-cert"`
+	FlagNodeTLSClientKey  string   `json:"node-tls-client-key"`
+
+	// history of check results
+	FlagHistoryDir     string `json:"history-dir"`
+	FlagHistoryMaxSize int    `json:"history-max-size"`
+	FlagHistoryMaxAge  string `json:"history-max-age"`
+
+	// asynchronous runs
+	FlagRunsMaxFinished int    `json:"runs-max-finished"`
+	FlagRunsMaxAge      string `json:"runs-max-age"`
+
+	// probe endpoints
+	FlagProbeConfig string `json:"probe-config"`
+
+	// check state tracking
+	FlagStateFile string `json:"state-file"`
+
+	// webhook notifications
+	FlagWebhookURLs       []string `json:"webhook-url"`
+	FlagWebhookSecretFile string   `json:"webhook-secret-file"`
+	FlagWebhookDebounce   int      `json:"webhook-debounce"`
+	FlagWebhookQueueDir   string   `json:"webhook-queue-dir"`
+	FlagWebhookQueueSize  int      `json:"webhook-queue-size"`
 }
 
 // LoadFromViper takes a map of flags with values and updates the config structure.
@@ -0,0 +1,343 @@
+// Package history records check results in a local, size and age bounded store and queries them.
+//
+// Records are appended as JSON lines to segment files in a directory. When the current segment exceeds the segment
+// size a new one is started, and the oldest segments are removed while the store exceeds its maximum size or when
+// they only contain records older than the maximum age.
+package history
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dcos/dcos-check-runner/runner"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// DefaultMaxSize is the default maximum size of a store in bytes.
+	DefaultMaxSize = 64 << 20
+
+	// DefaultMaxAge is the default age after which records are removed.
+	DefaultMaxAge = 7 * 24 * time.Hour
+
+	segmentExt = ".jsonl"
+)
+
+// statusUnknown is recorded for checks which could not be found or executed.
+const statusUnknown = 3
+
+// Record is the result of a single check run.
+type Record struct {
+	Time     time.Time `json:"time"`
+	Suite    string    `json:"suite"`
+	Check    string    `json:"check"`
+	Status   int       `json:"status"`
+	Output   string    `json:"output"`
+	Duration string    `json:"duration,omitempty"`
+
+	// Error is set if the check could not be found or executed.
+	Error string `json:"error,omitempty"`
+}
+
+// Options bound the size of a store.
+type Options struct {
+	// MaxSize is the maximum size of all segments in bytes. Defaults to DefaultMaxSize.
+	MaxSize int64
+
+	// MaxAge is the age after which segments are removed. Defaults to DefaultMaxAge.
+	MaxAge time.Duration
+
+	// SegmentSize is the size in bytes at which a new segment is started. Defaults to an eighth of MaxSize.
+	SegmentSize int64
+}
+
+// Store appends records to segment files in a directory. It implements runner.Observer.
+type Store struct {
+	dir  string
+	opts Options
+
+	mu      sync.Mutex
+	current *os.File
+	seq     int
+	size    int64
+}
+
+// Open opens the store in dir, creating the directory if needed. New records are appended to the latest segment.
+func Open(dir string, opts Options) (*Store, error) {
+	if opts.MaxSize <= 0 {
+		opts.MaxSize = DefaultMaxSize
+	}
+	if opts.MaxAge <= 0 {
+		opts.MaxAge = DefaultMaxAge
+	}
+	if opts.SegmentSize <= 0 {
+		opts.SegmentSize = opts.MaxSize / 8
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, errors.Wrap(err, "unable to create history directory")
+	}
+
+	s := &Store{dir: dir, opts: opts}
+	segments, err := listSegments(dir)
+	if err != nil {
+		return nil, err
+	}
+	seq := 1
+	if len(segments) > 0 {
+		seq = segments[len(segments)-1].seq
+	}
+	if err := s.openSegment(seq); err != nil {
+		return nil, err
+	}
+	if err := s.prune(); err != nil {
+		s.current.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// Close closes the current segment.
+func (s *Store) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.current.Close()
+}
+
+// Append writes records to the store.
+func (s *Store) Append(records ...Record) error {
+	var buf bytes.Buffer
+	for _, r := range records {
+		line, err := json.Marshal(r)
+		if err != nil {
+			return errors.Wrap(err, "unable to encode record")
+		}
+		buf.Write(line)
+		buf.WriteByte('\n')
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.size > 0 && s.size+int64(buf.Len()) > s.opts.SegmentSize {
+		if err := s.current.Close(); err != nil {
+			return errors.Wrap(err, "unable to close history segment")
+		}
+		if err := s.openSegment(s.seq + 1); err != nil {
+			return err
+		}
+		if err := s.prune(); err != nil {
+			return err
+		}
+	}
+
+	n, err := s.current.Write(buf.Bytes())
+	s.size += int64(n)
+	if err != nil {
+		return errors.Wrap(err, "unable to write history segment")
+	}
+	return nil
+}
+
+// ObserveChecks records the results of a check run. Errors are logged, so that a broken store doesn't fail checks.
+func (s *Store) ObserveChecks(suite string, rs *runner.CombinedResponse) {
+	if err := s.Append(Records(suite, rs)...); err != nil {
+		logrus.Errorf("Unable to record check results: %s", err)
+	}
+}
+
+// Records returns the records of the checks in rs.
+func Records(suite string, rs *runner.CombinedResponse) []Record {
+	now := time.Now()
+
+	var records []Record
+	for _, check := range rs.Checks() {
+		records = append(records, Record{
+			Time:     check.Start(),
+			Suite:    suite,
+			Check:    check.Name(),
+			Status:   check.Status(),
+			Output:   check.Output(),
+			Duration: check.Duration().String(),
+		})
+	}
+
+	errs := rs.Errors()
+	var names []string
+	for name := range errs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		records = append(records, Record{
+			Time:   now,
+			Suite:  suite,
+			Check:  name,
+			Status: statusUnknown,
+			Error:  errs[name],
+		})
+	}
+	return records
+}
+
+// Query returns the records matching q.
+func (s *Store) Query(q Query) ([]Record, error) {
+	// Only read the current segment up to the last complete write, appends continue while the query runs.
+	s.mu.Lock()
+	seq, size := s.seq, s.size
+	s.mu.Unlock()
+
+	segments, err := listSegments(s.dir)
+	if err != nil {
+		return nil, err
+	}
+	for i := range segments {
+		if segments[i].seq == seq {
+			segments[i].limit = size
+		}
+	}
+	return query(segments, q)
+}
+
+// QueryDir returns the records matching q from the store in dir, without opening it for writing.
+func QueryDir(dir string, q Query) ([]Record, error) {
+	segments, err := listSegments(dir)
+	if err != nil {
+		return nil, err
+	}
+	return query(segments, q)
+}
+
+// openSegment opens the segment with the given sequence number for appending.
+func (s *Store) openSegment(seq int) error {
+	f, err := os.OpenFile(segmentPath(s.dir, seq), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return errors.Wrap(err, "unable to open history segment")
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return errors.Wrap(err, "unable to open history segment")
+	}
+	s.current, s.seq, s.size = f, seq, info.Size()
+	return nil
+}
+
+// prune removes the oldest segments while the store is larger than MaxSize, and segments which were last written
+// before MaxAge. The current segment is never removed.
+func (s *Store) prune() error {
+	segments, err := listSegments(s.dir)
+	if err != nil {
+		return err
+	}
+
+	var total int64
+	for _, seg := range segments {
+		total += seg.size
+	}
+	cutoff := time.Now().Add(-s.opts.MaxAge)
+	for _, seg := range segments {
+		if seg.seq == s.seq {
+			break
+		}
+		if total <= s.opts.MaxSize && !seg.modTime.Before(cutoff) {
+			continue
+		}
+		if err := os.Remove(seg.path); err != nil && !os.IsNotExist(err) {
+			return errors.Wrap(err, "unable to remove history segment")
+		}
+		total -= seg.size
+	}
+	return nil
+}
+
+type segment struct {
+	path    string
+	seq     int
+	size    int64
+	modTime time.Time
+
+	// limit is the number of bytes to read, or 0 to read the whole segment.
+	limit int64
+}
+
+func segmentPath(dir string, seq int) string {
+	return filepath.Join(dir, fmt.Sprintf("%010d%s", seq, segmentExt))
+}
+
+// listSegments returns the segments in dir, oldest first.
+func listSegments(dir string) ([]segment, error) {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to read history directory")
+	}
+
+	var segments []segment
+	for _, f := range files {
+		name := f.Name()
+		if f.IsDir() || !strings.HasSuffix(name, segmentExt) {
+			continue
+		}
+		seq, err := strconv.Atoi(strings.TrimSuffix(name, segmentExt))
+		if err != nil {
+			continue
+		}
+		segments = append(segments, segment{
+			path:    filepath.Join(dir, name),
+			seq:     seq,
+			size:    f.Size(),
+			modTime: f.ModTime(),
+		})
+	}
+	sort.Slice(segments, func(i, j int) bool {
+		return segments[i].seq < segments[j].seq
+	})
+	return segments, nil
+}
+
+// readSegment calls f for each record in seg. Lines which can't be decoded, e.g. partially written records after a
+// crash, are skipped.
+func readSegment(seg segment, f func(Record)) error {
+	file, err := os.Open(seg.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			// Removed by pruning since it was listed.
+			return nil
+		}
+		return errors.Wrap(err, "unable to open history segment")
+	}
+	defer file.Close()
+
+	var r io.Reader = file
+	if seg.limit > 0 {
+		r = io.LimitReader(file, seg.limit)
+	}
+	reader := bufio.NewReader(r)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(line) > 0 && line[len(line)-1] == '\n' {
+			var record Record
+			if json.Unmarshal(line, &record) == nil {
+				f(record)
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return errors.Wrap(err, "unable to read history segment")
+		}
+	}
+}
@@ -0,0 +1,257 @@
+package history
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dcos/dcos-check-runner/runner"
+)
+
+func newTestDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "history-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func TestStore(t *testing.T) {
+	dir := newTestDir(t)
+	defer os.RemoveAll(dir)
+
+	s, err := Open(dir, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	start := time.Date(2019, 3, 1, 12, 0, 0, 0, time.UTC)
+	for i := 0; i < 10; i++ {
+		status := 0
+		if i >= 6 {
+			status = 2
+		}
+		err := s.Append(
+			Record{Time: start.Add(time.Duration(i) * time.Minute), Suite: runner.SuiteNodePostStart, Check: "a", Status: status},
+			Record{Time: start.Add(time.Duration(i) * time.Minute), Suite: runner.SuiteCluster, Check: "b"},
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	t.Run("all records", func(t *testing.T) {
+		records, err := s.Query(Query{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(records) != 20 {
+			t.Fatalf("expected 20 records, got %d", len(records))
+		}
+	})
+
+	t.Run("by check and time", func(t *testing.T) {
+		records, err := s.Query(Query{Checks: []string{"a"}, Since: start.Add(5 * time.Minute), Until: start.Add(8 * time.Minute)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var statuses []int
+		for _, r := range records {
+			if r.Check != "a" {
+				t.Fatalf("unexpected check %s", r.Check)
+			}
+			statuses = append(statuses, r.Status)
+		}
+		if len(statuses) != 3 || statuses[0] != 0 || statuses[1] != 2 || statuses[2] != 2 {
+			t.Fatalf("expected statuses [0 2 2], got %v", statuses)
+		}
+	})
+
+	t.Run("by suite with limit", func(t *testing.T) {
+		records, err := s.Query(Query{Suite: runner.SuiteCluster, Limit: 2})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(records) != 2 || !records[1].Time.Equal(start.Add(9*time.Minute)) || records[0].Check != "b" {
+			t.Fatalf("expected the 2 most recent cluster records, got %+v", records)
+		}
+	})
+
+	t.Run("reopen", func(t *testing.T) {
+		if err := s.Close(); err != nil {
+			t.Fatal(err)
+		}
+		s, err = Open(dir, Options{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := s.Append(Record{Time: start.Add(time.Hour), Check: "c"}); err != nil {
+			t.Fatal(err)
+		}
+
+		records, err := QueryDir(dir, Query{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(records) != 21 || records[20].Check != "c" {
+			t.Fatalf("expected 21 records ending with c, got %d", len(records))
+		}
+	})
+}
+
+func TestStoreRotation(t *testing.T) {
+	dir := newTestDir(t)
+	defer os.RemoveAll(dir)
+
+	s, err := Open(dir, Options{MaxSize: 1000, SegmentSize: 200})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	output := strings.Repeat("x", 50)
+	for i := 0; i < 100; i++ {
+		if err := s.Append(Record{Time: time.Now(), Check: "a", Output: output}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	segments, err := listSegments(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var total int64
+	for _, seg := range segments {
+		total += seg.size
+	}
+	if len(segments) < 2 || total > 1000+200 {
+		t.Fatalf("expected rotated segments bounded by the maximum size, got %d segments with %d bytes", len(segments), total)
+	}
+
+	// The most recent records are kept.
+	records, err := s.Query(Query{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) == 0 || len(records) >= 100 {
+		t.Fatalf("expected some of the records to be pruned, got %d", len(records))
+	}
+}
+
+func TestStorePruneAge(t *testing.T) {
+	dir := newTestDir(t)
+	defer os.RemoveAll(dir)
+
+	old := segmentPath(dir, 1)
+	if err := ioutil.WriteFile(old, []byte(`{"check":"old"}`+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	past := time.Now().Add(-48 * time.Hour)
+	if err := os.Chtimes(old, past, past); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(segmentPath(dir, 2), []byte(`{"check":"new"}`+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := Open(dir, Options{MaxAge: 24 * time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	if _, err := os.Stat(old); !os.IsNotExist(err) {
+		t.Fatalf("expected old segment to be removed, got %v", err)
+	}
+	records, err := s.Query(Query{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 1 || records[0].Check != "new" {
+		t.Fatalf("expected only the new record, got %+v", records)
+	}
+}
+
+func TestQueryPartialRecords(t *testing.T) {
+	dir := newTestDir(t)
+	defer os.RemoveAll(dir)
+
+	content := `{"check":"a"}` + "\n" + `{"check":` + "\n" + `{"check":"b"}` + "\n" + `{"check":"c"`
+	if err := ioutil.WriteFile(filepath.Join(dir, "0000000001.jsonl"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	records, err := QueryDir(dir, Query{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 || records[0].Check != "a" || records[1].Check != "b" {
+		t.Fatalf("expected records a and b, got %+v", records)
+	}
+}
+
+func TestObserveChecks(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("test checks require sh")
+	}
+
+	dir := newTestDir(t)
+	defer os.RemoveAll(dir)
+
+	s, err := Open(dir, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	r, err := runner.NewRunner("master")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := `{"cluster_checks": {"failing": {"cmd": ["sh", "-c", "echo oops; exit 2"], "timeout": "1s"}}}`
+	if err := r.Load(strings.NewReader(cfg)); err != nil {
+		t.Fatal(err)
+	}
+	r.Observers = []runner.Observer{s}
+
+	if _, err := r.Cluster(context.Background(), false); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Cluster(context.Background(), false, "missing"); err != nil {
+		t.Fatal(err)
+	}
+
+	records, err := s.Query(Query{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("expected 2 records, got %+v", records)
+	}
+	if r := records[0]; r.Check != "failing" || r.Suite != runner.SuiteCluster || r.Status != 2 || r.Output != "oops\n" || r.Time.IsZero() || r.Duration == "" {
+		t.Fatalf("unexpected record %+v", r)
+	}
+	if r := records[1]; r.Check != "missing" || r.Status != statusUnknown || r.Error != "Check not found" {
+		t.Fatalf("unexpected record %+v", r)
+	}
+}
+
+func TestParseTime(t *testing.T) {
+	now := time.Date(2019, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	if ts, err := ParseTime("2h", now); err != nil || !ts.Equal(now.Add(-2*time.Hour)) {
+		t.Fatalf("unexpected result %s, %v", ts, err)
+	}
+	if ts, err := ParseTime("2019-02-28T10:00:00Z", now); err != nil || !ts.Equal(time.Date(2019, 2, 28, 10, 0, 0, 0, time.UTC)) {
+		t.Fatalf("unexpected result %s, %v", ts, err)
+	}
+	if _, err := ParseTime("yesterday", now); err == nil {
+		t.Fatal("expected error")
+	}
+}
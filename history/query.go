@@ -0,0 +1,83 @@
+package history
+
+import (
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Query selects records. Zero values match all records.
+type Query struct {
+	// Checks selects records of the given checks.
+	Checks []string
+
+	// Suite selects records of the given check suite, e.g. "node-poststart".
+	Suite string
+
+	// Since and Until select records in the time range [Since, Until).
+	Since time.Time
+	Until time.Time
+
+	// Limit selects only the most recent records.
+	Limit int
+}
+
+// match returns true if r is selected by q, disregarding the limit.
+func (q Query) match(r Record) bool {
+	if q.Suite != "" && r.Suite != q.Suite {
+		return false
+	}
+	if !q.Since.IsZero() && r.Time.Before(q.Since) {
+		return false
+	}
+	if !q.Until.IsZero() && !r.Time.Before(q.Until) {
+		return false
+	}
+	if len(q.Checks) == 0 {
+		return true
+	}
+	for _, check := range q.Checks {
+		if r.Check == check {
+			return true
+		}
+	}
+	return false
+}
+
+// query returns the records of segments matching q, oldest first.
+func query(segments []segment, q Query) ([]Record, error) {
+	records := []Record{}
+	for _, seg := range segments {
+		// Segments are only appended to, so a segment last written before Since has no matching records.
+		if !q.Since.IsZero() && seg.modTime.Before(q.Since) {
+			continue
+		}
+
+		err := readSegment(seg, func(r Record) {
+			if q.match(r) {
+				records = append(records, r)
+			}
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if q.Limit > 0 && len(records) > q.Limit {
+		records = records[len(records)-q.Limit:]
+	}
+	return records, nil
+}
+
+// ParseTime parses an RFC 3339 timestamp, or a duration like "2h" meaning that long before now.
+func ParseTime(s string, now time.Time) (time.Time, error) {
+	if d, err := time.ParseDuration(s); err == nil {
+		return now.Add(-d), nil
+	}
+	t, err := time.Parse(time.RFC3339, strings.TrimSpace(s))
+	if err != nil {
+		return time.Time{}, errors.Errorf("invalid time %q, expected an RFC 3339 timestamp or a duration", s)
+	}
+	return t, nil
+}
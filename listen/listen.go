@@ -0,0 +1,286 @@
+// Package listen opens the listeners the check runner HTTP server is served on: TCP addresses, Unix domain sockets and
+// sockets passed by systemd socket activation.
+package listen
+
+import (
+	"encoding/json"
+	"net"
+	"os"
+	"os/user"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Types of listeners.
+const (
+	TypeTCP     = "tcp"
+	TypeUnix    = "unix"
+	TypeSystemd = "systemd"
+)
+
+// Authentication of listeners.
+const (
+	// AuthDefault authenticates and authorizes requests like the HTTP server does by default.
+	AuthDefault = ""
+
+	// AuthNone trusts all clients of the listener, e.g. local clients of a Unix domain socket with restrictive
+	// permissions. Requests are neither authenticated nor authorized.
+	AuthNone = "none"
+)
+
+// DefaultSocketMode is the default file mode of Unix domain sockets.
+const DefaultSocketMode os.FileMode = 0660
+
+// Config configures a listener.
+type Config struct {
+	// Type is the type of the listener, one of TypeTCP, TypeUnix and TypeSystemd.
+	Type string `json:"type"`
+
+	// Address is the host:port a TCP listener listens at.
+	Address string `json:"address,omitempty"`
+
+	// Path is the path of a Unix domain socket. A stale socket at Path is replaced.
+	Path string `json:"path,omitempty"`
+
+	// Mode is the octal file mode of a Unix domain socket. It defaults to DefaultSocketMode.
+	Mode string `json:"mode,omitempty"`
+
+	// Owner is the owner of a Unix domain socket, given as user, user:group or :group with names or numeric IDs.
+	// Ownership is not changed if it is empty.
+	Owner string `json:"owner,omitempty"`
+
+	// Name selects the sockets passed by systemd with the given FileDescriptorName=. If it is empty, all sockets which
+	// are not selected by name by another listener are used.
+	Name string `json:"name,omitempty"`
+
+	// Auth is the authentication of the listener, AuthDefault or AuthNone.
+	Auth string `json:"auth,omitempty"`
+
+	// AuthPolicyFile is a policy file authorizing the requests received on the listener instead of the HTTP server's
+	// default policy. Requests are authenticated as by default.
+	AuthPolicyFile string `json:"auth_policy_file,omitempty"`
+}
+
+// Listener is an open listener.
+type Listener struct {
+	net.Listener
+
+	// Config is the configuration the listener was opened with.
+	Config Config
+}
+
+// LoadConfigs reads listener configurations from a JSON file containing a list of them, like
+// [{"type": "tcp", "address": ":8000"}, {"type": "unix", "path": "/run/check-runner.sock", "auth": "none"}].
+func LoadConfigs(path string) ([]Config, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to open listener config file")
+	}
+	defer f.Close()
+
+	var configs []Config
+	if err := json.NewDecoder(f).Decode(&configs); err != nil {
+		return nil, errors.Wrapf(err, "invalid listener config file %s", path)
+	}
+	if len(configs) == 0 {
+		return nil, errors.Errorf("no listeners configured in %s", path)
+	}
+	for i, c := range configs {
+		if err := c.validate(); err != nil {
+			return nil, errors.Wrapf(err, "invalid listener %d in %s", i+1, path)
+		}
+	}
+	return configs, nil
+}
+
+// validate returns an error if c is incomplete or sets options which don't apply to its type.
+func (c Config) validate() error {
+	switch c.Type {
+	case TypeTCP:
+		if c.Address == "" {
+			return errors.New("tcp listener requires an address")
+		}
+	case TypeUnix:
+		if c.Path == "" {
+			return errors.New("unix listener requires a path")
+		}
+		if _, err := c.socketMode(); err != nil {
+			return err
+		}
+	case TypeSystemd:
+	default:
+		return errors.Errorf("unknown listener type %q", c.Type)
+	}
+
+	if c.Address != "" && c.Type != TypeTCP {
+		return errors.New("address only applies to tcp listeners")
+	}
+	if (c.Path != "" || c.Mode != "" || c.Owner != "") && c.Type != TypeUnix {
+		return errors.New("path, mode and owner only apply to unix listeners")
+	}
+	if c.Name != "" && c.Type != TypeSystemd {
+		return errors.New("name only applies to systemd listeners")
+	}
+
+	switch c.Auth {
+	case AuthDefault:
+	case AuthNone:
+		if c.AuthPolicyFile != "" {
+			return errors.New("auth_policy_file doesn't apply to listeners without authentication")
+		}
+	default:
+		return errors.Errorf("unknown auth %q", c.Auth)
+	}
+	return nil
+}
+
+// socketMode returns the file mode of a Unix domain socket.
+func (c Config) socketMode() (os.FileMode, error) {
+	if c.Mode == "" {
+		return DefaultSocketMode, nil
+	}
+	mode, err := strconv.ParseUint(c.Mode, 8, 32)
+	if err != nil || mode > 0777 {
+		return 0, errors.Errorf("invalid socket mode %q", c.Mode)
+	}
+	return os.FileMode(mode), nil
+}
+
+// Open opens the listeners configured by configs. systemdListeners maps the names of the sockets passed by systemd to
+// their listeners, as returned by activation.ListenersWithNames. If a listener can't be opened, the listeners opened
+// so far are closed and an error is returned.
+func Open(configs []Config, systemdListeners map[string][]net.Listener) ([]Listener, error) {
+	// Listeners without a name take the systemd sockets which are not taken by name.
+	named := make(map[string]bool)
+	for _, c := range configs {
+		if c.Type == TypeSystemd && c.Name != "" {
+			named[c.Name] = true
+		}
+	}
+
+	var listeners []Listener
+	closeAll := func() {
+		for _, l := range listeners {
+			l.Close()
+		}
+	}
+	for _, c := range configs {
+		if err := c.validate(); err != nil {
+			closeAll()
+			return nil, err
+		}
+
+		switch c.Type {
+		case TypeTCP:
+			l, err := net.Listen("tcp", c.Address)
+			if err != nil {
+				closeAll()
+				return nil, errors.Wrapf(err, "unable to listen at %s", c.Address)
+			}
+			listeners = append(listeners, Listener{l, c})
+		case TypeUnix:
+			l, err := listenUnix(c)
+			if err != nil {
+				closeAll()
+				return nil, err
+			}
+			listeners = append(listeners, Listener{l, c})
+		case TypeSystemd:
+			var found []net.Listener
+			for name, ls := range systemdListeners {
+				if name == c.Name || (c.Name == "" && !named[name]) {
+					found = append(found, ls...)
+				}
+			}
+			if len(found) == 0 {
+				closeAll()
+				if c.Name != "" {
+					return nil, errors.Errorf("no systemd socket named %s found", c.Name)
+				}
+				return nil, errors.New("no systemd socket found")
+			}
+			for _, l := range found {
+				listeners = append(listeners, Listener{l, c})
+			}
+		}
+	}
+	return listeners, nil
+}
+
+// listenUnix listens at the Unix domain socket configured by c, replacing a stale socket at its path, and sets its mode
+// and owner.
+func listenUnix(c Config) (net.Listener, error) {
+	mode, err := c.socketMode()
+	if err != nil {
+		return nil, err
+	}
+	uid, gid, err := lookupOwner(c.Owner)
+	if err != nil {
+		return nil, err
+	}
+
+	if fi, err := os.Lstat(c.Path); err == nil && fi.Mode()&os.ModeSocket != 0 {
+		// A socket left behind by a previous process which didn't exit cleanly.
+		if err := os.Remove(c.Path); err != nil {
+			return nil, errors.Wrapf(err, "unable to remove stale socket %s", c.Path)
+		}
+	}
+
+	l, err := net.Listen("unix", c.Path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to listen at %s", c.Path)
+	}
+	if err := os.Chmod(c.Path, mode); err != nil {
+		l.Close()
+		return nil, errors.Wrapf(err, "unable to set the mode of %s", c.Path)
+	}
+	if uid != -1 || gid != -1 {
+		if err := os.Chown(c.Path, uid, gid); err != nil {
+			l.Close()
+			return nil, errors.Wrapf(err, "unable to set the owner of %s", c.Path)
+		}
+	}
+	return l, nil
+}
+
+// lookupOwner returns the user and group IDs of owner, given as user, user:group or :group. IDs which are not given are
+// returned as -1, which leaves them unchanged with os.Chown.
+func lookupOwner(owner string) (int, int, error) {
+	uid, gid := -1, -1
+	if owner == "" {
+		return uid, gid, nil
+	}
+
+	parts := strings.SplitN(owner, ":", 2)
+	if parts[0] != "" {
+		id := parts[0]
+		if _, err := strconv.Atoi(id); err != nil {
+			u, err := user.Lookup(id)
+			if err != nil {
+				return 0, 0, errors.Wrapf(err, "invalid socket owner %q", owner)
+			}
+			id = u.Uid
+		}
+		var err error
+		if uid, err = strconv.Atoi(id); err != nil {
+			return 0, 0, errors.Errorf("invalid socket owner %q", owner)
+		}
+	}
+	if len(parts) == 2 && parts[1] != "" {
+		id := parts[1]
+		if _, err := strconv.Atoi(id); err != nil {
+			g, err := user.LookupGroup(id)
+			if err != nil {
+				return 0, 0, errors.Wrapf(err, "invalid socket group %q", owner)
+			}
+			id = g.Gid
+		}
+		var err error
+		if gid, err = strconv.Atoi(id); err != nil {
+			return 0, 0, errors.Errorf("invalid socket group %q", owner)
+		}
+	}
+	return uid, gid, nil
+}
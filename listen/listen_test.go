@@ -0,0 +1,226 @@
+//go:build !windows
+// +build !windows
+
+package listen
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"testing"
+)
+
+func newTestDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "listen-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func closeListeners(listeners []Listener) {
+	for _, l := range listeners {
+		l.Close()
+	}
+}
+
+func TestLoadConfigs(t *testing.T) {
+	dir := newTestDir(t)
+	defer os.RemoveAll(dir)
+
+	load := func(config string) ([]Config, error) {
+		path := filepath.Join(dir, "listeners.json")
+		if err := ioutil.WriteFile(path, []byte(config), 0600); err != nil {
+			t.Fatal(err)
+		}
+		return LoadConfigs(path)
+	}
+
+	configs, err := load(`[
+		{"type": "tcp", "address": ":8000", "auth_policy_file": "/etc/policy.json"},
+		{"type": "unix", "path": "/run/check-runner.sock", "mode": "0600", "owner": "root:root", "auth": "none"},
+		{"type": "systemd", "name": "check-runner-tcp"}
+	]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []Config{
+		{Type: TypeTCP, Address: ":8000", AuthPolicyFile: "/etc/policy.json"},
+		{Type: TypeUnix, Path: "/run/check-runner.sock", Mode: "0600", Owner: "root:root", Auth: AuthNone},
+		{Type: TypeSystemd, Name: "check-runner-tcp"},
+	}
+	if !reflect.DeepEqual(configs, expected) {
+		t.Fatalf("expected %+v, got %+v", expected, configs)
+	}
+
+	for _, config := range []string{
+		`[]`,
+		`{}`,
+		`[{"type": "udp"}]`,
+		`[{"type": "tcp"}]`,
+		`[{"type": "unix"}]`,
+		`[{"type": "unix", "path": "/run/check-runner.sock", "mode": "rw"}]`,
+		`[{"type": "unix", "path": "/run/check-runner.sock", "mode": "1777"}]`,
+		`[{"type": "tcp", "address": ":8000", "path": "/run/check-runner.sock"}]`,
+		`[{"type": "systemd", "address": ":8000"}]`,
+		`[{"type": "tcp", "address": ":8000", "name": "check-runner"}]`,
+		`[{"type": "tcp", "address": ":8000", "auth": "token"}]`,
+		`[{"type": "tcp", "address": ":8000", "auth": "none", "auth_policy_file": "/etc/policy.json"}]`,
+	} {
+		if _, err := load(config); err == nil {
+			t.Fatalf("expected an error for %s", config)
+		}
+	}
+}
+
+func TestOpen(t *testing.T) {
+	dir := newTestDir(t)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "check-runner.sock")
+
+	// A stale socket is replaced.
+	stale, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	stale.Close()
+
+	owner := strconv.Itoa(os.Getuid()) + ":" + strconv.Itoa(os.Getgid())
+	listeners, err := Open([]Config{
+		{Type: TypeTCP, Address: "127.0.0.1:0"},
+		{Type: TypeUnix, Path: path, Mode: "0600", Owner: owner, Auth: AuthNone},
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeListeners(listeners)
+
+	if len(listeners) != 2 {
+		t.Fatalf("expected 2 listeners, got %d", len(listeners))
+	}
+	if network := listeners[0].Addr().Network(); network != "tcp" {
+		t.Fatalf("expected a tcp listener, got %s", network)
+	}
+	if listeners[1].Config.Auth != AuthNone {
+		t.Fatalf("unexpected config %+v", listeners[1].Config)
+	}
+
+	fi, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Mode()&os.ModeSocket == 0 || fi.Mode().Perm() != 0600 {
+		t.Fatalf("unexpected socket mode %s", fi.Mode())
+	}
+
+	conn, err := net.Dial("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.Close()
+
+	// Other files are not replaced.
+	file := filepath.Join(dir, "file")
+	if err := ioutil.WriteFile(file, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Open([]Config{{Type: TypeUnix, Path: file}}, nil); err == nil {
+		t.Fatal("expected an error listening at a regular file")
+	}
+}
+
+func TestOpenSystemd(t *testing.T) {
+	newListener := func() net.Listener {
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return l
+	}
+	systemdListeners := map[string][]net.Listener{
+		"check-runner-tcp":   {newListener(), newListener()},
+		"check-runner-local": {newListener()},
+		"LISTEN_FD_6":        {newListener()},
+	}
+	addrs := func(names ...string) []string {
+		var a []string
+		for _, name := range names {
+			for _, l := range systemdListeners[name] {
+				a = append(a, l.Addr().String())
+			}
+		}
+		sort.Strings(a)
+		return a
+	}
+	defer func() {
+		for _, ls := range systemdListeners {
+			for _, l := range ls {
+				l.Close()
+			}
+		}
+	}()
+
+	listeners, err := Open([]Config{
+		{Type: TypeSystemd, Name: "check-runner-local", Auth: AuthNone},
+		{Type: TypeSystemd},
+	}, systemdListeners)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	byAuth := make(map[string][]string)
+	for _, l := range listeners {
+		byAuth[l.Config.Auth] = append(byAuth[l.Config.Auth], l.Addr().String())
+	}
+	for _, a := range byAuth {
+		sort.Strings(a)
+	}
+	expected := map[string][]string{
+		AuthNone:    addrs("check-runner-local"),
+		AuthDefault: addrs("check-runner-tcp", "LISTEN_FD_6"),
+	}
+	if !reflect.DeepEqual(byAuth, expected) {
+		t.Fatalf("expected %v, got %v", expected, byAuth)
+	}
+
+	if _, err := Open([]Config{{Type: TypeSystemd, Name: "nonexistent"}}, systemdListeners); err == nil {
+		t.Fatal("expected an error for a nonexistent systemd socket")
+	}
+	if _, err := Open([]Config{{Type: TypeSystemd}}, nil); err == nil {
+		t.Fatal("expected an error without systemd sockets")
+	}
+}
+
+func TestOpenClosesOnError(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	// The second listener fails, as its address is in use, so the first one must be closed again.
+	free, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := free.Addr().String()
+	free.Close()
+
+	if _, err := Open([]Config{
+		{Type: TypeTCP, Address: addr},
+		{Type: TypeTCP, Address: l.Addr().String()},
+	}, nil); err == nil {
+		t.Fatal("expected an error listening at an address in use")
+	}
+
+	reopened, err := net.Listen("tcp", addr)
+	if err != nil {
+		t.Fatalf("expected the first listener to be closed: %s", err)
+	}
+	reopened.Close()
+}
@@ -0,0 +1,100 @@
+package output
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/dcos/dcos-check-runner/runner"
+)
+
+type junitTestSuites struct {
+	XMLName xml.Name         `xml:"testsuites"`
+	Suites  []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Errors   int             `xml:"errors,attr"`
+	Time     string          `xml:"time,attr"`
+	Cases    []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitMessage `xml:"failure,omitempty"`
+	Error     *junitMessage `xml:"error,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Body    string `xml:",chardata"`
+}
+
+// writeJUnit writes a JUnit XML report with one test case per check. CRITICAL checks are failures, UNKNOWN checks and
+// checks which could not be found or executed are errors. OK and WARNING checks pass, their output is kept in
+// system-out.
+func writeJUnit(w io.Writer, rs *runner.CombinedResponse, opts Options) error {
+	name := opts.Name
+	if name == "" {
+		name = "checks"
+	}
+	suite := junitTestSuite{Name: name}
+
+	var total time.Duration
+	for _, r := range resultsByName(rs) {
+		total += r.duration
+		tc := junitTestCase{
+			Name:      r.name,
+			ClassName: name,
+			Time:      formatSeconds(r.duration),
+		}
+
+		switch rank(r.status) {
+		case statusOK, statusWarning:
+			tc.SystemOut = r.output
+		case statusCritical:
+			tc.Failure = &junitMessage{Message: firstLine(r.output), Type: StatusName(r.status), Body: r.output}
+			suite.Failures++
+		default:
+			tc.Error = &junitMessage{Message: firstLine(r.output), Type: StatusName(r.status), Body: r.output}
+			suite.Errors++
+		}
+		suite.Cases = append(suite.Cases, tc)
+	}
+	suite.Tests = len(suite.Cases)
+	suite.Time = formatSeconds(total)
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(junitTestSuites{Suites: []junitTestSuite{suite}}); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintln(w)
+	return err
+}
+
+// formatSeconds returns d in seconds with millisecond precision, as used by JUnit reports.
+func formatSeconds(d time.Duration) string {
+	return fmt.Sprintf("%.3f", d.Seconds())
+}
+
+// resultsByName returns the results of rs sorted by name.
+func resultsByName(rs *runner.CombinedResponse) []result {
+	rows := results(rs)
+	sort.Slice(rows, func(i, j int) bool {
+		return rows[i].name < rows[j].name
+	})
+	return rows
+}
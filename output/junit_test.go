@@ -0,0 +1,49 @@
+package output
+
+import (
+	"bytes"
+	"encoding/xml"
+	"testing"
+)
+
+func TestJUnit(t *testing.T) {
+	rs := newTestResponse(t, false, "ok", "warning", "critical", "missing1", "missing2")
+
+	var buf bytes.Buffer
+	if err := Write(&buf, "junit", rs, Options{Name: "cluster"}); err != nil {
+		t.Fatal(err)
+	}
+
+	var report junitTestSuites
+	if err := xml.Unmarshal(buf.Bytes(), &report); err != nil {
+		t.Fatalf("invalid XML: %s\n%s", err, buf.String())
+	}
+	if len(report.Suites) != 1 {
+		t.Fatalf("expected 1 test suite, got %d", len(report.Suites))
+	}
+
+	suite := report.Suites[0]
+	if suite.Name != "cluster" || suite.Tests != 5 || suite.Failures != 1 || suite.Errors != 2 {
+		t.Fatalf("unexpected test suite %+v", suite)
+	}
+
+	cases := make(map[string]junitTestCase)
+	for _, tc := range suite.Cases {
+		cases[tc.Name] = tc
+	}
+	if tc := cases["ok"]; tc.Failure != nil || tc.Error != nil || tc.SystemOut != "all good\n" || tc.ClassName != "cluster" {
+		t.Fatalf("unexpected test case %+v", tc)
+	}
+	if tc := cases["warning"]; tc.Failure != nil || tc.Error != nil || tc.SystemOut != "first\nsecond\n" {
+		t.Fatalf("unexpected test case %+v", tc)
+	}
+	if tc := cases["critical"]; tc.Failure == nil || *tc.Failure != (junitMessage{Message: "broken", Type: "CRITICAL", Body: "broken\n"}) {
+		t.Fatalf("unexpected test case %+v", tc)
+	}
+	// Each missing check is reported, although they failed with the same error.
+	for _, name := range []string{"missing1", "missing2"} {
+		if tc := cases[name]; tc.Error == nil || tc.Error.Type != "ERROR" || tc.Error.Message != "Check not found" {
+			t.Fatalf("unexpected test case %+v", tc)
+		}
+	}
+}
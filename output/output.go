@@ -0,0 +1,156 @@
+// Package output formats check results for the command line.
+package output
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dcos/dcos-check-runner/runner"
+)
+
+// Check statuses, following the exec check convention.
+const (
+	statusOK       = 0
+	statusWarning  = 1
+	statusCritical = 2
+	statusUnknown  = 3
+)
+
+// StatusError is the status of checks which could not be found or executed. It ranks above all check statuses.
+const StatusError = 4
+
+// Options configure the output.
+type Options struct {
+	// Color enables ANSI colors, usually when writing to a terminal.
+	Color bool
+
+	// Name identifies the checks in reports, e.g. the check type. It is used as the JUnit test suite name.
+	Name string
+}
+
+// Writer writes a formatted check response to w.
+type Writer func(w io.Writer, rs *runner.CombinedResponse, opts Options) error
+
+var formats = map[string]Writer{
+	"table":   writeTable,
+	"summary": writeSummary,
+	"junit":   writeJUnit,
+	"tap":     writeTAP,
+
+	"prom-textfile": writePrometheus,
+}
+
+// Formats returns the sorted names of the supported output formats, besides the default JSON.
+func Formats() []string {
+	var names []string
+	for name := range formats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Write writes rs to w in the given format.
+func Write(w io.Writer, format string, rs *runner.CombinedResponse, opts Options) error {
+	f, ok := formats[format]
+	if !ok {
+		return fmt.Errorf("unsupported output format %q, expected one of json, %s", format, strings.Join(Formats(), ", "))
+	}
+	return f(w, rs, opts)
+}
+
+// result is a check result or a check which could not be found or executed.
+type result struct {
+	name     string
+	status   int
+	output   string
+	duration time.Duration
+}
+
+// results returns the results of rs, sorted by status, worst first, then by name.
+func results(rs *runner.CombinedResponse) []result {
+	var rows []result
+	for _, check := range rs.Checks() {
+		rows = append(rows, result{
+			name:     check.Name(),
+			status:   check.Status(),
+			output:   check.Output(),
+			duration: check.Duration(),
+		})
+	}
+	for name, err := range rs.Errors() {
+		rows = append(rows, result{name: name, status: StatusError, output: err})
+	}
+
+	sort.Slice(rows, func(i, j int) bool {
+		if rank(rows[i].status) != rank(rows[j].status) {
+			return rank(rows[i].status) > rank(rows[j].status)
+		}
+		return rows[i].name < rows[j].name
+	})
+	return rows
+}
+
+// rank returns the severity of status. Invalid statuses are as severe as UNKNOWN.
+func rank(status int) int {
+	if status == StatusError {
+		return StatusError
+	}
+	if status < statusOK || status > statusUnknown {
+		return statusUnknown
+	}
+	return status
+}
+
+// StatusName returns the name of a check status, or ERROR for checks which could not be found or executed.
+func StatusName(status int) string {
+	switch status {
+	case statusOK:
+		return "OK"
+	case statusWarning:
+		return "WARNING"
+	case statusCritical:
+		return "CRITICAL"
+	case statusUnknown:
+		return "UNKNOWN"
+	case StatusError:
+		return "ERROR"
+	}
+	return fmt.Sprintf("UNKNOWN(%d)", status)
+}
+
+var statusColors = map[int]string{
+	statusOK:       "\x1b[32m",
+	statusWarning:  "\x1b[33m",
+	statusCritical: "\x1b[31m",
+	statusUnknown:  "\x1b[35m",
+	StatusError:    "\x1b[31m",
+}
+
+// colorStatus returns the name of status, colored if enabled in opts. All color codes have the same length, so
+// colored columns stay aligned.
+func colorStatus(status int, opts Options) string {
+	name := StatusName(status)
+	if !opts.Color {
+		return name
+	}
+	return statusColors[rank(status)] + name + "\x1b[0m"
+}
+
+// formatDuration returns d rounded for display.
+func formatDuration(d time.Duration) string {
+	return d.Round(time.Millisecond).String()
+}
+
+// firstLine returns the first non-empty line of s.
+func firstLine(s string) string {
+	for _, line := range strings.Split(s, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			return line
+		}
+	}
+	return ""
+}
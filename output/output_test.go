@@ -0,0 +1,165 @@
+package output
+
+import (
+	"bytes"
+	"context"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/dcos/dcos-check-runner/runner"
+)
+
+const testConfig = `{
+  "cluster_checks": {
+    "ok": {"description": "OK check", "cmd": ["sh", "-c", "echo all good"], "timeout": "1s"},
+    "warning": {"description": "Warning check", "cmd": ["sh", "-c", "echo first; echo second; exit 1"], "timeout": "1s"},
+    "critical": {"description": "Critical check", "cmd": ["sh", "-c", "echo broken; exit 2"], "timeout": "1s"},
+    "also-ok": {"description": "Another OK check", "cmd": ["sh", "-c", "echo fine"], "timeout": "2s"}
+  }
+}`
+
+// newTestResponse returns the response of running or listing the checks of testConfig.
+func newTestResponse(t *testing.T, list bool, checks ...string) *runner.CombinedResponse {
+	if runtime.GOOS == "windows" {
+		t.Skip("test checks require sh")
+	}
+
+	r, err := runner.NewRunner("master")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Load(strings.NewReader(testConfig)); err != nil {
+		t.Fatal(err)
+	}
+	rs, err := r.Cluster(context.Background(), list, checks...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rs
+}
+
+// lines returns the lines of s with runs of spaces collapsed, so that assertions don't depend on column widths.
+func lines(s string) []string {
+	var result []string
+	for _, line := range strings.Split(strings.TrimRight(s, "\n"), "\n") {
+		result = append(result, strings.Join(strings.Fields(line), " "))
+	}
+	return result
+}
+
+func TestTable(t *testing.T) {
+	rs := newTestResponse(t, false)
+
+	var buf bytes.Buffer
+	if err := Write(&buf, "table", rs, Options{}); err != nil {
+		t.Fatal(err)
+	}
+
+	rows := lines(buf.String())
+	expectedPrefixes := []string{
+		"STATUS CHECK DURATION OUTPUT",
+		"CRITICAL critical ",
+		"WARNING warning ",
+		"OK also-ok ",
+		"OK ok ",
+	}
+	if len(rows) != len(expectedPrefixes) {
+		t.Fatalf("expected %d rows, got %q", len(expectedPrefixes), rows)
+	}
+	for i, prefix := range expectedPrefixes {
+		if !strings.HasPrefix(rows[i], prefix) {
+			t.Fatalf("expected row %d to start with %q, got %q", i, prefix, rows[i])
+		}
+	}
+	if !strings.HasSuffix(rows[2], " first") {
+		t.Fatalf("expected only the first line of the output, got %q", rows[2])
+	}
+}
+
+func TestTableColor(t *testing.T) {
+	rs := newTestResponse(t, false, "critical", "ok")
+
+	var buf bytes.Buffer
+	if err := Write(&buf, "table", rs, Options{Color: true}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "\x1b[31mCRITICAL\x1b[0m") || !strings.Contains(buf.String(), "\x1b[32mOK\x1b[0m") {
+		t.Fatalf("expected colored statuses, got %q", buf.String())
+	}
+
+	buf.Reset()
+	if err := Write(&buf, "table", rs, Options{}); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(buf.String(), "\x1b[") {
+		t.Fatalf("expected no colors, got %q", buf.String())
+	}
+}
+
+func TestTableList(t *testing.T) {
+	rs := newTestResponse(t, true)
+
+	var buf bytes.Buffer
+	if err := Write(&buf, "table", rs, Options{}); err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{
+		"CHECK TIMEOUT DESCRIPTION",
+		"also-ok 2s Another OK check",
+		"critical 1s Critical check",
+		"ok 1s OK check",
+		"warning 1s Warning check",
+	}
+	if rows := lines(buf.String()); strings.Join(rows, "\n") != strings.Join(expected, "\n") {
+		t.Fatalf("expected %q, got %q", expected, rows)
+	}
+}
+
+func TestSummary(t *testing.T) {
+	rs := newTestResponse(t, false)
+
+	var buf bytes.Buffer
+	if err := Write(&buf, "summary", rs, Options{}); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+
+	for _, expected := range []string{
+		"Status: CRITICAL\n",
+		"Checks: 4 total, 2 OK, 1 WARNING, 1 CRITICAL, 0 UNKNOWN\n",
+		"\nSlowest checks:\n",
+		"\nFailing checks:\n  CRITICAL critical\n      broken\n  WARNING warning\n      first\n      second\n",
+	} {
+		if !strings.Contains(out, expected) {
+			t.Fatalf("expected summary to contain %q, got:\n%s", expected, out)
+		}
+	}
+	if strings.Contains(out, "all good") {
+		t.Fatalf("expected no output of OK checks, got:\n%s", out)
+	}
+}
+
+func TestSummaryErrors(t *testing.T) {
+	rs := newTestResponse(t, false, "ok", "missing")
+
+	var buf bytes.Buffer
+	if err := Write(&buf, "summary", rs, Options{}); err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{
+		"Status: ERROR\n",
+		"Checks: 2 total, 1 OK, 0 WARNING, 0 CRITICAL, 0 UNKNOWN, 1 ERROR\n",
+		"  ERROR missing\n      Check not found\n",
+	} {
+		if !strings.Contains(buf.String(), expected) {
+			t.Fatalf("expected summary to contain %q, got:\n%s", expected, buf.String())
+		}
+	}
+}
+
+func TestWriteUnsupportedFormat(t *testing.T) {
+	if err := Write(&bytes.Buffer{}, "xml", runner.NewCombinedResponse(false), Options{}); err == nil {
+		t.Fatal("expected error for unsupported format")
+	}
+}
@@ -0,0 +1,108 @@
+package output
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dcos/dcos-check-runner/runner"
+	"github.com/pkg/errors"
+)
+
+// now returns the current time. It is replaced in tests.
+var now = time.Now
+
+// writePrometheus writes the check results as gauges in the Prometheus text format, as read by the node_exporter
+// textfile collector. Checks which could not be found or executed only get the error gauge.
+func writePrometheus(w io.Writer, rs *runner.CombinedResponse, opts Options) error {
+	if rs.List() {
+		return errors.New("listed checks can't be written as metrics")
+	}
+
+	checkType := escapeLabelValue(opts.Name)
+	t := now()
+	timestamp := float64(t.Unix()) + float64(t.Nanosecond())/1e9
+	rows := resultsByName(rs)
+
+	var b strings.Builder
+	metric := func(name, help string, value func(r result) (float64, bool)) {
+		fmt.Fprintf(&b, "# HELP %s %s\n", name, help)
+		fmt.Fprintf(&b, "# TYPE %s gauge\n", name)
+		for _, r := range rows {
+			if v, ok := value(r); ok {
+				fmt.Fprintf(&b, "%s{check=\"%s\",check_type=\"%s\"} %s\n", name, escapeLabelValue(r.name), checkType,
+					strconv.FormatFloat(v, 'f', -1, 64))
+			}
+		}
+	}
+
+	metric("dcos_check_status", "Status of the check: 0 (OK), 1 (WARNING), 2 (CRITICAL) or 3 (UNKNOWN).",
+		func(r result) (float64, bool) {
+			return float64(r.status), r.status != StatusError
+		})
+	metric("dcos_check_duration_seconds", "Time it took to run the check.",
+		func(r result) (float64, bool) {
+			return r.duration.Seconds(), r.status != StatusError
+		})
+	metric("dcos_check_error", "Whether the check could not be found or executed.",
+		func(r result) (float64, bool) {
+			if r.status == StatusError {
+				return 1, true
+			}
+			return 0, true
+		})
+	metric("dcos_check_timestamp_seconds", "Unix time at which the check result was written.",
+		func(r result) (float64, bool) {
+			return timestamp, true
+		})
+
+	fmt.Fprintln(&b, "# HELP dcos_check_combined_status Combined status of all checks of the check type.")
+	fmt.Fprintln(&b, "# TYPE dcos_check_combined_status gauge")
+	fmt.Fprintf(&b, "dcos_check_combined_status{check_type=\"%s\"} %d\n", checkType, rs.Status())
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// escapeLabelValue escapes s for use as a label value in the Prometheus text format.
+func escapeLabelValue(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s)
+}
+
+// WriteFile writes rs in the given format to path. The output is written to a temporary file in the same directory,
+// which is renamed to path, so readers like the node_exporter textfile collector never see a partial file.
+func WriteFile(path, format string, rs *runner.CombinedResponse, opts Options) error {
+	dir, name := filepath.Split(path)
+	if dir == "" {
+		dir = "."
+	}
+
+	// The temporary file must not match *.prom, otherwise it could be collected.
+	f, err := ioutil.TempFile(dir, "."+name+".tmp")
+	if err != nil {
+		return errors.Wrap(err, "unable to create temporary file")
+	}
+	defer os.Remove(f.Name())
+
+	if err := Write(f, format, rs, opts); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Chmod(0644); err != nil {
+		f.Close()
+		return errors.Wrap(err, "unable to set file mode")
+	}
+	if err := f.Close(); err != nil {
+		return errors.Wrap(err, "unable to write temporary file")
+	}
+
+	if err := os.Rename(f.Name(), path); err != nil {
+		return errors.Wrapf(err, "unable to rename temporary file to %s", path)
+	}
+	return nil
+}
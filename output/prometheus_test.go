@@ -0,0 +1,101 @@
+package output
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestPrometheus(t *testing.T) {
+	defer func(f func() time.Time) { now = f }(now)
+	now = func() time.Time { return time.Unix(1500000000, 250000000) }
+
+	rs := newTestResponse(t, false, "ok", "critical", "missing")
+
+	var buf bytes.Buffer
+	if err := Write(&buf, "prom-textfile", rs, Options{Name: "cluster"}); err != nil {
+		t.Fatal(err)
+	}
+
+	out := regexp.MustCompile(`(dcos_check_duration_seconds\{[^}]*\}) \S+`).ReplaceAllString(buf.String(), "$1 0")
+	expected := `# HELP dcos_check_status Status of the check: 0 (OK), 1 (WARNING), 2 (CRITICAL) or 3 (UNKNOWN).
+# TYPE dcos_check_status gauge
+dcos_check_status{check="critical",check_type="cluster"} 2
+dcos_check_status{check="ok",check_type="cluster"} 0
+# HELP dcos_check_duration_seconds Time it took to run the check.
+# TYPE dcos_check_duration_seconds gauge
+dcos_check_duration_seconds{check="critical",check_type="cluster"} 0
+dcos_check_duration_seconds{check="ok",check_type="cluster"} 0
+# HELP dcos_check_error Whether the check could not be found or executed.
+# TYPE dcos_check_error gauge
+dcos_check_error{check="critical",check_type="cluster"} 0
+dcos_check_error{check="missing",check_type="cluster"} 1
+dcos_check_error{check="ok",check_type="cluster"} 0
+# HELP dcos_check_timestamp_seconds Unix time at which the check result was written.
+# TYPE dcos_check_timestamp_seconds gauge
+dcos_check_timestamp_seconds{check="critical",check_type="cluster"} 1500000000.25
+dcos_check_timestamp_seconds{check="missing",check_type="cluster"} 1500000000.25
+dcos_check_timestamp_seconds{check="ok",check_type="cluster"} 1500000000.25
+# HELP dcos_check_combined_status Combined status of all checks of the check type.
+# TYPE dcos_check_combined_status gauge
+dcos_check_combined_status{check_type="cluster"} 2
+`
+	if out != expected {
+		t.Fatalf("expected:\n%s\ngot:\n%s", expected, out)
+	}
+}
+
+func TestPrometheusList(t *testing.T) {
+	rs := newTestResponse(t, true)
+	if err := Write(&bytes.Buffer{}, "prom-textfile", rs, Options{}); err == nil {
+		t.Fatal("expected error for listed checks")
+	}
+}
+
+func TestEscapeLabelValue(t *testing.T) {
+	if s := escapeLabelValue("a\\b\"c\nd"); s != `a\\b\"c\nd` {
+		t.Fatalf("unexpected escaped value %s", s)
+	}
+}
+
+func TestWriteFile(t *testing.T) {
+	rs := newTestResponse(t, false, "ok")
+
+	dir, err := ioutil.TempDir("", "output-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "checks.prom")
+	if err := ioutil.WriteFile(path, []byte("old"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteFile(path, "prom-textfile", rs, Options{Name: "cluster"}); err != nil {
+		t.Fatal(err)
+	}
+
+	body, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(body, []byte(`dcos_check_status{check="ok",check_type="cluster"} 0`)) {
+		t.Fatalf("unexpected file content:\n%s", body)
+	}
+
+	// The temporary file is removed after renaming, and on errors.
+	if err := WriteFile(path, "xml", rs, Options{}); err == nil {
+		t.Fatal("expected error for unsupported format")
+	}
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 || files[0].Name() != "checks.prom" {
+		t.Fatalf("expected only checks.prom, got %d files", len(files))
+	}
+}
@@ -0,0 +1,81 @@
+package output
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/dcos/dcos-check-runner/runner"
+)
+
+// slowestChecks is the number of checks listed as the slowest in the summary.
+const slowestChecks = 5
+
+// writeSummary writes the number of checks per status, the slowest checks, and the full output of all checks which
+// are not OK.
+func writeSummary(w io.Writer, rs *runner.CombinedResponse, opts Options) error {
+	if rs.List() {
+		_, err := fmt.Fprintf(w, "%d checks\n", len(rs.Checks()))
+		return err
+	}
+
+	rows := results(rs)
+	counts := make(map[int]int)
+	status := rs.Status()
+	for _, r := range rows {
+		counts[rank(r.status)]++
+		if r.status == StatusError {
+			status = StatusError
+		}
+	}
+
+	var parts []string
+	for _, s := range []int{statusOK, statusWarning, statusCritical, statusUnknown, StatusError} {
+		if counts[s] > 0 || s != StatusError {
+			parts = append(parts, fmt.Sprintf("%d %s", counts[s], colorStatus(s, opts)))
+		}
+	}
+	fmt.Fprintf(w, "Status: %s\n", colorStatus(status, opts))
+	fmt.Fprintf(w, "Checks: %d total, %s\n", len(rows), strings.Join(parts, ", "))
+
+	var executed []result
+	for _, r := range rows {
+		if r.status != StatusError {
+			executed = append(executed, r)
+		}
+	}
+	sort.SliceStable(executed, func(i, j int) bool {
+		return executed[i].duration > executed[j].duration
+	})
+	if len(executed) > slowestChecks {
+		executed = executed[:slowestChecks]
+	}
+	if len(executed) > 0 {
+		fmt.Fprintln(w, "\nSlowest checks:")
+		for _, r := range executed {
+			fmt.Fprintf(w, "  %-10s %s\n", formatDuration(r.duration), r.name)
+		}
+	}
+
+	var failing []result
+	for _, r := range rows {
+		if r.status != statusOK {
+			failing = append(failing, r)
+		}
+	}
+	if len(failing) > 0 {
+		fmt.Fprintln(w, "\nFailing checks:")
+		for _, r := range failing {
+			fmt.Fprintf(w, "  %s %s\n", colorStatus(r.status, opts), r.name)
+			output := strings.TrimRight(r.output, "\r\n")
+			if output == "" {
+				continue
+			}
+			for _, line := range strings.Split(output, "\n") {
+				fmt.Fprintf(w, "      %s\n", strings.TrimRight(line, "\r"))
+			}
+		}
+	}
+	return nil
+}
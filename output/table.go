@@ -0,0 +1,33 @@
+package output
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/dcos/dcos-check-runner/runner"
+)
+
+// writeTable writes one row per check with its status, duration and the first line of its output. Listed checks are
+// written with their description and timeout instead.
+func writeTable(w io.Writer, rs *runner.CombinedResponse, opts Options) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+
+	if rs.List() {
+		fmt.Fprintln(tw, "CHECK\tTIMEOUT\tDESCRIPTION")
+		for _, check := range rs.Checks() {
+			fmt.Fprintf(tw, "%s\t%s\t%s\n", check.Name(), check.Timeout(), check.Description())
+		}
+		return tw.Flush()
+	}
+
+	fmt.Fprintln(tw, "STATUS\tCHECK\tDURATION\tOUTPUT")
+	for _, r := range results(rs) {
+		duration := "-"
+		if r.status != StatusError {
+			duration = formatDuration(r.duration)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", colorStatus(r.status, opts), r.name, duration, firstLine(r.output))
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,45 @@
+package output
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/dcos/dcos-check-runner/runner"
+)
+
+// writeTAP writes a TAP version 13 report with one test point per check. OK and WARNING checks pass, all other
+// checks fail. Checks which are not OK get a YAML diagnostic block with their status, duration and output.
+func writeTAP(w io.Writer, rs *runner.CombinedResponse, opts Options) error {
+	rows := resultsByName(rs)
+
+	var b strings.Builder
+	b.WriteString("TAP version 13\n")
+	fmt.Fprintf(&b, "1..%d\n", len(rows))
+	for i, r := range rows {
+		result := "ok"
+		if rank(r.status) > statusWarning {
+			result = "not ok"
+		}
+		fmt.Fprintf(&b, "%s %d - %s\n", result, i+1, r.name)
+
+		if r.status == statusOK {
+			continue
+		}
+		b.WriteString("  ---\n")
+		fmt.Fprintf(&b, "  status: %s\n", StatusName(r.status))
+		if r.status != StatusError {
+			fmt.Fprintf(&b, "  duration_ms: %d\n", r.duration.Nanoseconds()/1e6)
+		}
+		if output := strings.TrimRight(r.output, "\r\n"); output != "" {
+			b.WriteString("  output: |\n")
+			for _, line := range strings.Split(output, "\n") {
+				fmt.Fprintf(&b, "    %s\n", strings.TrimRight(line, "\r"))
+			}
+		}
+		b.WriteString("  ...\n")
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,53 @@
+package output
+
+import (
+	"bytes"
+	"regexp"
+	"testing"
+)
+
+func TestTAP(t *testing.T) {
+	rs := newTestResponse(t, false, "ok", "warning", "critical", "missing1", "missing2")
+
+	var buf bytes.Buffer
+	if err := Write(&buf, "tap", rs, Options{}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Durations vary between runs.
+	out := regexp.MustCompile(`duration_ms: \d+`).ReplaceAllString(buf.String(), "duration_ms: 0")
+	expected := `TAP version 13
+1..5
+not ok 1 - critical
+  ---
+  status: CRITICAL
+  duration_ms: 0
+  output: |
+    broken
+  ...
+not ok 2 - missing1
+  ---
+  status: ERROR
+  output: |
+    Check not found
+  ...
+not ok 3 - missing2
+  ---
+  status: ERROR
+  output: |
+    Check not found
+  ...
+ok 4 - ok
+ok 5 - warning
+  ---
+  status: WARNING
+  duration_ms: 0
+  output: |
+    first
+    second
+  ...
+`
+	if out != expected {
+		t.Fatalf("expected:\n%s\ngot:\n%s", expected, out)
+	}
+}
@@ -0,0 +1,242 @@
+package runner
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// builtinCheck is implemented by the native check types which are executed in-process instead of spawning Cmd.
+type builtinCheck interface {
+	run(ctx context.Context) (output []byte, status int, err error)
+}
+
+// builtinCheckFactory returns a builtinCheck configured from the check params. hostRoot is the path under which
+// /proc and /sys are looked up.
+type builtinCheckFactory func(params json.RawMessage, hostRoot string) (builtinCheck, error)
+
+// builtinCheckTypes maps the value of Check.Type to the factory for that check type.
+var builtinCheckTypes = map[string]builtinCheckFactory{
+	"sysctl":         newSysctlCheck,
+	"kernel_module":  newKernelModuleCheck,
+	"cgroup":         newCgroupCheck,
+	"ulimit":         newUlimitCheck,
+	"binary_version": newBinaryVersionCheck,
+	"time_sync":      newTimeSyncCheck,
+}
+
+// newBuiltinCheck returns the builtinCheck for the given check type.
+func newBuiltinCheck(checkType string, params json.RawMessage, hostRoot string) (builtinCheck, error) {
+	factory, ok := builtinCheckTypes[checkType]
+	if !ok {
+		return nil, errors.Errorf("unknown check type %q", checkType)
+	}
+
+	if len(params) == 0 {
+		params = json.RawMessage("{}")
+	}
+	return factory(params, hostRoot)
+}
+
+// hostPath returns path rooted at hostRoot. An empty hostRoot means the real root filesystem.
+func hostPath(hostRoot string, path ...string) string {
+	if hostRoot == "" {
+		hostRoot = "/"
+	}
+	return filepath.Join(append([]string{hostRoot}, path...)...)
+}
+
+// decodeParams decodes the JSON check params into v, rejecting unknown fields so that typos in the config are caught
+// when the config is loaded.
+func decodeParams(params json.RawMessage, v interface{}) error {
+	decoder := json.NewDecoder(strings.NewReader(string(params)))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		return errors.Wrap(err, "unable to decode check params")
+	}
+	return nil
+}
+
+// comparisonOperators is the list of operators supported by compareValues.
+var comparisonOperators = []string{"==", "!=", "<=", ">=", "<", ">"}
+
+func validateOperator(op string) error {
+	for _, o := range comparisonOperators {
+		if o == op {
+			return nil
+		}
+	}
+	return errors.Errorf("invalid operator %q, must be one of %s", op, comparisonOperators)
+}
+
+// parseNumber parses s as a float. The value "unlimited" is treated as infinity.
+func parseNumber(s string) (float64, error) {
+	s = strings.TrimSpace(s)
+	if s == "unlimited" {
+		return math.Inf(1), nil
+	}
+	return strconv.ParseFloat(s, 64)
+}
+
+// compareValues returns the result of "actual op expected". Both values are compared as numbers when possible.
+// Otherwise only == and != are supported and the values are compared as whitespace-normalized strings, which allows
+// matching multi-value sysctls such as net.ipv4.tcp_rmem.
+func compareValues(actual, op, expected string) (bool, error) {
+	a, aErr := parseNumber(actual)
+	e, eErr := parseNumber(expected)
+	if aErr == nil && eErr == nil {
+		switch op {
+		case "==":
+			return a == e, nil
+		case "!=":
+			return a != e, nil
+		case "<":
+			return a < e, nil
+		case "<=":
+			return a <= e, nil
+		case ">":
+			return a > e, nil
+		case ">=":
+			return a >= e, nil
+		}
+		return false, validateOperator(op)
+	}
+
+	normalize := func(s string) string {
+		return strings.Join(strings.Fields(s), " ")
+	}
+	switch op {
+	case "==":
+		return normalize(actual) == normalize(expected), nil
+	case "!=":
+		return normalize(actual) != normalize(expected), nil
+	}
+	return false, errors.Errorf("operator %s requires numeric values, got %q and %q", op, actual, expected)
+}
+
+// versionConstraint is a single comparison such as ">= 1.13" from a constraint string.
+type versionConstraint struct {
+	op      string
+	version string
+}
+
+func (vc versionConstraint) String() string {
+	return vc.op + " " + vc.version
+}
+
+var versionConstraintRegex = regexp.MustCompile(`^(==|!=|<=|>=|<|>|=)?\s*([0-9A-Za-z.\-+_]+)$`)
+
+// parseVersionConstraints parses a comma-separated list of version constraints, e.g. ">= 18.09, < 20". A
+// constraint without an operator requires an exact match.
+func parseVersionConstraints(s string) ([]versionConstraint, error) {
+	var constraints []versionConstraint
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		m := versionConstraintRegex.FindStringSubmatch(part)
+		if m == nil {
+			return nil, errors.Errorf("invalid version constraint %q", part)
+		}
+
+		op := m[1]
+		if op == "" || op == "=" {
+			op = "=="
+		}
+		constraints = append(constraints, versionConstraint{op: op, version: m[2]})
+	}
+
+	if len(constraints) == 0 {
+		return nil, errors.New("empty version constraint")
+	}
+	return constraints, nil
+}
+
+// splitVersion splits a version string into its dot, dash and plus separated segments.
+func splitVersion(v string) []string {
+	return strings.FieldsFunc(strings.TrimPrefix(v, "v"), func(r rune) bool {
+		return r == '.' || r == '-' || r == '+' || r == '_'
+	})
+}
+
+// compareVersions returns -1, 0 or 1 if a is lower than, equal to or greater than b. Numeric segments are compared
+// numerically, other segments lexically. Missing segments are treated as 0, so 1.2 equals 1.2.0, except that a
+// non-numeric segment is lower than a missing one, so pre-releases like 1.2.0-rc1 are lower than 1.2.0.
+func compareVersions(a, b string) int {
+	as, bs := splitVersion(a), splitVersion(b)
+	for i := 0; i < len(as) || i < len(bs); i++ {
+		x, y := "0", "0"
+		if i < len(as) {
+			x = as[i]
+		}
+		if i < len(bs) {
+			y = bs[i]
+		}
+
+		xn, xErr := strconv.ParseUint(x, 10, 64)
+		yn, yErr := strconv.ParseUint(y, 10, 64)
+		switch {
+		case i >= len(as) && yErr != nil:
+			return 1
+		case i >= len(bs) && xErr != nil:
+			return -1
+		case xErr == nil && yErr == nil:
+			if xn != yn {
+				if xn < yn {
+					return -1
+				}
+				return 1
+			}
+		case x != y:
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+// satisfiesVersionConstraints returns true if version satisfies all constraints.
+func satisfiesVersionConstraints(version string, constraints []versionConstraint) bool {
+	for _, c := range constraints {
+		cmp := compareVersions(version, c.version)
+		var ok bool
+		switch c.op {
+		case "==":
+			ok = cmp == 0
+		case "!=":
+			ok = cmp != 0
+		case "<":
+			ok = cmp < 0
+		case "<=":
+			ok = cmp <= 0
+		case ">":
+			ok = cmp > 0
+		case ">=":
+			ok = cmp >= 0
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// checkResult formats the output of a built-in check and returns it along with statusOK or statusCritical.
+func checkResult(ok bool, format string, a ...interface{}) ([]byte, int, error) {
+	status := statusOK
+	if !ok {
+		status = statusCritical
+	}
+	return []byte(fmt.Sprintf(format, a...) + "\n"), status, nil
+}
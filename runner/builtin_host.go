@@ -0,0 +1,333 @@
+package runner
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	goexec "os/exec"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// sysctlCheck compares a kernel parameter from /proc/sys against an expected value.
+type sysctlCheck struct {
+	Key      string `json:"key"`
+	Operator string `json:"operator"`
+	Value    string `json:"value"`
+
+	hostRoot string
+}
+
+func newSysctlCheck(params json.RawMessage, hostRoot string) (builtinCheck, error) {
+	c := &sysctlCheck{Operator: "==", hostRoot: hostRoot}
+	if err := decodeParams(params, c); err != nil {
+		return nil, err
+	}
+	if c.Key == "" {
+		return nil, errors.New("sysctl check requires a key")
+	}
+	if err := validateOperator(c.Operator); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *sysctlCheck) run(ctx context.Context) ([]byte, int, error) {
+	path := hostPath(c.hostRoot, "proc", "sys", strings.Replace(c.Key, ".", "/", -1))
+	value, err := ioutil.ReadFile(path)
+	if err != nil {
+		return checkResult(false, "unable to read sysctl %s: %s", c.Key, err)
+	}
+
+	actual := strings.TrimSpace(string(value))
+	ok, err := compareValues(actual, c.Operator, c.Value)
+	if err != nil {
+		return checkResult(false, "sysctl %s: %s", c.Key, err)
+	}
+	return checkResult(ok, "sysctl %s = %s (expected %s %s)", c.Key, actual, c.Operator, c.Value)
+}
+
+// kernelModuleCheck asserts that kernel modules are loaded or built into the kernel.
+type kernelModuleCheck struct {
+	Modules []string `json:"modules"`
+
+	hostRoot string
+}
+
+func newKernelModuleCheck(params json.RawMessage, hostRoot string) (builtinCheck, error) {
+	c := &kernelModuleCheck{hostRoot: hostRoot}
+	if err := decodeParams(params, c); err != nil {
+		return nil, err
+	}
+	if len(c.Modules) == 0 {
+		return nil, errors.New("kernel_module check requires at least one module")
+	}
+	return c, nil
+}
+
+func (c *kernelModuleCheck) run(ctx context.Context) ([]byte, int, error) {
+	loaded := make(map[string]bool)
+
+	// /proc/modules lists loadable modules. Modules built into the kernel don't show up there, but do have an entry
+	// in /sys/module.
+	f, err := os.Open(hostPath(c.hostRoot, "proc", "modules"))
+	if err == nil {
+		defer f.Close()
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			if fields := strings.Fields(scanner.Text()); len(fields) > 0 {
+				loaded[fields[0]] = true
+			}
+		}
+	}
+
+	var missing []string
+	for _, module := range c.Modules {
+		// Module names may use dashes and underscores interchangeably, the kernel always reports underscores.
+		name := strings.Replace(module, "-", "_", -1)
+		if loaded[name] {
+			continue
+		}
+		if _, err := os.Stat(hostPath(c.hostRoot, "sys", "module", name)); err == nil {
+			continue
+		}
+		missing = append(missing, module)
+	}
+
+	if len(missing) > 0 {
+		return checkResult(false, "kernel modules not loaded: %s", strings.Join(missing, ", "))
+	}
+	return checkResult(true, "kernel modules loaded: %s", strings.Join(c.Modules, ", "))
+}
+
+// cgroupCheck asserts the cgroup hierarchy version and that the required controllers are enabled.
+type cgroupCheck struct {
+	// Version is the required cgroup version, 1 or 2. 0 accepts either version.
+	Version     int      `json:"version"`
+	Controllers []string `json:"controllers"`
+
+	hostRoot string
+}
+
+func newCgroupCheck(params json.RawMessage, hostRoot string) (builtinCheck, error) {
+	c := &cgroupCheck{hostRoot: hostRoot}
+	if err := decodeParams(params, c); err != nil {
+		return nil, err
+	}
+	switch c.Version {
+	case 0, 1, 2:
+	default:
+		return nil, errors.Errorf("invalid cgroup version %d, must be 1 or 2", c.Version)
+	}
+	return c, nil
+}
+
+func (c *cgroupCheck) run(ctx context.Context) ([]byte, int, error) {
+	version := 1
+	var enabled map[string]bool
+	var err error
+
+	// The unified (v2) hierarchy exposes cgroup.controllers at its root.
+	unified := hostPath(c.hostRoot, "sys", "fs", "cgroup", "cgroup.controllers")
+	if _, statErr := os.Stat(unified); statErr == nil {
+		version = 2
+		enabled, err = c.v2Controllers(unified)
+	} else {
+		enabled, err = c.v1Controllers()
+	}
+	if err != nil {
+		return checkResult(false, "unable to read cgroup controllers: %s", err)
+	}
+
+	if c.Version != 0 && c.Version != version {
+		return checkResult(false, "cgroup version %d is in use (expected %d)", version, c.Version)
+	}
+
+	var missing []string
+	for _, controller := range c.Controllers {
+		if !enabled[controller] {
+			missing = append(missing, controller)
+		}
+	}
+	if len(missing) > 0 {
+		return checkResult(false, "cgroup v%d controllers not enabled: %s", version, strings.Join(missing, ", "))
+	}
+	return checkResult(true, "cgroup v%d is in use", version)
+}
+
+// v2Controllers returns the controllers listed in the root cgroup.controllers file.
+func (c *cgroupCheck) v2Controllers(path string) (map[string]bool, error) {
+	body, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	enabled := make(map[string]bool)
+	for _, controller := range strings.Fields(string(body)) {
+		enabled[controller] = true
+	}
+	return enabled, nil
+}
+
+// v1Controllers returns the controllers marked as enabled in /proc/cgroups.
+func (c *cgroupCheck) v1Controllers() (map[string]bool, error) {
+	f, err := os.Open(hostPath(c.hostRoot, "proc", "cgroups"))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	// #subsys_name	hierarchy	num_cgroups	enabled
+	enabled := make(map[string]bool)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 4 && fields[3] == "1" {
+			enabled[fields[0]] = true
+		}
+	}
+	return enabled, scanner.Err()
+}
+
+// ulimitResources maps the short resource names accepted by ulimitCheck to their rows in /proc/<pid>/limits.
+var ulimitResources = map[string]string{
+	"cpu":        "Max cpu time",
+	"fsize":      "Max file size",
+	"data":       "Max data size",
+	"stack":      "Max stack size",
+	"core":       "Max core file size",
+	"rss":        "Max resident set",
+	"nproc":      "Max processes",
+	"nofile":     "Max open files",
+	"memlock":    "Max locked memory",
+	"as":         "Max address space",
+	"locks":      "Max file locks",
+	"sigpending": "Max pending signals",
+	"msgqueue":   "Max msgqueue size",
+	"nice":       "Max nice priority",
+	"rtprio":     "Max realtime priority",
+}
+
+// ulimitCheck compares the soft resource limit of a process against an expected value.
+type ulimitCheck struct {
+	Resource string `json:"resource"`
+	Operator string `json:"operator"`
+	Value    string `json:"value"`
+	// PID is the process whose limits are checked. Defaults to "self".
+	PID string `json:"pid"`
+
+	hostRoot string
+}
+
+func newUlimitCheck(params json.RawMessage, hostRoot string) (builtinCheck, error) {
+	c := &ulimitCheck{Operator: ">=", PID: "self", hostRoot: hostRoot}
+	if err := decodeParams(params, c); err != nil {
+		return nil, err
+	}
+	if _, ok := ulimitResources[c.Resource]; !ok {
+		return nil, errors.Errorf("unknown ulimit resource %q", c.Resource)
+	}
+	if err := validateOperator(c.Operator); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *ulimitCheck) run(ctx context.Context) ([]byte, int, error) {
+	body, err := ioutil.ReadFile(hostPath(c.hostRoot, "proc", c.PID, "limits"))
+	if err != nil {
+		return checkResult(false, "unable to read limits: %s", err)
+	}
+
+	// Limit                     Soft Limit           Hard Limit           Units
+	// Max open files            1024                 4096                 files
+	row := ulimitResources[c.Resource]
+	for _, line := range strings.Split(string(body), "\n") {
+		if !strings.HasPrefix(line, row) {
+			continue
+		}
+		fields := strings.Fields(strings.TrimPrefix(line, row))
+		if len(fields) < 2 {
+			break
+		}
+
+		ok, err := compareValues(fields[0], c.Operator, c.Value)
+		if err != nil {
+			return checkResult(false, "ulimit %s: %s", c.Resource, err)
+		}
+		return checkResult(ok, "ulimit %s = %s (expected %s %s)", c.Resource, fields[0], c.Operator, c.Value)
+	}
+	return checkResult(false, "ulimit %s not found in limits", c.Resource)
+}
+
+// defaultVersionPattern matches the first dotted version number in a program's output.
+const defaultVersionPattern = `\d+(?:\.\d+)+`
+
+// binaryVersionCheck asserts that a binary is installed and that its reported version satisfies a constraint.
+type binaryVersionCheck struct {
+	Binary string   `json:"binary"`
+	Args   []string `json:"args"`
+	// Pattern is a regular expression which extracts the version from the binary's output. If it contains a
+	// capturing group the first group is used, otherwise the whole match.
+	Pattern    string `json:"pattern"`
+	Constraint string `json:"constraint"`
+
+	pattern     *regexp.Regexp
+	constraints []versionConstraint
+}
+
+func newBinaryVersionCheck(params json.RawMessage, hostRoot string) (builtinCheck, error) {
+	c := &binaryVersionCheck{Args: []string{"--version"}, Pattern: defaultVersionPattern}
+	if err := decodeParams(params, c); err != nil {
+		return nil, err
+	}
+	if c.Binary == "" {
+		return nil, errors.New("binary_version check requires a binary")
+	}
+
+	var err error
+	if c.pattern, err = regexp.Compile(c.Pattern); err != nil {
+		return nil, errors.Wrap(err, "invalid version pattern")
+	}
+	if c.Constraint != "" {
+		if c.constraints, err = parseVersionConstraints(c.Constraint); err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+func (c *binaryVersionCheck) run(ctx context.Context) ([]byte, int, error) {
+	path, err := goexec.LookPath(c.Binary)
+	if err != nil {
+		return checkResult(false, "%s not found: %s", c.Binary, err)
+	}
+
+	output, err := goexec.CommandContext(ctx, path, c.Args...).CombinedOutput()
+	if err != nil {
+		return checkResult(false, "unable to get version of %s: %s", c.Binary, err)
+	}
+
+	m := c.pattern.FindStringSubmatch(string(output))
+	if m == nil {
+		return checkResult(false, "unable to find version of %s in output %q", c.Binary, strings.TrimSpace(string(output)))
+	}
+	version := m[0]
+	if len(m) > 1 {
+		version = m[1]
+	}
+
+	if c.constraints == nil {
+		return checkResult(true, "%s version %s is installed", c.Binary, version)
+	}
+	ok := satisfiesVersionConstraints(version, c.constraints)
+	return checkResult(ok, "%s version %s is installed (expected %s)", c.Binary, version, c.Constraint)
+}
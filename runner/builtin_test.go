@@ -0,0 +1,285 @@
+package runner
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// newFakeHostRoot creates a temporary directory containing the given files, which are relative to the host root.
+func newFakeHostRoot(t *testing.T, files map[string]string) string {
+	root, err := ioutil.TempDir("", "dcos-check-runner-root")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for path, content := range files {
+		fullPath := filepath.Join(root, path)
+		if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(fullPath, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+func TestBuiltinChecks(t *testing.T) {
+	root := newFakeHostRoot(t, map[string]string{
+		"proc/sys/net/ipv4/ip_forward":           "1\n",
+		"proc/sys/net/ipv4/tcp_rmem":             "4096\t87380\t6291456\n",
+		"proc/sys/fs/file-max":                   "9223372036854775807\n",
+		"proc/sys/vm/max_map_count":              "65530\n",
+		"proc/modules":                           "overlay 77824 0 - Live 0x0000000000000000\nbr_netfilter 24576 0 - Live 0x0000000000000000\n",
+		"sys/module/ip_tables/refcnt":            "0\n",
+		"proc/cgroups":                           "#subsys_name\thierarchy\tnum_cgroups\tenabled\ncpu\t3\t1\t1\nmemory\t5\t1\t1\nblkio\t2\t1\t0\n",
+		"proc/1234/limits":                       "Limit                     Soft Limit           Hard Limit           Units     \nMax open files            1024                 4096                 files     \nMax processes             unlimited            unlimited            processes \n",
+		"proc/self/limits":                       "Limit                     Soft Limit           Hard Limit           Units     \nMax open files            65536                65536                files     \n",
+		"other/sys/fs/cgroup/cgroup.controllers": "cpuset cpu io memory pids\n",
+	})
+	defer os.RemoveAll(root)
+
+	cgroupV2Root := filepath.Join(root, "other")
+
+	tests := []struct {
+		name           string
+		checkType      string
+		params         string
+		root           string
+		expectedStatus int
+		expectedOutput string
+	}{
+		{"sysctl equal", "sysctl", `{"key": "net.ipv4.ip_forward", "value": "1"}`, root, statusOK, "sysctl net.ipv4.ip_forward = 1 (expected == 1)"},
+		{"sysctl not equal", "sysctl", `{"key": "net.ipv4.ip_forward", "value": "0"}`, root, statusCritical, "sysctl net.ipv4.ip_forward = 1 (expected == 0)"},
+		{"sysctl greater", "sysctl", `{"key": "vm.max_map_count", "operator": ">=", "value": "262144"}`, root, statusCritical, "sysctl vm.max_map_count = 65530 (expected >= 262144)"},
+		{"sysctl large value", "sysctl", `{"key": "fs.file-max", "operator": ">", "value": "65536"}`, root, statusOK, ""},
+		{"sysctl multi-value", "sysctl", `{"key": "net.ipv4.tcp_rmem", "value": "4096 87380 6291456"}`, root, statusOK, ""},
+		{"sysctl multi-value operator", "sysctl", `{"key": "net.ipv4.tcp_rmem", "operator": "<", "value": "1"}`, root, statusCritical, ""},
+		{"sysctl missing", "sysctl", `{"key": "net.ipv6.foo"}`, root, statusCritical, ""},
+		{"kernel modules loaded", "kernel_module", `{"modules": ["overlay", "br-netfilter", "ip_tables"]}`, root, statusOK, ""},
+		{"kernel modules missing", "kernel_module", `{"modules": ["overlay", "dummy", "xfs"]}`, root, statusCritical, "kernel modules not loaded: dummy, xfs"},
+		{"cgroup v1", "cgroup", `{"version": 1, "controllers": ["cpu", "memory"]}`, root, statusOK, "cgroup v1 is in use"},
+		{"cgroup v1 disabled controller", "cgroup", `{"controllers": ["cpu", "blkio"]}`, root, statusCritical, "cgroup v1 controllers not enabled: blkio"},
+		{"cgroup v1 wrong version", "cgroup", `{"version": 2}`, root, statusCritical, "cgroup version 1 is in use (expected 2)"},
+		{"cgroup v2", "cgroup", `{"version": 2, "controllers": ["cpu", "memory", "pids"]}`, cgroupV2Root, statusOK, "cgroup v2 is in use"},
+		{"cgroup v2 missing controller", "cgroup", `{"controllers": ["hugetlb"]}`, cgroupV2Root, statusCritical, "cgroup v2 controllers not enabled: hugetlb"},
+		{"ulimit self", "ulimit", `{"resource": "nofile", "value": "65536"}`, root, statusOK, "ulimit nofile = 65536 (expected >= 65536)"},
+		{"ulimit pid", "ulimit", `{"resource": "nofile", "value": "65536", "pid": "1234"}`, root, statusCritical, "ulimit nofile = 1024 (expected >= 65536)"},
+		{"ulimit unlimited", "ulimit", `{"resource": "nproc", "value": "4096", "pid": "1234"}`, root, statusOK, ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			check := &Check{Type: test.checkType, Params: json.RawMessage(test.params), Timeout: "1s"}
+			if err := check.init(test.root); err != nil {
+				t.Fatal(err)
+			}
+
+			output, status, err := check.Run(context.TODO(), "master")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if status != test.expectedStatus {
+				t.Fatalf("expected status %d, got %d with output %q", test.expectedStatus, status, output)
+			}
+			if test.expectedOutput != "" && strings.TrimSpace(string(output)) != test.expectedOutput {
+				t.Fatalf("expected output %q, got %q", test.expectedOutput, output)
+			}
+		})
+	}
+}
+
+func TestBinaryVersionCheck(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("TestBinaryVersionCheck was skipped on Windows")
+	}
+
+	tests := []struct {
+		params         string
+		expectedStatus int
+		expectedOutput string
+	}{
+		{`{"binary": "./fixture/version.sh"}`, statusOK, "./fixture/version.sh version 18.09.1 is installed"},
+		{`{"binary": "./fixture/version.sh", "constraint": ">= 17.03, < 19"}`, statusOK, ""},
+		{`{"binary": "./fixture/version.sh", "constraint": ">= 18.09.2"}`, statusCritical, "./fixture/version.sh version 18.09.1 is installed (expected >= 18.09.2)"},
+		{`{"binary": "./fixture/version.sh", "constraint": "18.09.1"}`, statusOK, ""},
+		{`{"binary": "./fixture/version.sh", "pattern": "build ([0-9a-f]+)"}`, statusOK, "./fixture/version.sh version 4c52b90 is installed"},
+		{`{"binary": "./fixture/nonexistent.sh"}`, statusCritical, ""},
+	}
+
+	for _, test := range tests {
+		check := &Check{Type: "binary_version", Params: json.RawMessage(test.params), Timeout: "1s"}
+		output, status, err := check.Run(context.TODO(), "master")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if status != test.expectedStatus {
+			t.Fatalf("%s: expected status %d, got %d with output %q", test.params, test.expectedStatus, status, output)
+		}
+		if test.expectedOutput != "" && strings.TrimSpace(string(output)) != test.expectedOutput {
+			t.Fatalf("%s: expected output %q, got %q", test.params, test.expectedOutput, output)
+		}
+	}
+}
+
+func TestBuiltinCheckConfig(t *testing.T) {
+	// Invalid built-in check params are rejected when the config is loaded.
+	for _, check := range []string{
+		`{"type": "nonexistent"}`,
+		`{"type": "sysctl", "params": {}}`,
+		`{"type": "sysctl", "params": {"key": "vm.swappiness", "operator": "~"}}`,
+		`{"type": "sysctl", "params": {"key": "vm.swappiness", "unknown": true}}`,
+		`{"type": "kernel_module", "params": {"modules": []}}`,
+		`{"type": "cgroup", "params": {"version": 3}}`,
+		`{"type": "ulimit", "params": {"resource": "foo"}}`,
+		`{"type": "binary_version", "params": {"binary": "docker", "constraint": "~> 1"}}`,
+	} {
+		r, err := NewRunner("master")
+		if err != nil {
+			t.Fatal(err)
+		}
+		cfg := `{"node_checks": {"checks": {"check1": ` + check + `}, "prestart": ["check1"]}}`
+		if err := r.Load(strings.NewReader(cfg)); err == nil {
+			t.Fatalf("expected an error loading check %s", check)
+		}
+	}
+
+	// Built-in checks use the runner's host root.
+	root := newFakeHostRoot(t, map[string]string{"proc/sys/vm/swappiness": "0\n"})
+	defer os.RemoveAll(root)
+
+	r, err := NewRunner("agent")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := json.Marshal(map[string]interface{}{
+		"host_root": root,
+		"node_checks": map[string]interface{}{
+			"checks": map[string]interface{}{
+				"swappiness": map[string]interface{}{
+					"description": "Swap is disabled",
+					"type":        "sysctl",
+					"params":      map[string]string{"key": "vm.swappiness", "value": "0"},
+					"timeout":     "1s",
+				},
+			},
+			"prestart": []string{"swappiness"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Load(strings.NewReader(string(cfg))); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := r.PreStart(context.TODO(), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := validateCheck("swappiness", statusOK, "sysctl vm.swappiness = 0 (expected == 0)\n", out.checks); err != nil {
+		t.Fatal(err)
+	}
+	// Checks which were not loaded by a Runner are initialized once on first use, even if they are run concurrently.
+	check := &Check{Type: "sysctl", Params: json.RawMessage(`{"key": "vm.swappiness", "value": "0"}`), Timeout: "1s"}
+	done := make(chan struct{})
+	for i := 0; i < 2; i++ {
+		go func() {
+			check.Run(context.TODO(), "agent")
+			done <- struct{}{}
+		}()
+	}
+	for i := 0; i < 2; i++ {
+		<-done
+	}
+	if check.builtin == nil {
+		t.Fatal("expected the check to be initialized")
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		expected int
+	}{
+		{"1.2.3", "1.2.3", 0},
+		{"1.2", "1.2.0", 0},
+		{"v1.10", "1.9", 1},
+		{"18.09.1", "18.09.2", -1},
+		{"1.0.0-rc1", "1.0.0-rc2", -1},
+		{"1.2.0-rc1", "1.2.0", -1},
+		{"1.2", "1.2.0-rc1", 1},
+		{"1.2.0-rc1", "1.2.0-rc1.1", -1},
+		{"2", "10", -1},
+	}
+	for _, test := range tests {
+		if actual := compareVersions(test.a, test.b); actual != test.expected {
+			t.Fatalf("compareVersions(%q, %q): expected %d, got %d", test.a, test.b, test.expected, actual)
+		}
+	}
+}
+
+func TestTimeSyncCheck(t *testing.T) {
+	tests := []struct {
+		name           string
+		params         string
+		tx             timex
+		expectedStatus int
+	}{
+		{"synchronized", `{}`, timex{synchronized: true, offset: time.Millisecond, estimatedError: 2 * time.Millisecond}, statusOK},
+		{"not synchronized", `{}`, timex{synchronized: false, offset: time.Millisecond}, statusCritical},
+		{"offset warning", `{}`, timex{synchronized: true, offset: -200 * time.Millisecond}, statusWarning},
+		{"offset critical", `{}`, timex{synchronized: true, offset: time.Second}, statusCritical},
+		{"error warning", `{"error_warning": "10ms"}`, timex{synchronized: true, estimatedError: 20 * time.Millisecond}, statusWarning},
+		{"error critical", `{"error_critical": "10ms"}`, timex{synchronized: true, estimatedError: 20 * time.Millisecond}, statusCritical},
+		{"custom offset thresholds", `{"offset_warning": "1s", "offset_critical": "2s"}`, timex{synchronized: true, offset: 600 * time.Millisecond}, statusOK},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			builtin, err := newTimeSyncCheck(json.RawMessage(test.params), "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			tx := test.tx
+			builtin.(*timeSyncCheck).readTimex = func() (timex, error) {
+				return tx, nil
+			}
+
+			check := &Check{Type: "time_sync", Timeout: "1s", builtin: builtin}
+			output, status, err := check.Run(context.TODO(), "master")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if status != test.expectedStatus {
+				t.Fatalf("expected status %d, got %d with output %q", test.expectedStatus, status, output)
+			}
+		})
+	}
+
+	// Errors reading the kernel clock state result in an unknown status.
+	builtin, err := newTimeSyncCheck(json.RawMessage(`{}`), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	builtin.(*timeSyncCheck).readTimex = func() (timex, error) {
+		return timex{}, errors.New("not permitted")
+	}
+	check := &Check{Type: "time_sync", Timeout: "1s", builtin: builtin}
+	if _, status, err := check.Run(context.TODO(), "master"); err != nil || status != statusUnknown {
+		t.Fatalf("expected status %d, got %d (error: %v)", statusUnknown, status, err)
+	}
+
+	if _, err := newTimeSyncCheck(json.RawMessage(`{"offset_warning": "soon"}`), ""); err == nil {
+		t.Fatal("expected an error for an invalid threshold")
+	}
+}
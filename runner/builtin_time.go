@@ -0,0 +1,92 @@
+package runner
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// timex is the subset of the kernel clock state used by timeSyncCheck.
+type timex struct {
+	synchronized   bool
+	offset         time.Duration
+	estimatedError time.Duration
+	maxError       time.Duration
+}
+
+// timeSyncCheck asserts that the kernel clock is synchronized and that its offset and estimated error are below the
+// configured thresholds.
+type timeSyncCheck struct {
+	OffsetWarning  string `json:"offset_warning"`
+	OffsetCritical string `json:"offset_critical"`
+	ErrorWarning   string `json:"error_warning"`
+	ErrorCritical  string `json:"error_critical"`
+
+	offsetWarning  time.Duration
+	offsetCritical time.Duration
+	errorWarning   time.Duration
+	errorCritical  time.Duration
+
+	// readTimex returns the kernel clock state. Tests replace it to inject timex values.
+	readTimex func() (timex, error)
+}
+
+func newTimeSyncCheck(params json.RawMessage, hostRoot string) (builtinCheck, error) {
+	c := &timeSyncCheck{
+		OffsetWarning:  "100ms",
+		OffsetCritical: "500ms",
+		ErrorWarning:   "100ms",
+		ErrorCritical:  "500ms",
+		readTimex:      readKernelTimex,
+	}
+	if err := decodeParams(params, c); err != nil {
+		return nil, err
+	}
+
+	for _, threshold := range []struct {
+		name  string
+		value string
+		dest  *time.Duration
+	}{
+		{"offset_warning", c.OffsetWarning, &c.offsetWarning},
+		{"offset_critical", c.OffsetCritical, &c.offsetCritical},
+		{"error_warning", c.ErrorWarning, &c.errorWarning},
+		{"error_critical", c.ErrorCritical, &c.errorCritical},
+	} {
+		d, err := time.ParseDuration(threshold.value)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid %s", threshold.name)
+		}
+		*threshold.dest = d
+	}
+	return c, nil
+}
+
+func (c *timeSyncCheck) run(ctx context.Context) ([]byte, int, error) {
+	tx, err := c.readTimex()
+	if err != nil {
+		return []byte(fmt.Sprintf("unable to read kernel clock state: %s\n", err)), statusUnknown, nil
+	}
+
+	offset := tx.offset
+	if offset < 0 {
+		offset = -offset
+	}
+	summary := fmt.Sprintf("offset %s, estimated error %s, maximum error %s", tx.offset, tx.estimatedError, tx.maxError)
+
+	if !tx.synchronized {
+		return []byte("clock is not synchronized: " + summary + "\n"), statusCritical, nil
+	}
+
+	status := statusOK
+	switch {
+	case offset >= c.offsetCritical || tx.estimatedError >= c.errorCritical:
+		status = statusCritical
+	case offset >= c.offsetWarning || tx.estimatedError >= c.errorWarning:
+		status = statusWarning
+	}
+	return []byte("clock is synchronized: " + summary + "\n"), status, nil
+}
@@ -2,17 +2,18 @@ package runner
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	goexec "os/exec"
-	"runtime"
+	"sync"
 	"time"
 
-	"github.com/dcos/dcos-go/exec"
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 )
 
-// signalKilled is an error message returned by dcos-go/exec if the check exceeds timeout
+// signalKilled is the message of the *exec.ExitError of a command killed by runCommand, e.g. because the check exceeded
+// its timeout.
 const signalKilled = "signal: killed"
 
 // Check is a basic structure that describes DC/OS check.
@@ -29,35 +30,106 @@ type Check struct {
 	// Roles is a list of DC/OS roles (e.g. master, agent). Node must be one of roles
 	// to execute a check.
 	Roles []string `json:"roles"`
+
+	// Type selects a built-in check (e.g. sysctl, kernel_module) which is executed in-process instead of Cmd.
+	Type string `json:"type"`
+
+	// Params configures a built-in check. The expected fields depend on Type.
+	Params json.RawMessage `json:"params"`
+
+	// Provider is the name of a CheckProvider registered with RegisterCheckProvider which is executed in-process
+	// instead of Cmd.
+	Provider string `json:"provider"`
+
+	// Fresh makes every run of the check execute it. By default, a run of the check while it is already being executed
+	// for a concurrent run shares the result of that execution.
+	Fresh bool `json:"fresh"`
+
+	builtin  builtinCheck
+	provider CheckProvider
+
+	// lazyInit guards the initialization of checks which were not loaded by a Runner on first use, which can happen
+	// concurrently.
+	lazyInit    sync.Once
+	lazyInitErr error
+}
+
+// init prepares a built-in or provider check for execution. Built-in checks look up /proc and /sys under hostRoot.
+func (c *Check) init(hostRoot string) error {
+	if c.Type != "" && c.Provider != "" {
+		return errors.New("type and provider are mutually exclusive")
+	}
+
+	if c.Provider != "" {
+		provider, err := lookupCheckProvider(c.Provider)
+		if err != nil {
+			return err
+		}
+		c.provider = provider
+	}
+
+	if c.Type != "" {
+		builtin, err := newBuiltinCheck(c.Type, c.Params, hostRoot)
+		if err != nil {
+			return err
+		}
+		c.builtin = builtin
+	}
+	return nil
 }
 
-// Run executes the given check.
+// Run executes the given check. Exec checks are run as local processes.
 func (c *Check) Run(ctx context.Context, role string) ([]byte, int, error) {
+	return c.run(ctx, role, LocalExecutor{}, nil)
+}
+
+// run executes the given check, using executor to run the command of an exec check. If output is set, it is called for
+// each line of output, while the command runs if executor implements StreamingExecutor.
+func (c *Check) run(ctx context.Context, role string, executor Executor, output func(line string)) ([]byte, int, error) {
 	if !c.verifyRole(role) {
 		return nil, -1, errors.Errorf("check can be executed on a node with the following roles %s. Current role %s", c.Roles, role)
 	}
 
-	if len(c.Cmd) == 0 {
+	if c.Type == "" && c.Provider == "" && len(c.Cmd) == 0 {
 		return nil, -1, errors.New("unable to execute a command with empty Cmd field")
 	}
 
-	timeout, err := time.ParseDuration(c.Timeout)
+	timeout, err := c.timeout()
 	if err != nil {
 		logrus.Warningf("error reading timeout %s. Using default timeout 5sec", err)
-		timeout = time.Second * 5
 	}
 
 	newCtx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
 
-	if runtime.GOOS == "windows" {
-		c.Cmd = append([]string{"powershell.exe"}, c.Cmd...)
+	if c.Type != "" || c.Provider != "" {
+		combinedOutput, code, err := c.runInProcess(newCtx, timeout)
+		emitLines(output, combinedOutput)
+		return combinedOutput, code, err
+	}
+
+	var (
+		combinedOutput []byte
+		code           int
+	)
+	if se, ok := executor.(StreamingExecutor); ok && output != nil {
+		// Stdout and stderr are interleaved in the order they are produced.
+		w := &lineWriter{emit: output}
+		code, err = se.ExecuteStream(newCtx, c.Cmd, w)
+		w.flush()
+		combinedOutput = w.bytes()
+	} else {
+		var stdout, stderr []byte
+		stdout, stderr, code, err = executor.Execute(newCtx, c.Cmd)
+		combinedOutput = append(stdout, stderr...)
+		if err == nil {
+			emitLines(output, combinedOutput)
+		}
 	}
-	stdout, stderr, code, err := exec.FullOutput(exec.CommandContext(newCtx, c.Cmd...))
 	if err != nil {
 		// check if the error happened due to command timeout and treat it as a failed command
 		// instead of error.
-		if c.checkTimeout(err) {
+		if c.checkTimeout(err) || newCtx.Err() == context.DeadlineExceeded {
 			errMsg := fmt.Sprintf("command %s exceeded timeout %s and was killed", c.Cmd, timeout)
 			return []byte(errMsg), statusUnknown, nil
 		}
@@ -65,10 +137,46 @@ func (c *Check) Run(ctx context.Context, role string) ([]byte, int, error) {
 		return nil, -1, err
 	}
 
-	combinedOutput := append(stdout, stderr...)
 	return combinedOutput, code, nil
 }
 
+// emitLines calls output for each line of b, if output is set.
+func emitLines(output func(line string), b []byte) {
+	if output == nil || len(b) == 0 {
+		return
+	}
+	w := &lineWriter{emit: output}
+	w.Write(b)
+	w.flush()
+}
+
+// runInProcess executes a built-in or provider check. Checks which were not loaded by a Runner are initialized on
+// first use.
+func (c *Check) runInProcess(ctx context.Context, timeout time.Duration) ([]byte, int, error) {
+	c.lazyInit.Do(func() {
+		if c.builtin == nil && c.provider == nil {
+			c.lazyInitErr = c.init("")
+		}
+	})
+	if c.lazyInitErr != nil {
+		return nil, -1, c.lazyInitErr
+	}
+
+	if c.provider != nil {
+		return callWithTimeout(ctx, c.Provider, timeout, c.provider.RunCheck)
+	}
+	return callWithTimeout(ctx, c.Type, timeout, c.builtin.run)
+}
+
+// timeout returns the timeout of c, or 5 seconds and an error if it is invalid.
+func (c *Check) timeout() (time.Duration, error) {
+	timeout, err := time.ParseDuration(c.Timeout)
+	if err != nil {
+		return 5 * time.Second, err
+	}
+	return timeout, nil
+}
+
 func (c *Check) verifyRole(role string) bool {
 	// no roles means we are allowed to execute a check on any node.
 	if len(c.Roles) == 0 {
@@ -88,7 +196,7 @@ func (c *Check) verifyRole(role string) bool {
 // exec.ExitError, second the error message must be "signal: killed". Unfortunately go native exec package does not
 // expose the concrete error for a process to be killed, that is why we have to type assert first and then check
 // against hardcoded string. see https://golang.org/src/os/exec_posix.go Line 85
-func (c Check) checkTimeout(e error) bool {
+func (c *Check) checkTimeout(e error) bool {
 	if exiterr, ok := e.(*goexec.ExitError); ok {
 		return signalKilled == exiterr.Error()
 	}
@@ -0,0 +1,117 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// Executor runs the commands of exec checks. Implementations must stop the command and return when ctx is done. The
+// returned code is the command's exit code, err is only set if the command could not be executed.
+type Executor interface {
+	Execute(ctx context.Context, cmd []string) (stdout, stderr []byte, code int, err error)
+}
+
+// ExecutorFunc is an adapter to allow the use of ordinary functions as an Executor.
+type ExecutorFunc func(ctx context.Context, cmd []string) ([]byte, []byte, int, error)
+
+// Execute calls f(ctx, cmd).
+func (f ExecutorFunc) Execute(ctx context.Context, cmd []string) ([]byte, []byte, int, error) {
+	return f(ctx, cmd)
+}
+
+// LocalExecutor runs check commands as local processes. It is the default Executor of a Runner, which runs commands
+// with its CheckEnv unless Env is set.
+type LocalExecutor struct {
+	// Env is set in the environment of commands, in addition to the environment of the process.
+	Env map[string]string
+}
+
+// Execute runs cmd as a child process and waits for it to exit. On Windows the command is run with powershell.exe.
+func (e LocalExecutor) Execute(ctx context.Context, cmd []string) ([]byte, []byte, int, error) {
+	var stdout, stderr bytes.Buffer
+	code, err := runCommand(ctx, localCommand(cmd, e.Env), &stdout, &stderr)
+	return stdout.Bytes(), stderr.Bytes(), code, err
+}
+
+// localCommand returns the command running cmd as a local process with env added to the environment of the process. On
+// Windows the command is run with powershell.exe.
+func localCommand(cmd []string, env map[string]string) *exec.Cmd {
+	if runtime.GOOS == "windows" {
+		cmd = append([]string{"powershell.exe"}, cmd...)
+	}
+	name := cmd[0]
+	if path, ok := env["PATH"]; ok && runtime.GOOS != "windows" && !strings.Contains(name, "/") {
+		// The command is looked up in the PATH of the check environment instead of the one of the process.
+		if p, err := lookPath(name, path); err == nil {
+			name = p
+		}
+	}
+	c := exec.Command(name, cmd[1:]...)
+	if len(env) > 0 {
+		// Later entries take precedence over the environment of the process.
+		c.Env = os.Environ()
+		for k, v := range env {
+			c.Env = append(c.Env, k+"="+v)
+		}
+	}
+	return c
+}
+
+// lookPath returns the path of the executable file in the directories of path, like exec.LookPath does with the PATH
+// of the process.
+func lookPath(file, path string) (string, error) {
+	for _, dir := range filepath.SplitList(path) {
+		if dir == "" {
+			dir = "."
+		}
+		p := filepath.Join(dir, file)
+		if fi, err := os.Stat(p); err == nil && !fi.IsDir() && fi.Mode()&0111 != 0 {
+			return p, nil
+		}
+	}
+	return "", errors.Errorf("executable file %s not found in %s", file, path)
+}
+
+// runCommand runs c with the given stdout and stderr in a new process group and returns its exit code. When ctx is
+// done, the whole process group is killed, so that processes started by the command don't outlive it and keep its
+// output open. err is set if the command could not be run or did not exit on its own, in which case it is ctx.Err() if
+// ctx is done.
+func runCommand(ctx context.Context, c *exec.Cmd, stdout, stderr io.Writer) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	c.Stdout = stdout
+	c.Stderr = stderr
+	setProcessGroup(c)
+	if err := c.Start(); err != nil {
+		return 0, err
+	}
+
+	exited := make(chan struct{})
+	defer close(exited)
+	go func() {
+		select {
+		case <-ctx.Done():
+			if err := killProcessGroup(c.Process); err != nil {
+				logrus.WithError(err).Warnf("Unable to kill command %s", c.Args)
+			}
+		case <-exited:
+		}
+	}()
+
+	code, err := exitCode(c.Wait())
+	if err != nil && ctx.Err() != nil {
+		return 0, ctx.Err()
+	}
+	return code, err
+}
@@ -0,0 +1,188 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+// fakeExecutor is an Executor which records the commands it is asked to run and returns canned results.
+type fakeExecutor struct {
+	mu       sync.Mutex
+	commands [][]string
+}
+
+func (e *fakeExecutor) Execute(ctx context.Context, cmd []string) ([]byte, []byte, int, error) {
+	e.mu.Lock()
+	e.commands = append(e.commands, cmd)
+	e.mu.Unlock()
+
+	switch cmd[0] {
+	case "fail":
+		return []byte("stdout\n"), []byte("stderr\n"), statusCritical, nil
+	case "error":
+		return nil, nil, 0, errors.New("unable to run command")
+	case "hang":
+		<-ctx.Done()
+		return nil, nil, 0, ctx.Err()
+	}
+	return []byte(strings.Join(cmd, " ") + "\n"), nil, statusOK, nil
+}
+
+func TestExecutor(t *testing.T) {
+	r, err := NewRunner("agent")
+	if err != nil {
+		t.Fatal(err)
+	}
+	executor := &fakeExecutor{}
+	r.Executor = executor
+
+	cfg := `
+{
+  "node_checks": {
+    "checks": {
+      "check1": {
+        "cmd": ["ok", "--flag"],
+        "timeout": "1s"
+      },
+      "check2": {
+        "cmd": ["fail"],
+        "timeout": "1s"
+      },
+      "check3": {
+        "cmd": ["hang"],
+        "timeout": "100ms"
+      },
+      "check4": {
+        "cmd": ["error"],
+        "timeout": "1s"
+      }
+    },
+    "prestart": ["check1", "check2", "check3"],
+    "poststart": ["check4"]
+  }
+}`
+	if err := r.Load(strings.NewReader(cfg)); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := r.PreStart(context.TODO(), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.Status() != statusUnknown {
+		t.Fatalf("expected status %d, got %d", statusUnknown, out.Status())
+	}
+	if check := out.checks["check1"]; check.status != statusOK || check.output != "ok --flag\n" {
+		t.Fatalf("unexpected result for check1: status %d, output %q", check.status, check.output)
+	}
+	if check := out.checks["check2"]; check.status != statusCritical || check.output != "stdout\nstderr\n" {
+		t.Fatalf("unexpected result for check2: status %d, output %q", check.status, check.output)
+	}
+	// Executors report timeouts through the context.
+	if check := out.checks["check3"]; check.status != statusUnknown || check.output != "command [hang] exceeded timeout 100ms and was killed" {
+		t.Fatalf("unexpected result for check3: status %d, output %q", check.status, check.output)
+	}
+
+	executor.mu.Lock()
+	if len(executor.commands) != 3 {
+		t.Fatalf("expected 3 commands to be executed, got %d", len(executor.commands))
+	}
+	executor.mu.Unlock()
+
+	// Executor errors are reported as execution errors.
+	out, err = r.PostStart(context.TODO(), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(out.errs) != 1 {
+		t.Fatalf("expected 1 error, got %d", len(out.errs))
+	}
+
+	// Listing checks doesn't execute any commands.
+	executor.mu.Lock()
+	executor.commands = nil
+	executor.mu.Unlock()
+	if _, err := r.PreStart(context.TODO(), true); err != nil {
+		t.Fatal(err)
+	}
+	if len(executor.commands) != 0 {
+		t.Fatalf("expected no commands to be executed, got %v", executor.commands)
+	}
+}
+
+func TestLocalExecutor(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("TestLocalExecutor was skipped on Windows")
+	}
+
+	stdout, stderr, code, err := LocalExecutor{}.Execute(context.TODO(), []string{"./fixture/exit.sh"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if code != 3 {
+		t.Fatalf("expected exit code 3, got %d", code)
+	}
+	if !reflect.DeepEqual(stdout, []byte("Failed\n")) || len(stderr) != 0 {
+		t.Fatalf("unexpected output %q, %q", stdout, stderr)
+	}
+
+	// Env is added to the environment of the command, which is looked up in the PATH of Env.
+	dir, err := ioutil.TempDir("", "local-executor-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	script := "#!/bin/sh\necho \"$CHECK_VAR\"\n"
+	if err := ioutil.WriteFile(filepath.Join(dir, "check-env"), []byte(script), 0700); err != nil {
+		t.Fatal(err)
+	}
+	env := map[string]string{"PATH": dir + ":" + os.Getenv("PATH"), "CHECK_VAR": "value"}
+	stdout, _, _, err = LocalExecutor{Env: env}.Execute(context.TODO(), []string{"check-env"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(stdout) != "value\n" {
+		t.Fatalf("expected the check environment, got %q", stdout)
+	}
+
+	// The runner runs commands with its CheckEnv, and variables removed by a reload are no longer set.
+	r, err := NewRunner("agent")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := `{"node_checks": {"checks": {"check1": {"cmd": ["check-env"], "timeout": "1s"}}, "poststart": ["check1"]}, ` +
+		`"check_env": {"PATH": "%s", "CHECK_VAR": "value"}}`
+	if err := r.Load(strings.NewReader(fmt.Sprintf(cfg, env["PATH"]))); err != nil {
+		t.Fatal(err)
+	}
+	rs, err := r.PostStart(context.TODO(), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if output := rs.checks["check1"].Output(); output != "value\n" {
+		t.Fatalf("expected the check environment, got %q", output)
+	}
+
+	cfg = `{"node_checks": {"checks": {"check1": {"cmd": ["check-env"], "timeout": "1s"}}, "poststart": ["check1"]}, ` +
+		`"check_env": {"PATH": "%s"}}`
+	if err := r.Reload(strings.NewReader(fmt.Sprintf(cfg, env["PATH"]))); err != nil {
+		t.Fatal(err)
+	}
+	rs, err = r.PostStart(context.TODO(), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if output := rs.checks["check1"].Output(); output != "\n" {
+		t.Fatalf("expected CHECK_VAR to be unset after reloading, got %q", output)
+	}
+}
@@ -0,0 +1,3 @@
+#!/bin/sh
+
+echo "Docker version 18.09.1, build 4c52b90"
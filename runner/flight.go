@@ -0,0 +1,145 @@
+package runner
+
+import (
+	"context"
+	"sync"
+)
+
+// flightGroup deduplicates concurrent executions of the same check. A run of a check which is already being executed
+// for a concurrent run waits for that execution and shares its result, instead of executing the check again.
+type flightGroup struct {
+	mu      sync.Mutex
+	flights map[*Check]*flight
+}
+
+// flight is an execution of a check shared by concurrent runs.
+type flight struct {
+	// cancel stops the execution. It is called when all waiting runs are canceled.
+	cancel context.CancelFunc
+
+	// streaming is set if the output is emitted while the check runs. Otherwise it is emitted to the waiting runs when
+	// the check finished.
+	streaming bool
+
+	done   chan struct{}
+	output []byte
+	code   int
+	err    error
+
+	mu      sync.Mutex
+	waiters int
+	lines   []string
+	emits   map[int]func(line string)
+	nextID  int
+}
+
+// do executes c with execute and returns its result, or waits for and returns the result of an execution of c which
+// is already in progress. If output is set, it is called for each line of output, including the lines the check printed
+// before the run joined the execution.
+//
+// The execution is not bound to ctx, as it is shared by all waiting runs. It is canceled once all of them are canceled,
+// and the last of them returns when the execution returned.
+func (g *flightGroup) do(ctx context.Context, c *Check, output func(line string),
+	execute func(ctx context.Context, output func(line string)) ([]byte, int, error)) ([]byte, int, error) {
+
+	g.mu.Lock()
+	if g.flights == nil {
+		g.flights = make(map[*Check]*flight)
+	}
+	f, ok := g.flights[c]
+	if !ok || f.abandoned() {
+		flightCtx, cancel := context.WithCancel(context.Background())
+		f = &flight{
+			cancel:    cancel,
+			streaming: output != nil,
+			done:      make(chan struct{}),
+			emits:     make(map[int]func(string)),
+		}
+		g.flights[c] = f
+
+		var emit func(string)
+		if f.streaming {
+			emit = f.emit
+		}
+		go func() {
+			defer cancel()
+			out, code, err := execute(flightCtx, emit)
+
+			g.mu.Lock()
+			if g.flights[c] == f {
+				delete(g.flights, c)
+			}
+			g.mu.Unlock()
+
+			f.output, f.code, f.err = out, code, err
+			close(f.done)
+		}()
+	}
+	id := f.join(output)
+	g.mu.Unlock()
+
+	select {
+	case <-f.done:
+		if !f.streaming && f.err == nil {
+			emitLines(output, f.output)
+		}
+		return f.output, f.code, f.err
+	case <-ctx.Done():
+		g.mu.Lock()
+		canceled := f.leave(id)
+		g.mu.Unlock()
+		if canceled {
+			<-f.done
+		}
+		return nil, -1, ctx.Err()
+	}
+}
+
+// join adds a waiting run and replays the lines emitted so far to its output. It returns an ID identifying the run.
+func (f *flight) join(output func(line string)) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.waiters++
+	f.nextID++
+	if output != nil && f.streaming {
+		for _, line := range f.lines {
+			output(line)
+		}
+		f.emits[f.nextID] = output
+	}
+	return f.nextID
+}
+
+// abandoned returns whether all runs waiting for f were canceled, so that its execution is canceled as well.
+func (f *flight) abandoned() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.waiters == 0
+}
+
+// leave removes a waiting run which was canceled, and cancels the execution if no run is waiting anymore. It returns
+// true if the execution was canceled.
+func (f *flight) leave(id int) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	delete(f.emits, id)
+	f.waiters--
+	if f.waiters == 0 {
+		f.cancel()
+		return true
+	}
+	return false
+}
+
+// emit passes a line of output to all waiting runs.
+func (f *flight) emit(line string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.lines = append(f.lines, line)
+	for _, output := range f.emits {
+		output(line)
+	}
+}
@@ -0,0 +1,187 @@
+package runner
+
+import (
+	"context"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// gateExecutor is a StreamingExecutor which prints a line, blocks until it is released and prints another line.
+type gateExecutor struct {
+	release chan struct{}
+
+	mu      sync.Mutex
+	started int
+}
+
+func (e *gateExecutor) ExecuteStream(ctx context.Context, cmd []string, output io.Writer) (int, error) {
+	e.mu.Lock()
+	e.started++
+	e.mu.Unlock()
+
+	io.WriteString(output, "started\n")
+	select {
+	case <-e.release:
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	}
+	io.WriteString(output, "finished\n")
+	return statusOK, nil
+}
+
+func (e *gateExecutor) Execute(ctx context.Context, cmd []string) ([]byte, []byte, int, error) {
+	var b strings.Builder
+	code, err := e.ExecuteStream(ctx, cmd, &b)
+	return []byte(b.String()), nil, code, err
+}
+
+func (e *gateExecutor) executions() int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.started
+}
+
+// waitForExecutions waits until e started n executions.
+func (e *gateExecutor) waitForExecutions(t *testing.T, n int) {
+	deadline := time.Now().Add(5 * time.Second)
+	for e.executions() < n {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected %d executions, got %d", n, e.executions())
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// lineRecorder is a StreamHandler which records the output lines of a check.
+type lineRecorder struct {
+	mu    sync.Mutex
+	lines []string
+}
+
+func (l *lineRecorder) CheckOutput(check, line string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.lines = append(l.lines, line)
+}
+
+func (l *lineRecorder) CheckResult(check string, resp *Response, err error) {}
+
+func newGateRunner(t *testing.T, fresh bool) (*Runner, *gateExecutor) {
+	r, err := NewRunner("master")
+	if err != nil {
+		t.Fatal(err)
+	}
+	executor := &gateExecutor{release: make(chan struct{})}
+	r.Executor = executor
+
+	cfg := `{"cluster_checks": {"locking-check": {"cmd": ["lock"], "timeout": "5s", "fresh": false}}}`
+	if fresh {
+		cfg = strings.Replace(cfg, `"fresh": false`, `"fresh": true`, 1)
+	}
+	if err := r.Load(strings.NewReader(cfg)); err != nil {
+		t.Fatal(err)
+	}
+	return r, executor
+}
+
+func TestSharedExecution(t *testing.T) {
+	r, executor := newGateRunner(t, false)
+
+	const runs = 5
+	results := make(chan *CombinedResponse, runs)
+	for i := 0; i < runs; i++ {
+		go func() {
+			rs, err := r.Cluster(context.Background(), false)
+			if err != nil {
+				t.Error(err)
+			}
+			results <- rs
+		}()
+	}
+
+	executor.waitForExecutions(t, 1)
+	// Give the other runs time to join the execution.
+	time.Sleep(50 * time.Millisecond)
+	close(executor.release)
+
+	for i := 0; i < runs; i++ {
+		rs := <-results
+		check := rs.checks["locking-check"]
+		if check == nil || check.status != statusOK || check.output != "started\nfinished\n" {
+			t.Fatalf("unexpected result %+v", check)
+		}
+	}
+	if n := executor.executions(); n != 1 {
+		t.Fatalf("expected 1 shared execution, got %d", n)
+	}
+
+	// Runs after the execution finished execute the check again.
+	if _, err := r.Cluster(context.Background(), false); err != nil {
+		t.Fatal(err)
+	}
+	if n := executor.executions(); n != 2 {
+		t.Fatalf("expected 2 executions, got %d", n)
+	}
+}
+
+func TestFreshExecution(t *testing.T) {
+	r, executor := newGateRunner(t, true)
+
+	const runs = 3
+	var wg sync.WaitGroup
+	for i := 0; i < runs; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := r.Cluster(context.Background(), false); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+
+	executor.waitForExecutions(t, runs)
+	close(executor.release)
+	wg.Wait()
+}
+
+func TestSharedExecutionCancel(t *testing.T) {
+	r, executor := newGateRunner(t, false)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	canceled := make(chan error)
+	go func() {
+		_, err := r.Stream(ctx, SuiteCluster, &lineRecorder{})
+		canceled <- err
+	}()
+	executor.waitForExecutions(t, 1)
+
+	// A streaming run joining the execution receives the lines printed so far.
+	joined := &lineRecorder{}
+	result := make(chan *CombinedResponse)
+	go func() {
+		rs, _ := r.Stream(context.Background(), SuiteCluster, joined)
+		result <- rs
+	}()
+	time.Sleep(50 * time.Millisecond)
+
+	// Canceling one run doesn't cancel the execution shared with another run.
+	cancel()
+	if err := <-canceled; err != context.Canceled {
+		t.Fatalf("expected the canceled run to fail, got %v", err)
+	}
+	close(executor.release)
+
+	rs := <-result
+	if check := rs.checks["locking-check"]; check == nil || check.output != "started\nfinished\n" {
+		t.Fatalf("unexpected result %+v", check)
+	}
+	if strings.Join(joined.lines, ",") != "started,finished" {
+		t.Fatalf("unexpected output lines %v", joined.lines)
+	}
+	if n := executor.executions(); n != 1 {
+		t.Fatalf("expected 1 shared execution, got %d", n)
+	}
+}
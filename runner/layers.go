@@ -0,0 +1,162 @@
+package runner
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// configFile is a check configuration file, which is merged with the files loaded before it.
+type configFile struct {
+	name string
+	data []byte
+}
+
+// configLayer is a check configuration file decoded only as far as needed to merge it. Checks are kept as their JSON
+// fields, so that a layer overrides only the fields it sets.
+type configLayer struct {
+	ClusterChecks map[string]map[string]json.RawMessage `json:"cluster_checks"`
+	NodeChecks    struct {
+		Checks          map[string]map[string]json.RawMessage `json:"checks"`
+		PreStart        []string                              `json:"prestart"`
+		PostStart       []string                              `json:"poststart"`
+		PreStartRemove  []string                              `json:"prestart_remove"`
+		PostStartRemove []string                              `json:"poststart_remove"`
+	} `json:"node_checks"`
+	CheckEnv map[string]string `json:"check_env"`
+	HostRoot *string           `json:"host_root"`
+}
+
+// mergedConfig is the effective configuration merged from all layers.
+type mergedConfig struct {
+	ClusterChecks map[string]map[string]json.RawMessage `json:"cluster_checks"`
+	NodeChecks    struct {
+		Checks    map[string]map[string]json.RawMessage `json:"checks"`
+		PreStart  []string                              `json:"prestart"`
+		PostStart []string                              `json:"poststart"`
+	} `json:"node_checks"`
+	CheckEnv map[string]string `json:"check_env,omitempty"`
+	HostRoot string            `json:"host_root,omitempty"`
+}
+
+// mergeConfigs merges the check configuration files in order and returns the effective configuration as JSON:
+//
+//   - Checks are added, or override the fields they set of a check of the same name loaded before.
+//   - Checks with "enabled": false are removed, also from the prestart and poststart lists.
+//   - Checks in prestart and poststart are added to the lists, and those in prestart_remove and poststart_remove are
+//     removed from them.
+//   - check_env variables are added or override variables of the same name, and host_root overrides the host root.
+func mergeConfigs(files []configFile) ([]byte, error) {
+	var merged mergedConfig
+	merged.ClusterChecks = make(map[string]map[string]json.RawMessage)
+	merged.NodeChecks.Checks = make(map[string]map[string]json.RawMessage)
+	merged.NodeChecks.PreStart = []string{}
+	merged.NodeChecks.PostStart = []string{}
+
+	for _, f := range files {
+		var layer configLayer
+		if err := json.Unmarshal(f.data, &layer); err != nil {
+			return nil, errors.Wrapf(err, "unable to decode config file %s", f.name)
+		}
+
+		mergeChecks(merged.ClusterChecks, layer.ClusterChecks)
+		mergeChecks(merged.NodeChecks.Checks, layer.NodeChecks.Checks)
+		merged.NodeChecks.PreStart = mergeList(merged.NodeChecks.PreStart, layer.NodeChecks.PreStart, layer.NodeChecks.PreStartRemove)
+		merged.NodeChecks.PostStart = mergeList(merged.NodeChecks.PostStart, layer.NodeChecks.PostStart, layer.NodeChecks.PostStartRemove)
+
+		for k, v := range layer.CheckEnv {
+			if merged.CheckEnv == nil {
+				merged.CheckEnv = make(map[string]string)
+			}
+			merged.CheckEnv[k] = v
+		}
+		if layer.HostRoot != nil {
+			merged.HostRoot = *layer.HostRoot
+		}
+	}
+
+	// Disabled checks are removed once all layers are merged, so that a later layer can enable them again.
+	var disabled []string
+	for _, checks := range []map[string]map[string]json.RawMessage{merged.ClusterChecks, merged.NodeChecks.Checks} {
+		for name, fields := range checks {
+			enabled := true
+			if raw, ok := fields["enabled"]; ok {
+				if err := json.Unmarshal(raw, &enabled); err != nil {
+					return nil, errors.Wrapf(err, "invalid enabled field of check %s", name)
+				}
+				delete(fields, "enabled")
+			}
+			if !enabled {
+				delete(checks, name)
+				disabled = append(disabled, name)
+			}
+		}
+	}
+	merged.NodeChecks.PreStart = mergeList(merged.NodeChecks.PreStart, nil, disabled)
+	merged.NodeChecks.PostStart = mergeList(merged.NodeChecks.PostStart, nil, disabled)
+
+	return json.MarshalIndent(merged, "", "  ")
+}
+
+// mergeChecks adds the checks of layer to checks, overriding the fields of checks which already exist.
+func mergeChecks(checks, layer map[string]map[string]json.RawMessage) {
+	for name, fields := range layer {
+		if fields == nil {
+			continue
+		}
+		check, ok := checks[name]
+		if !ok {
+			check = make(map[string]json.RawMessage)
+			checks[name] = check
+		}
+		for field, value := range fields {
+			check[field] = value
+		}
+	}
+}
+
+// mergeList returns list with the names in add appended unless they are already in it, and the names in remove
+// removed.
+func mergeList(list, add, remove []string) []string {
+	removed := make(map[string]bool)
+	for _, name := range remove {
+		removed[name] = true
+	}
+
+	merged := []string{}
+	for _, name := range dedupeStrings(append(list, add...)) {
+		if !removed[name] {
+			merged = append(merged, name)
+		}
+	}
+	return merged
+}
+
+// ConfigFiles returns files followed by the files in dir ending in .json, sorted by name, which is the order in which
+// they are merged by LoadFromFiles. dir is ignored if it is empty or doesn't exist.
+func ConfigFiles(files []string, dir string) ([]string, error) {
+	all := append([]string{}, files...)
+	if dir == "" {
+		return all, nil
+	}
+
+	infos, err := ioutil.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return all, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to read config directory")
+	}
+	for _, fi := range infos {
+		// Hidden files are skipped, e.g. temporary files of editors.
+		if fi.IsDir() || strings.HasPrefix(fi.Name(), ".") || filepath.Ext(fi.Name()) != ".json" {
+			continue
+		}
+		all = append(all, filepath.Join(dir, fi.Name()))
+	}
+	return all, nil
+}
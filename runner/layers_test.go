@@ -0,0 +1,187 @@
+package runner
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const baseConfig = `
+{
+  "cluster_checks": {
+    "cluster-check": {"cmd": ["cluster"], "timeout": "1s"}
+  },
+  "node_checks": {
+    "checks": {
+      "check1": {"description": "First check", "cmd": ["check1"], "timeout": "1s"},
+      "check2": {"cmd": ["check2"], "timeout": "1s"},
+      "check3": {"cmd": ["check3"], "timeout": "1s"}
+    },
+    "prestart": ["check1", "check2"],
+    "poststart": ["check1", "check2", "check3"]
+  },
+  "check_env": {"PATH": "/usr/bin", "LANG": "C"}
+}`
+
+func TestMergeConfigs(t *testing.T) {
+	override := `
+{
+  "cluster_checks": {
+    "site-check": {"cmd": ["site"], "timeout": "5s"}
+  },
+  "node_checks": {
+    "checks": {
+      "check1": {"timeout": "10s"},
+      "check2": {"enabled": false},
+      "check4": {"cmd": ["check4"], "timeout": "1s"}
+    },
+    "prestart": ["check4"],
+    "poststart_remove": ["check3"]
+  },
+  "check_env": {"PATH": "/opt/bin:/usr/bin"},
+  "host_root": "/host"
+}`
+
+	merged, err := mergeConfigs([]configFile{{"base", []byte(baseConfig)}, {"override", []byte(override)}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var cfg map[string]interface{}
+	if err := json.Unmarshal(merged, &cfg); err != nil {
+		t.Fatal(err)
+	}
+	var expected map[string]interface{}
+	if err := json.Unmarshal([]byte(`
+{
+  "cluster_checks": {
+    "cluster-check": {"cmd": ["cluster"], "timeout": "1s"},
+    "site-check": {"cmd": ["site"], "timeout": "5s"}
+  },
+  "node_checks": {
+    "checks": {
+      "check1": {"description": "First check", "cmd": ["check1"], "timeout": "10s"},
+      "check3": {"cmd": ["check3"], "timeout": "1s"},
+      "check4": {"cmd": ["check4"], "timeout": "1s"}
+    },
+    "prestart": ["check1", "check4"],
+    "poststart": ["check1"]
+  },
+  "check_env": {"PATH": "/opt/bin:/usr/bin", "LANG": "C"},
+  "host_root": "/host"
+}`), &expected); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(cfg, expected) {
+		t.Fatalf("expected %v, got %v", expected, cfg)
+	}
+
+	// A later file can enable a disabled check again, which keeps its place in the prestart and poststart lists.
+	enable := `{"node_checks": {"checks": {"check2": {"enabled": true}}}}`
+	merged, err = mergeConfigs([]configFile{
+		{"base", []byte(baseConfig)}, {"override", []byte(override)}, {"enable", []byte(enable)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(merged, &cfg); err != nil {
+		t.Fatal(err)
+	}
+	nodeChecks := cfg["node_checks"].(map[string]interface{})
+	if _, ok := nodeChecks["checks"].(map[string]interface{})["check2"]; !ok {
+		t.Fatalf("expected check2 to be enabled, got %v", nodeChecks["checks"])
+	}
+	if prestart := nodeChecks["prestart"]; !reflect.DeepEqual(prestart, []interface{}{"check1", "check2", "check4"}) {
+		t.Fatalf("unexpected prestart checks %v", prestart)
+	}
+
+	for _, invalid := range []string{
+		`[]`,
+		`{"cluster_checks": {"check": "cmd"}}`,
+		`{"node_checks": {"checks": {"check1": {"enabled": "no"}}}}`,
+	} {
+		if _, err := mergeConfigs([]configFile{{"base", []byte(baseConfig)}, {"invalid", []byte(invalid)}}); err == nil {
+			t.Fatalf("expected an error merging %s", invalid)
+		}
+	}
+}
+
+func TestLoadLayers(t *testing.T) {
+	dir, err := ioutil.TempDir("", "runner-layers-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	base := filepath.Join(dir, "checks.json")
+	if err := ioutil.WriteFile(base, []byte(baseConfig), 0600); err != nil {
+		t.Fatal(err)
+	}
+	confd := filepath.Join(dir, "checks.d")
+	if err := os.Mkdir(confd, 0700); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range map[string]string{
+		"20-disable.json":      `{"node_checks": {"checks": {"check3": {"enabled": false}}}}`,
+		"10-timeout.json":      `{"node_checks": {"checks": {"check3": {"timeout": "10s"}, "check1": {"timeout": "10s"}}}}`,
+		".20-disable.json.swp": `invalid`,
+		"README":               `invalid`,
+	} {
+		if err := ioutil.WriteFile(filepath.Join(confd, name), []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	files, err := ConfigFiles([]string{base}, confd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{base, filepath.Join(confd, "10-timeout.json"), filepath.Join(confd, "20-disable.json")}
+	if !reflect.DeepEqual(files, expected) {
+		t.Fatalf("expected %v, got %v", expected, files)
+	}
+
+	// A nonexistent directory is ignored.
+	if files, err := ConfigFiles([]string{base}, filepath.Join(dir, "nonexistent")); err != nil || len(files) != 1 {
+		t.Fatalf("unexpected files %v, error %v", files, err)
+	}
+
+	r, err := NewRunner("agent")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.LoadFromFiles(files...); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := r.NodeChecks.Checks["check3"]; ok {
+		t.Fatal("expected check3 to be disabled")
+	}
+	if timeout := r.NodeChecks.Checks["check1"].Timeout; timeout != "10s" {
+		t.Fatalf("expected the timeout of check1 to be overridden, got %s", timeout)
+	}
+	if !reflect.DeepEqual(r.NodeChecks.PostStart, []string{"check1", "check2"}) {
+		t.Fatalf("unexpected poststart checks %v", r.NodeChecks.PostStart)
+	}
+	if !strings.Contains(string(r.Config()), `"timeout": "10s"`) {
+		t.Fatalf("expected the effective config, got %s", r.Config())
+	}
+
+	// Reloading picks up changed files.
+	if err := ioutil.WriteFile(filepath.Join(confd, "20-disable.json"), []byte(`{}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	hash := r.ConfigHash()
+	if err := r.ReloadFromFiles(files...); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := r.Checks(SuiteNodePostStart)["check3"]; !ok {
+		t.Fatal("expected check3 to be enabled after reloading")
+	}
+	if r.ConfigHash() == hash {
+		t.Fatal("expected the config hash to change")
+	}
+}
@@ -0,0 +1,16 @@
+package runner
+
+// Observer is notified of the results of executed checks, e.g. to record them. ObserveChecks is called synchronously
+// after each run of a suite, before the response is returned, so it should not block for long. It is not called for
+// listed checks.
+type Observer interface {
+	ObserveChecks(suite string, rs *CombinedResponse)
+}
+
+// ObserverFunc is an adapter to allow the use of ordinary functions as an Observer.
+type ObserverFunc func(suite string, rs *CombinedResponse)
+
+// ObserveChecks calls f(suite, rs).
+func (f ObserverFunc) ObserveChecks(suite string, rs *CombinedResponse) {
+	f(suite, rs)
+}
@@ -0,0 +1,68 @@
+package runner
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestObservers(t *testing.T) {
+	r, err := NewRunner("master")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := `
+{
+  "cluster_checks": {
+    "cluster_check_1": {
+      "description": "Cluster check 1",
+      "cmd": ["echo", "cluster_check_1"],
+      "timeout": "1s"
+    }
+  },
+  "node_checks": {
+    "checks": {
+      "node_check_1": {
+        "description": "Node check 1",
+        "cmd": ["echo", "node_check_1"],
+        "timeout": "1s"
+      }
+    },
+    "prestart": ["node_check_1"],
+    "poststart": ["node_check_1"]
+  }
+}`
+	if err := r.Load(strings.NewReader(cfg)); err != nil {
+		t.Fatal(err)
+	}
+
+	var suites []string
+	r.Observers = []Observer{ObserverFunc(func(suite string, rs *CombinedResponse) {
+		suites = append(suites, suite)
+		for _, check := range rs.Checks() {
+			if check.Start().IsZero() {
+				t.Errorf("expected start time of check %s", check.Name())
+			}
+		}
+	})}
+
+	if _, err := r.Cluster(context.TODO(), false); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.PreStart(context.TODO(), false); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.PostStart(context.TODO(), false); err != nil {
+		t.Fatal(err)
+	}
+	// Listing checks doesn't notify observers.
+	if _, err := r.PostStart(context.TODO(), true); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{SuiteCluster, SuiteNodePreStart, SuiteNodePostStart}
+	if strings.Join(suites, ",") != strings.Join(expected, ",") {
+		t.Fatalf("expected observed suites %v, got %v", expected, suites)
+	}
+}
@@ -0,0 +1,77 @@
+//go:build !windows
+// +build !windows
+
+package runner
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestRunCommandKillsProcessGroup(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	// The background sleep inherits the output pipe, so the command only returns once its child was killed as well.
+	var output bytes.Buffer
+	start := time.Now()
+	_, err := runCommand(ctx, exec.Command("sh", "-c", "sleep 30 & wait"), &output, &output)
+	if err != context.DeadlineExceeded {
+		t.Fatalf("expected %v, got %v", context.DeadlineExceeded, err)
+	}
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Fatalf("expected the command and its child to be killed, returned after %s", elapsed)
+	}
+}
+
+func TestCanceledRunWaitsForChecks(t *testing.T) {
+	dir, err := ioutil.TempDir("", "runner-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	pidFile := filepath.Join(dir, "pid")
+
+	r, err := NewRunner("master")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := fmt.Sprintf(`{"cluster_checks": {"slow": {"cmd": ["sh", "-c", "echo $$ > %s; exec sleep 30"], "timeout": "60s"}}}`, pidFile)
+	if err := r.Load(strings.NewReader(cfg)); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	pids := make(chan int, 1)
+	go func() {
+		for {
+			if data, err := ioutil.ReadFile(pidFile); err == nil && bytes.HasSuffix(data, []byte("\n")) {
+				pid, _ := strconv.Atoi(strings.TrimSpace(string(data)))
+				pids <- pid
+				cancel()
+				return
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}()
+
+	if _, err := r.Cluster(ctx, false); err != context.Canceled {
+		t.Fatalf("expected %v, got %v", context.Canceled, err)
+	}
+	// The check's process must have exited and been reaped when the run returns.
+	pid := <-pids
+	if err := syscall.Kill(pid, 0); err != syscall.ESRCH {
+		t.Fatalf("expected process %d to have exited, got %v", pid, err)
+	}
+}
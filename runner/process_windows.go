@@ -0,0 +1,14 @@
+package runner
+
+import (
+	"os"
+	"os/exec"
+)
+
+// setProcessGroup does nothing on Windows, where child processes are not killed with the command.
+func setProcessGroup(c *exec.Cmd) {}
+
+// killProcessGroup kills p.
+func killProcessGroup(p *os.Process) error {
+	return p.Kill()
+}
@@ -0,0 +1,116 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// CheckProvider is implemented by checks which run in-process. Programs embedding the runner register providers with
+// RegisterCheckProvider and reference them by name from the "provider" field of a check definition.
+type CheckProvider interface {
+	// RunCheck executes the check and returns its output and status. Status follows the exec check convention:
+	// 0 (OK), 1 (WARNING), 2 (CRITICAL) or 3 (UNKNOWN). ctx is canceled when the check times out or the run is
+	// canceled. A non-nil error means the check could not be executed.
+	RunCheck(ctx context.Context) (output []byte, status int, err error)
+}
+
+// CheckProviderFunc is an adapter to allow the use of ordinary functions as a CheckProvider.
+type CheckProviderFunc func(ctx context.Context) ([]byte, int, error)
+
+// RunCheck calls f(ctx).
+func (f CheckProviderFunc) RunCheck(ctx context.Context) ([]byte, int, error) {
+	return f(ctx)
+}
+
+var (
+	providersMu sync.RWMutex
+	providers   = make(map[string]CheckProvider)
+)
+
+// RegisterCheckProvider makes a CheckProvider available under the given name. Providers must be registered before
+// the check config referencing them is loaded, usually from an init function. If RegisterCheckProvider is called twice
+// with the same name or if provider is nil, it panics.
+func RegisterCheckProvider(name string, provider CheckProvider) {
+	providersMu.Lock()
+	defer providersMu.Unlock()
+
+	if provider == nil {
+		panic("runner: RegisterCheckProvider provider is nil")
+	}
+	if _, dup := providers[name]; dup {
+		panic("runner: RegisterCheckProvider called twice for provider " + name)
+	}
+	providers[name] = provider
+}
+
+// CheckProviders returns a sorted list of the names of the registered check providers.
+func CheckProviders() []string {
+	providersMu.RLock()
+	defer providersMu.RUnlock()
+
+	var names []string
+	for name := range providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// unregisterCheckProvider removes a provider from the registry. It is used by tests.
+func unregisterCheckProvider(name string) {
+	providersMu.Lock()
+	defer providersMu.Unlock()
+	delete(providers, name)
+}
+
+func lookupCheckProvider(name string) (CheckProvider, error) {
+	providersMu.RLock()
+	defer providersMu.RUnlock()
+
+	provider, ok := providers[name]
+	if !ok {
+		return nil, errors.Errorf("check provider %q is not registered", name)
+	}
+	return provider, nil
+}
+
+// callWithTimeout calls f with ctx, which must be bounded by timeout. If f doesn't return before the timeout, the
+// check is reported with statusUnknown without waiting for f any longer. Panics in f are reported as errors so that a
+// faulty in-process check cannot take down the runner.
+func callWithTimeout(ctx context.Context, name string, timeout time.Duration, f func(context.Context) ([]byte, int, error)) ([]byte, int, error) {
+	type result struct {
+		output []byte
+		status int
+		err    error
+	}
+
+	done := make(chan result, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- result{nil, -1, errors.Errorf("%s check panicked: %v", name, r)}
+			}
+		}()
+
+		output, status, err := f(ctx)
+		done <- result{output, status, err}
+	}()
+
+	select {
+	case res := <-done:
+		if ctx.Err() == context.DeadlineExceeded {
+			return []byte(fmt.Sprintf("%s check exceeded timeout %s", name, timeout)), statusUnknown, nil
+		}
+		return res.output, res.status, res.err
+	case <-ctx.Done():
+		if ctx.Err() == context.DeadlineExceeded {
+			return []byte(fmt.Sprintf("%s check exceeded timeout %s", name, timeout)), statusUnknown, nil
+		}
+		return nil, -1, ctx.Err()
+	}
+}
@@ -0,0 +1,174 @@
+package runner
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCheckProvider(t *testing.T) {
+	RegisterCheckProvider("test-ok", CheckProviderFunc(func(ctx context.Context) ([]byte, int, error) {
+		return []byte("in-process check\n"), statusOK, nil
+	}))
+	defer unregisterCheckProvider("test-ok")
+	RegisterCheckProvider("test-critical", CheckProviderFunc(func(ctx context.Context) ([]byte, int, error) {
+		return []byte("something is wrong\n"), statusCritical, nil
+	}))
+	defer unregisterCheckProvider("test-critical")
+	RegisterCheckProvider("test-blocking", CheckProviderFunc(func(ctx context.Context) ([]byte, int, error) {
+		// Ignore ctx to assert that the runner enforces the timeout by itself.
+		time.Sleep(5 * time.Second)
+		return nil, statusOK, nil
+	}))
+	defer unregisterCheckProvider("test-blocking")
+	RegisterCheckProvider("test-panic", CheckProviderFunc(func(ctx context.Context) ([]byte, int, error) {
+		panic("oops")
+	}))
+	defer unregisterCheckProvider("test-panic")
+
+	r, err := NewRunner("master")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := `
+{
+  "cluster_checks": {
+    "cluster_check_1": {
+      "description": "Cluster check 1",
+      "provider": "test-ok",
+      "timeout": "1s"
+    }
+  },
+  "node_checks": {
+    "checks": {
+      "node_check_1": {
+        "description": "Node check 1",
+        "cmd": ["echo", "node_check_1"],
+        "timeout": "1s"
+      },
+      "node_check_2": {
+        "description": "Node check 2",
+        "provider": "test-critical",
+        "timeout": "1s"
+      },
+      "node_check_3": {
+        "description": "Node check 3",
+        "provider": "test-blocking",
+        "timeout": "100ms"
+      },
+      "node_check_4": {
+        "description": "Node check 4",
+        "provider": "test-panic",
+        "timeout": "1s"
+      }
+    },
+    "prestart": ["node_check_1", "node_check_2", "node_check_3"],
+    "poststart": ["node_check_4"]
+  }
+}`
+	if err := r.Load(strings.NewReader(cfg)); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := r.Cluster(context.TODO(), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := validateCheck("cluster_check_1", statusOK, "in-process check\n", out.checks); err != nil {
+		t.Fatal(err)
+	}
+
+	// Provider checks are combined with exec checks.
+	start := time.Now()
+	out, err = r.PreStart(context.TODO(), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if time.Since(start) > time.Second {
+		t.Fatalf("expected the blocking provider to time out after 100ms, took %s", time.Since(start))
+	}
+	if out.Status() != statusUnknown {
+		t.Fatalf("expected status %d, got %d", statusUnknown, out.Status())
+	}
+	if len(out.checks) != 3 {
+		t.Fatalf("expected 3 checks, got %d", len(out.checks))
+	}
+	if check := out.checks["node_check_2"]; check.status != statusCritical || check.output != "something is wrong\n" {
+		t.Fatalf("unexpected result for node_check_2: status %d, output %q", check.status, check.output)
+	}
+	if check := out.checks["node_check_3"]; check.status != statusUnknown || check.output != "test-blocking check exceeded timeout 100ms" {
+		t.Fatalf("unexpected result for node_check_3: status %d, output %q", check.status, check.output)
+	}
+
+	// A panicking provider is reported as an execution error.
+	out, err = r.PostStart(context.TODO(), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(out.errs) != 1 {
+		t.Fatalf("expected 1 error, got %d", len(out.errs))
+	}
+
+	// Listing shows the provider name.
+	out, err = r.PreStart(context.TODO(), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if provider := out.checks["node_check_2"].provider; provider != "test-critical" {
+		t.Fatalf("expected provider test-critical, got %q", provider)
+	}
+}
+
+func TestCheckProviderCanceled(t *testing.T) {
+	RegisterCheckProvider("test-wait", CheckProviderFunc(func(ctx context.Context) ([]byte, int, error) {
+		<-ctx.Done()
+		return nil, statusOK, nil
+	}))
+	defer unregisterCheckProvider("test-wait")
+
+	check := &Check{Provider: "test-wait", Timeout: "5s"}
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+
+	if _, _, err := check.Run(ctx, "master"); err != context.Canceled {
+		t.Fatalf("expected error %s, got %v", context.Canceled, err)
+	}
+}
+
+func TestCheckProviderConfig(t *testing.T) {
+	for _, check := range []string{
+		`{"provider": "nonexistent"}`,
+		`{"provider": "test-config", "type": "sysctl", "params": {"key": "vm.swappiness"}}`,
+	} {
+		RegisterCheckProvider("test-config", CheckProviderFunc(func(ctx context.Context) ([]byte, int, error) {
+			return nil, statusOK, nil
+		}))
+
+		r, err := NewRunner("master")
+		if err != nil {
+			t.Fatal(err)
+		}
+		cfg := `{"cluster_checks": {"check1": ` + check + `}}`
+		err = r.Load(strings.NewReader(cfg))
+		unregisterCheckProvider("test-config")
+		if err == nil {
+			t.Fatalf("expected an error loading check %s", check)
+		}
+	}
+
+	// Registering a provider twice panics.
+	RegisterCheckProvider("test-dup", CheckProviderFunc(func(ctx context.Context) ([]byte, int, error) {
+		return nil, statusOK, nil
+	}))
+	defer unregisterCheckProvider("test-dup")
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected RegisterCheckProvider to panic")
+		}
+	}()
+	RegisterCheckProvider("test-dup", CheckProviderFunc(func(ctx context.Context) ([]byte, int, error) {
+		return nil, statusOK, nil
+	}))
+}
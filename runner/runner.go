@@ -1,20 +1,38 @@
 package runner
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
-	"os"
+	"io/ioutil"
+	"sort"
+	"sync"
 	"time"
 
 	"github.com/dcos/dcos-go/dcos"
 	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
 )
 
+// Check suites run by a Runner.
 const (
-	statusOK      = 0
-	statusUnknown = 3
+	SuiteCluster       = "cluster"
+	SuiteNodePreStart  = "node-prestart"
+	SuiteNodePostStart = "node-poststart"
+)
+
+// hungGrace is how long an execution of a check may exceed its timeout before the runner is considered unhealthy.
+const hungGrace = time.Minute
+
+const (
+	statusOK       = 0
+	statusWarning  = 1
+	statusCritical = 2
+	statusUnknown  = 3
 )
 
 // NewRunner returns an initialized instance of *Runner. It returns an error if the role is not master or agent.
@@ -24,13 +42,14 @@ func NewRunner(role string) (*Runner, error) {
 	default:
 		return nil, errors.New(fmt.Sprintf("Runner role must be one of \"%s\" or \"%s\". Got \"%s\"", dcos.RoleMaster, dcos.RoleAgent, role))
 	}
-	return &Runner{role: role}, nil
+	return &Runner{role: role, Executor: LocalExecutor{}}, nil
 }
 
 // Response provides a command Response.
 type Response struct {
 	name     string
-	duration string
+	start    time.Time
+	duration time.Duration
 	list     bool
 
 	output      string
@@ -38,17 +57,63 @@ type Response struct {
 	description string
 	cmd         []string
 	timeout     string
+	checkType   string
+	provider    string
+	state       *CheckState
+}
+
+// Name returns the name of the check.
+func (r Response) Name() string {
+	return r.name
+}
+
+// Output returns the combined output of the check.
+func (r Response) Output() string {
+	return r.output
+}
+
+// Status returns the status of the check.
+func (r Response) Status() int {
+	return r.status
+}
+
+// Start returns the time at which the check was started. It is zero if the check was only listed.
+func (r Response) Start() time.Time {
+	return r.start
+}
+
+// Duration returns how long the check took to run. It is zero if the check was only listed.
+func (r Response) Duration() time.Duration {
+	return r.duration
+}
+
+// Description returns the description of the check.
+func (r Response) Description() string {
+	return r.description
+}
+
+// Timeout returns the configured timeout of the check.
+func (r Response) Timeout() string {
+	return r.timeout
+}
+
+// State returns the state of the check across runs, or nil if states are not tracked.
+func (r Response) State() *CheckState {
+	return r.state
 }
 
 type response struct {
-	Output string `json:"output"`
-	Status int    `json:"status"`
+	Output string      `json:"output"`
+	Status int         `json:"status"`
+	State  *CheckState `json:"state,omitempty"`
 }
 
 type responseList struct {
 	Description string   `json:"description"`
 	Cmd         []string `json:"cmd"`
 	Timeout     string   `json:"timeout"`
+	Type        string   `json:"type,omitempty"`
+	Provider    string   `json:"provider,omitempty"`
 }
 
 type responseCheck struct {
@@ -67,12 +132,15 @@ func (r Response) MarshalJSON() ([]byte, error) {
 			Description: r.description,
 			Cmd:         r.cmd,
 			Timeout:     r.timeout,
+			Type:        r.checkType,
+			Provider:    r.provider,
 		})
 	}
 
 	return json.Marshal(&response{
 		Output: r.output,
 		Status: r.status,
+		State:  r.state,
 	})
 }
 
@@ -80,7 +148,8 @@ func (r Response) MarshalJSON() ([]byte, error) {
 func NewCombinedResponse(list bool) *CombinedResponse {
 	return &CombinedResponse{
 		checks: make(map[string]*Response),
-		errs:   make(map[string]*Response),
+		errs:   make(map[string]error),
+		failed: make(map[string]*Response),
 		list:   list,
 	}
 }
@@ -91,7 +160,11 @@ type CombinedResponse struct {
 	list          bool
 	checkNotFound bool
 	checks        map[string]*Response
-	errs          map[string]*Response
+	errs          map[string]error
+
+	// failed holds the responses of the checks in errs which were found but failed to execute. Their status is
+	// UNKNOWN and their output is the error message.
+	failed map[string]*Response
 }
 
 // Status returns checks combined status.
@@ -99,6 +172,45 @@ func (cr CombinedResponse) Status() int {
 	return cr.status
 }
 
+// List returns true if the checks were only listed, not executed.
+func (cr CombinedResponse) List() bool {
+	return cr.list
+}
+
+// Checks returns the responses of the checks which were executed or listed, sorted by name.
+func (cr CombinedResponse) Checks() []*Response {
+	checks := make([]*Response, 0, len(cr.checks))
+	for _, r := range cr.checks {
+		checks = append(checks, r)
+	}
+	sort.Slice(checks, func(i, j int) bool {
+		return checks[i].name < checks[j].name
+	})
+	return checks
+}
+
+// Errors maps the names of checks which could not be found or executed to the error message.
+func (cr CombinedResponse) Errors() map[string]string {
+	errs := make(map[string]string, len(cr.errs))
+	for name, err := range cr.errs {
+		errs[name] = err.Error()
+	}
+	return errs
+}
+
+// Failed returns the responses of the checks which were found but failed to execute, sorted by name. Their status is
+// UNKNOWN, their start is the start of the run and their output is the error message.
+func (cr CombinedResponse) Failed() []*Response {
+	failed := make([]*Response, 0, len(cr.failed))
+	for _, r := range cr.failed {
+		failed = append(failed, r)
+	}
+	sort.Slice(failed, func(i, j int) bool {
+		return failed[i].name < failed[j].name
+	})
+	return failed
+}
+
 // MarshalJSON is a custom json marshaller implementation used to return the appropriate response based
 // on user input. combinedResponseError is used to return back error message if runner was unable to execute a check.
 // CombinedResponse.Checks is used to return back a list of checks without executing them, combinedResponseSuccess is
@@ -106,9 +218,10 @@ func (cr CombinedResponse) Status() int {
 func (cr CombinedResponse) MarshalJSON() ([]byte, error) {
 	if len(cr.errs) > 0 {
 		var errs []string
-		for e, r := range cr.errs {
-			errs = append(errs, fmt.Sprintf("%s: %s", r.name, e))
+		for name, err := range cr.errs {
+			errs = append(errs, fmt.Sprintf("%s: %s", name, err))
 		}
+		sort.Strings(errs)
 
 		if cr.checkNotFound {
 			return json.Marshal(combinedResponseError{
@@ -117,9 +230,21 @@ func (cr CombinedResponse) MarshalJSON() ([]byte, error) {
 			})
 		}
 
+		var states map[string]*CheckState
+		for name, resp := range cr.failed {
+			if resp.state == nil {
+				continue
+			}
+			if states == nil {
+				states = make(map[string]*CheckState)
+			}
+			states[name] = resp.state
+		}
+
 		return json.Marshal(combinedResponseError{
 			Error:  "One or more requested checks failed to execute.",
 			Checks: errs,
+			States: states,
 		})
 	}
 
@@ -139,8 +264,9 @@ type combinedResponseSuccess struct {
 }
 
 type combinedResponseError struct {
-	Error  string   `json:"error"`
-	Checks []string `json:"checks"`
+	Error  string                 `json:"error"`
+	Checks []string               `json:"checks"`
+	States map[string]*CheckState `json:"states,omitempty"`
 }
 
 // Runner is a main instance of DC/OS check runner.
@@ -151,35 +277,269 @@ type Runner struct {
 		PreStart  []string          `json:"prestart"`
 		PostStart []string          `json:"poststart"`
 	} `json:"node_checks"`
+
+	// CheckEnv is set in the environment of the commands of exec checks run by the default LocalExecutor.
 	CheckEnv map[string]string `json:"check_env"`
 
-	role string
+	// HostRoot is the path under which built-in checks read /proc and /sys. Defaults to "/".
+	HostRoot string `json:"host_root"`
+
+	// Executor runs the commands of exec checks. If nil, commands are run as local processes.
+	Executor Executor `json:"-"`
+
+	// States tracks the state of checks across runs. If nil, states are not tracked.
+	States *StateTracker `json:"-"`
+
+	// Observers are notified of the results of every check run.
+	Observers []Observer `json:"-"`
+
+	role    string
+	flights flightGroup
+	tracker tracker
+
+	// mu guards the configuration, which is replaced by Reload.
+	mu           sync.RWMutex
+	config       []byte
+	configLoaded time.Time
+	configHash   string
 }
 
-// Load loads values to Runner struct from io.Reader
-func (r *Runner) Load(reader io.Reader) error {
-	if err := json.NewDecoder(reader).Decode(r); err != nil {
+// Load loads values to Runner struct from the config files read from readers, which are merged in order. Later files
+// add checks, override the fields of checks loaded before, and disable checks with "enabled": false.
+func (r *Runner) Load(readers ...io.Reader) error {
+	var files []configFile
+	for i, reader := range readers {
+		data, err := ioutil.ReadAll(reader)
+		if err != nil {
+			return errors.Wrap(err, "unable to read a config file")
+		}
+		files = append(files, configFile{fmt.Sprintf("#%d", i+1), data})
+	}
+	return r.load(files)
+}
+
+// load merges files and loads the merged configuration.
+func (r *Runner) load(files []configFile) error {
+	if len(files) == 0 {
+		return errors.New("no config file given")
+	}
+	data, err := mergeConfigs(files)
+	if err != nil {
+		return err
+	}
+	// The hash identifies the loaded files, so that the hash of a single file is the file's SHA-256 hash.
+	hash := sha256.New()
+	for _, f := range files {
+		hash.Write(f.data)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := json.NewDecoder(bytes.NewReader(data)).Decode(r); err != nil {
 		return errors.Wrap(err, "unable to decode a config file")
 	}
-	return r.validate()
+	if err := r.validate(); err != nil {
+		return err
+	}
+
+	r.config = data
+	r.configHash = hex.EncodeToString(hash.Sum(nil))
+	r.configLoaded = time.Now()
+	return nil
 }
 
 // LoadFromFile opens a config file and try to load the values to Runner struct.
 func (r *Runner) LoadFromFile(path string) error {
-	f, err := os.Open(path)
+	return r.LoadFromFiles(path)
+}
+
+// LoadFromFiles loads the config files at paths, which are merged in order like by Load.
+func (r *Runner) LoadFromFiles(paths ...string) error {
+	files, err := readConfigFiles(paths)
+	if err != nil {
+		return err
+	}
+	return r.load(files)
+}
+
+// readConfigFiles reads the config files at paths.
+func readConfigFiles(paths []string) ([]configFile, error) {
+	var files []configFile
+	for _, path := range paths {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, errors.Wrap(err, "unable to open config file")
+		}
+		files = append(files, configFile{path, data})
+	}
+	return files, nil
+}
+
+// Reload replaces the configuration of r with the one read from readers like Load while checks may be running. Runs
+// in progress finish with the checks they started with. If the new configuration is invalid, r keeps its
+// configuration.
+func (r *Runner) Reload(readers ...io.Reader) error {
+	next := &Runner{role: r.role}
+	if err := next.Load(readers...); err != nil {
+		return err
+	}
+	r.replaceConfig(next)
+	return nil
+}
+
+// ReloadFromFiles reloads the configuration of r from the config files at paths like Reload.
+func (r *Runner) ReloadFromFiles(paths ...string) error {
+	files, err := readConfigFiles(paths)
 	if err != nil {
-		return errors.Wrap(err, "unable to open config file")
+		return err
 	}
-	return r.Load(f)
+	next := &Runner{role: r.role}
+	if err := next.load(files); err != nil {
+		return err
+	}
+	r.replaceConfig(next)
+	return nil
+}
+
+// replaceConfig replaces the configuration of r with the one loaded by next.
+func (r *Runner) replaceConfig(next *Runner) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.ClusterChecks = next.ClusterChecks
+	r.NodeChecks = next.NodeChecks
+	r.CheckEnv = next.CheckEnv
+	r.HostRoot = next.HostRoot
+	r.config = next.config
+	r.configLoaded = next.configLoaded
+	r.configHash = next.configHash
 }
 
 func (r *Runner) validate() error {
+	for name, check := range r.ClusterChecks {
+		if err := check.init(r.HostRoot); err != nil {
+			return errors.Wrapf(err, "invalid cluster check %s", name)
+		}
+	}
+	for name, check := range r.NodeChecks.Checks {
+		if err := check.init(r.HostRoot); err != nil {
+			return errors.Wrapf(err, "invalid node check %s", name)
+		}
+	}
 	return nil
 }
 
+// Role returns the DC/OS role of the node the runner checks.
+func (r *Runner) Role() string {
+	return r.role
+}
+
+// ConfigLoaded returns the time the configuration was loaded, or the zero time if it wasn't loaded.
+func (r *Runner) ConfigLoaded() time.Time {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.configLoaded
+}
+
+// Config returns the effective configuration merged from the loaded config files as indented JSON, or nil if it wasn't
+// loaded.
+func (r *Runner) Config() []byte {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.config
+}
+
+// ConfigHash returns the hex encoded SHA-256 hash of the loaded configuration, or "" if it wasn't loaded.
+func (r *Runner) ConfigHash() string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.configHash
+}
+
+// Healthy returns an error if r is unable to run checks: if its configuration wasn't loaded, or if executions of checks
+// didn't return within hungGrace after their timeout, e.g. because their processes couldn't be killed.
+func (r *Runner) Healthy() error {
+	if r.ConfigHash() == "" {
+		return errors.New("check configuration is not loaded")
+	}
+	if n := r.tracker.overdue(hungGrace); n > 0 {
+		return errors.Errorf("%d check executions didn't return after their timeout", n)
+	}
+	return nil
+}
+
+// Checks returns the checks which can be selected in runs of suite: the cluster checks for SuiteCluster, and the node
+// checks listed in prestart or poststart for the node suites. It returns nil for an invalid suite.
+func (r *Runner) Checks(suite string) map[string]*Check {
+	checks, _, _ := r.suiteChecks(suite)
+	return checks
+}
+
+// suiteChecks returns the checks which can be selected in runs of suite and the names of the checks it runs by default.
+func (r *Runner) suiteChecks(suite string) (map[string]*Check, []string, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	switch suite {
+	case SuiteCluster:
+		return r.ClusterChecks, r.clusterCheckNames(), nil
+	case SuiteNodePreStart:
+		return selectChecks(r.NodeChecks.Checks, r.NodeChecks.PreStart), r.NodeChecks.PreStart, nil
+	case SuiteNodePostStart:
+		return selectChecks(r.NodeChecks.Checks, r.NodeChecks.PostStart), r.NodeChecks.PostStart, nil
+	}
+	return nil, nil, errors.Errorf("invalid check suite %s", suite)
+}
+
+// selectChecks returns the checks of checks which are named in names.
+func selectChecks(checks map[string]*Check, names []string) map[string]*Check {
+	selected := make(map[string]*Check, len(names))
+	for _, name := range names {
+		if c, ok := checks[name]; ok {
+			selected[name] = c
+		}
+	}
+	return selected
+}
+
+// executor returns the Executor used to run exec checks. A LocalExecutor without Env runs commands with the current
+// CheckEnv, so that variables removed by a reload are no longer set.
+func (r *Runner) executor() Executor {
+	e := r.Executor
+	if e == nil {
+		e = LocalExecutor{}
+	}
+	if local, ok := e.(LocalExecutor); ok && local.Env == nil {
+		r.mu.RLock()
+		local.Env = r.CheckEnv
+		r.mu.RUnlock()
+		return local
+	}
+	return e
+}
+
+// execute runs check c. Unless c.Fresh is set, an execution of c which is already in progress for a concurrent run is
+// shared instead of executing c again.
+func (r *Runner) execute(ctx context.Context, c *Check, output func(line string)) ([]byte, int, error) {
+	executor := r.executor()
+	run := func(ctx context.Context, output func(line string)) ([]byte, int, error) {
+		timeout, _ := c.timeout()
+		done, err := r.startExecution(timeout)
+		if err != nil {
+			return nil, -1, err
+		}
+		defer done()
+		return c.run(ctx, r.role, executor, output)
+	}
+	if c.Fresh {
+		return run(ctx, output)
+	}
+	return r.flights.do(ctx, c, output, run)
+}
+
 // Cluster executes cluster runner defined in config.
 func (r *Runner) Cluster(ctx context.Context, list bool, selectiveChecks ...string) (*CombinedResponse, error) {
-	return r.run(ctx, r.ClusterChecks, list, r.clusterCheckNames(), selectiveChecks...)
+	checks, names, _ := r.suiteChecks(SuiteCluster)
+	return r.run(ctx, SuiteCluster, checks, list, names, nil, selectiveChecks...)
 }
 
 func (r *Runner) clusterCheckNames() (clusterChecks []string) {
@@ -191,12 +551,14 @@ func (r *Runner) clusterCheckNames() (clusterChecks []string) {
 
 // PreStart executes the runner defined in config node_checks->prestart.
 func (r *Runner) PreStart(ctx context.Context, list bool, selectiveChecks ...string) (*CombinedResponse, error) {
-	return r.run(ctx, r.NodeChecks.Checks, list, r.NodeChecks.PreStart, selectiveChecks...)
+	checks, names, _ := r.suiteChecks(SuiteNodePreStart)
+	return r.run(ctx, SuiteNodePreStart, checks, list, names, nil, selectiveChecks...)
 }
 
 // PostStart executes the runner defined in config node_checks->poststart.
 func (r *Runner) PostStart(ctx context.Context, list bool, selectiveChecks ...string) (*CombinedResponse, error) {
-	return r.run(ctx, r.NodeChecks.Checks, list, r.NodeChecks.PostStart, selectiveChecks...)
+	checks, names, _ := r.suiteChecks(SuiteNodePostStart)
+	return r.run(ctx, SuiteNodePostStart, checks, list, names, nil, selectiveChecks...)
 }
 
 // dedupeStrings returns a slice containing the strings in s with duplicates omitted.
@@ -212,7 +574,10 @@ func dedupeStrings(s []string) []string {
 	return deduped
 }
 
-func (r *Runner) run(ctx context.Context, checkMap map[string]*Check, list bool, checkList []string, selectiveChecks ...string) (*CombinedResponse, error) {
+// run runs or lists the checks in checkList, or selectiveChecks if given. If stream is set, it receives the output and
+// results of the checks while they run.
+func (r *Runner) run(ctx context.Context, suite string, checkMap map[string]*Check, list bool, checkList []string,
+	stream StreamHandler, selectiveChecks ...string) (*CombinedResponse, error) {
 	max := func(a, b int) int {
 		// valid values are 0,1,2,3. All other values should result in 3.
 		if (a > statusUnknown || a < statusOK) || (b > statusUnknown || b < statusOK) {
@@ -225,6 +590,19 @@ func (r *Runner) run(ctx context.Context, checkMap map[string]*Check, list bool,
 		return b
 	}
 
+	// Listing checks doesn't execute them, so it is not stopped by Shutdown.
+	if !list {
+		var (
+			done func()
+			err  error
+		)
+		ctx, done, err = r.startRun(ctx)
+		if err != nil {
+			return nil, err
+		}
+		defer done()
+	}
+
 	// if no checks defined, return empty response.
 	combinedResponse := NewCombinedResponse(list)
 	if len(checkList) == 0 {
@@ -239,11 +617,21 @@ func (r *Runner) run(ctx context.Context, checkMap map[string]*Check, list bool,
 	checksToRun = dedupeStrings(checksToRun)
 
 	results := make(chan *responseCheck, len(checksToRun))
+	runStart := time.Now()
+
+	// The results are passed to stream by this goroutine, and the output of the checks only until run returns.
+	var guard *guardedStream
+	if stream != nil {
+		guard = &guardedStream{h: stream}
+		defer guard.close()
+	}
 
 	// main loop to get the checks info.
+	var wg sync.WaitGroup
 	for _, name := range dedupeStrings(checksToRun) {
-
+		wg.Add(1)
 		go func(name string) {
+			defer wg.Done()
 			currentCheck, ok := checkMap[name]
 			if !ok {
 				results <- &responseCheck{name, errors.New("Check not found"), true, &Response{name: name}}
@@ -265,22 +653,30 @@ func (r *Runner) run(ctx context.Context, checkMap map[string]*Check, list bool,
 				combinedOutput []byte
 				code           int
 				err            error
-				checkDuration  string
+				checkStart     time.Time
+				checkDuration  time.Duration
 			)
 
 			// list option disables the check execution
 			if !list {
-				start := time.Now()
-				combinedOutput, code, err = currentCheck.Run(ctx, r.role)
-				checkDuration = time.Since(start).String()
+				checkStart = time.Now()
+				var output func(string)
+				if guard != nil {
+					output = func(line string) { guard.CheckOutput(name, line) }
+				}
+				combinedOutput, code, err = r.execute(ctx, currentCheck, output)
+				checkDuration = time.Since(checkStart)
 			}
 
 			resp.output = string(combinedOutput)
 			resp.status = code
+			resp.start = checkStart
 			resp.duration = checkDuration
 			resp.description = currentCheck.Description
 			resp.cmd = currentCheck.Cmd
 			resp.timeout = currentCheck.Timeout
+			resp.checkType = currentCheck.Type
+			resp.provider = currentCheck.Provider
 			resp.list = list
 
 			results <- &responseCheck{name, err, false, resp}
@@ -293,11 +689,23 @@ func (r *Runner) run(ctx context.Context, checkMap map[string]*Check, list bool,
 			if result == nil {
 				// Check doesn't apply to our role.
 				continue
-			} else if result.err != nil {
+			}
+			if result.err != nil && !result.checkNotFound && !list {
+				// The check is UNKNOWN, so that its state tells it apart from a check which is still OK.
+				result.response.status = statusUnknown
+				result.response.output = result.err.Error()
+				result.response.start = runStart
+			}
+			if guard != nil {
+				guard.CheckResult(result.checkName, result.response, result.err)
+			}
+			if result.err != nil {
 				// Check failed to execute.
-				combinedResponse.errs[result.err.Error()] = result.response
+				combinedResponse.errs[result.checkName] = result.err
 				if result.checkNotFound {
 					combinedResponse.checkNotFound = true
+				} else if !list {
+					combinedResponse.failed[result.checkName] = result.response
 				}
 			} else {
 				// Check was executed.
@@ -305,9 +713,27 @@ func (r *Runner) run(ctx context.Context, checkMap map[string]*Check, list bool,
 				combinedResponse.status = max(combinedResponse.status, result.response.status)
 			}
 		case <-ctx.Done():
+			// The checks are canceled with ctx, wait for them to return, e.g. for their processes to be killed.
+			if guard != nil {
+				guard.close()
+			}
+			wg.Wait()
+			if r.tracker.aborted() {
+				return nil, ErrShutdown
+			}
 			return nil, ctx.Err()
 		}
 	}
 
+	if !list && r.States != nil {
+		if err := r.States.update(r.role, suite, combinedResponse); err != nil {
+			logrus.Errorf("Unable to save check states: %s", err)
+		}
+	}
+	if !list {
+		for _, o := range r.Observers {
+			o.ObserveChecks(suite, combinedResponse)
+		}
+	}
 	return combinedResponse, nil
 }
@@ -25,6 +25,123 @@ func TestConfigLoadConfig(t *testing.T) {
 	}
 }
 
+func TestConfigHash(t *testing.T) {
+	r := &Runner{}
+	if r.ConfigHash() != "" || !r.ConfigLoaded().IsZero() {
+		t.Fatal("expected no config hash and load time before loading the config")
+	}
+
+	before := time.Now()
+	if err := r.Load(strings.NewReader(`{"cluster_checks": {}}`)); err != nil {
+		t.Fatal(err)
+	}
+	// echo -n '{"cluster_checks": {}}' | sha256sum
+	if hash := r.ConfigHash(); hash != "35860be07404549fee4ae7026e387c66fee61693c3cf2c614eb1facea1be9d0e" {
+		t.Fatalf("unexpected config hash %s", hash)
+	}
+	if r.ConfigLoaded().Before(before) {
+		t.Fatalf("unexpected config load time %s", r.ConfigLoaded())
+	}
+}
+
+func TestReload(t *testing.T) {
+	r, executor := newGateRunner(t, false)
+	hash := r.ConfigHash()
+
+	result := make(chan *CombinedResponse)
+	go func() {
+		rs, err := r.Cluster(context.Background(), false)
+		if err != nil {
+			t.Error(err)
+		}
+		result <- rs
+	}()
+	executor.waitForExecutions(t, 1)
+
+	// An invalid configuration is not applied.
+	if err := r.Reload(strings.NewReader(`{"cluster_checks": {"check": {"type": "nonexistent"}}}`)); err == nil {
+		t.Fatal("expected an error reloading an invalid config")
+	}
+	if r.ConfigHash() != hash {
+		t.Fatal("expected the config to be kept after a failed reload")
+	}
+
+	cfg := `{"cluster_checks": {"new-check": {"cmd": ["new"], "timeout": "5s", "fresh": true}}}`
+	if err := r.Reload(strings.NewReader(cfg)); err != nil {
+		t.Fatal(err)
+	}
+	if r.ConfigHash() == hash {
+		t.Fatal("expected the config hash to change")
+	}
+
+	// The run in progress finishes with the previous checks.
+	close(executor.release)
+	if rs := <-result; rs.checks["locking-check"] == nil {
+		t.Fatalf("unexpected result %+v", rs.checks)
+	}
+
+	rs, err := r.Cluster(context.Background(), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rs.checks) != 1 || rs.checks["new-check"] == nil {
+		t.Fatalf("unexpected result %+v", rs.checks)
+	}
+	if _, ok := r.Checks(SuiteCluster)["locking-check"]; ok {
+		t.Fatal("expected the previous checks to be replaced")
+	}
+}
+
+func TestHealthy(t *testing.T) {
+	r, err := NewRunner("master")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Healthy(); err == nil {
+		t.Fatal("expected a runner without config to be unhealthy")
+	}
+
+	r, executor := newGateRunner(t, false)
+	if err := r.Healthy(); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	r.tracker.mu.Lock()
+	r.tracker.now = func() time.Time { return now }
+	r.tracker.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if _, err := r.Cluster(context.Background(), false); err != nil {
+			t.Error(err)
+		}
+	}()
+	executor.waitForExecutions(t, 1)
+
+	// The check's timeout is 5s.
+	r.tracker.mu.Lock()
+	r.tracker.now = func() time.Time { return now.Add(5*time.Second + hungGrace) }
+	r.tracker.mu.Unlock()
+	if err := r.Healthy(); err != nil {
+		t.Fatal(err)
+	}
+
+	r.tracker.mu.Lock()
+	r.tracker.now = func() time.Time { return now.Add(5*time.Second + hungGrace + time.Second) }
+	r.tracker.mu.Unlock()
+	if err := r.Healthy(); err == nil {
+		t.Fatal("expected a runner with a hung check to be unhealthy")
+	}
+
+	close(executor.release)
+	<-done
+	if err := r.Healthy(); err != nil {
+		t.Fatal(err)
+	}
+}
+
 func TestNewRunner(t *testing.T) {
 	// Assert that the only allowed roles are master and agent.
 	var (
@@ -372,6 +489,18 @@ func TestSelectedChecks(t *testing.T) {
 	if _, ok := out.checks[unexpectedCheckName]; ok {
 		t.Fatalf("found unexpected check %s", unexpectedCheckName)
 	}
+
+	// checks of another suite can't be selected
+	out, err = r.PreStart(context.TODO(), false, "node_check_1", "node_check_4", "cluster_check_1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(out.checks) != 1 || len(out.errs) != 2 || !out.checkNotFound {
+		t.Fatalf("expected node_check_4 and cluster_check_1 not to be found, got %+v", out)
+	}
+	if _, ok := r.Checks(SuiteNodePreStart)["node_check_4"]; ok {
+		t.Fatal("expected node_check_4 not to be a prestart check")
+	}
 }
 
 func validateCheckListing(cr *CombinedResponse, name, description, timeout string, cmd []string) error {
@@ -0,0 +1,179 @@
+package runner
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ErrShutdown is returned by runs which are started after Shutdown was called, or which were canceled because they
+// didn't finish before Shutdown's context was done.
+var ErrShutdown = errors.New("check runner is shutting down")
+
+// tracker counts the runs and check executions in progress, so that Shutdown can wait for them.
+type tracker struct {
+	mu       sync.Mutex
+	draining bool
+	active   int
+
+	// idle is closed when no run or execution is active anymore during Shutdown.
+	idle chan struct{}
+
+	// abort is closed when the remaining runs are canceled.
+	abort chan struct{}
+
+	// deadlines maps the IDs of the executions in progress to the time they should have returned by.
+	deadlines map[int]time.Time
+	nextID    int
+
+	// now returns the current time. Defaults to time.Now.
+	now func() time.Time
+}
+
+// init initializes t's channels and maps. t.mu must be held.
+func (t *tracker) init() {
+	if t.abort == nil {
+		t.abort = make(chan struct{})
+	}
+	if t.deadlines == nil {
+		t.deadlines = make(map[int]time.Time)
+	}
+	if t.now == nil {
+		t.now = time.Now
+	}
+}
+
+// aborted returns true if the remaining runs were canceled by Shutdown.
+func (t *tracker) aborted() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.init()
+
+	select {
+	case <-t.abort:
+		return true
+	default:
+		return false
+	}
+}
+
+// done marks a run or execution as finished.
+func (t *tracker) done() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.active--
+	if t.active == 0 && t.idle != nil {
+		close(t.idle)
+		t.idle = nil
+	}
+}
+
+// startRun registers a run and returns a context for it, which is canceled when the remaining runs are canceled by
+// Shutdown, and a function which must be called when the run finished. It returns ErrShutdown if Shutdown was called.
+func (r *Runner) startRun(ctx context.Context) (context.Context, func(), error) {
+	t := &r.tracker
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.init()
+
+	if t.draining {
+		return nil, nil, ErrShutdown
+	}
+	t.active++
+
+	ctx, cancel := context.WithCancel(ctx)
+	abort := t.abort
+	go func() {
+		select {
+		case <-abort:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, func() {
+		cancel()
+		t.done()
+	}, nil
+}
+
+// startExecution registers an execution of a check which should return within timeout, and returns a function which
+// must be called when it finished. Executions of runs which are in progress are started while Shutdown waits for them,
+// unless the remaining runs were canceled, in which case ErrShutdown is returned.
+func (r *Runner) startExecution(timeout time.Duration) (func(), error) {
+	t := &r.tracker
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.init()
+
+	select {
+	case <-t.abort:
+		return nil, ErrShutdown
+	default:
+	}
+	t.active++
+
+	id := t.nextID
+	t.nextID++
+	t.deadlines[id] = t.now().Add(timeout)
+	return func() {
+		t.mu.Lock()
+		delete(t.deadlines, id)
+		t.mu.Unlock()
+		t.done()
+	}, nil
+}
+
+// overdue returns the number of executions in progress which didn't return within grace after their deadline.
+func (t *tracker) overdue(grace time.Duration) int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.init()
+
+	n := 0
+	now := t.now()
+	for _, deadline := range t.deadlines {
+		if now.After(deadline.Add(grace)) {
+			n++
+		}
+	}
+	return n
+}
+
+// Shutdown stops the runner from starting new runs, which fail with ErrShutdown, and waits for the runs in progress
+// to finish. If ctx is done before, the remaining runs are canceled, which kills the processes of their checks, and
+// Shutdown returns ctx.Err() once their checks returned.
+func (r *Runner) Shutdown(ctx context.Context) error {
+	t := &r.tracker
+	t.mu.Lock()
+	t.init()
+	t.draining = true
+	if t.active == 0 {
+		t.mu.Unlock()
+		return nil
+	}
+	if t.idle == nil {
+		t.idle = make(chan struct{})
+	}
+	idle := t.idle
+	t.mu.Unlock()
+
+	select {
+	case <-idle:
+		return nil
+	case <-ctx.Done():
+	}
+
+	t.mu.Lock()
+	select {
+	case <-t.abort:
+	default:
+		close(t.abort)
+	}
+	t.mu.Unlock()
+
+	<-idle
+	return ctx.Err()
+}
@@ -0,0 +1,85 @@
+package runner
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestShutdownDrain(t *testing.T) {
+	r, executor := newGateRunner(t, false)
+
+	result := make(chan *CombinedResponse)
+	go func() {
+		rs, err := r.Cluster(context.Background(), false)
+		if err != nil {
+			t.Error(err)
+		}
+		result <- rs
+	}()
+	executor.waitForExecutions(t, 1)
+
+	shutdown := make(chan error)
+	go func() {
+		shutdown <- r.Shutdown(context.Background())
+	}()
+
+	// Shutdown waits for the running check.
+	select {
+	case err := <-shutdown:
+		t.Fatalf("expected Shutdown to wait for the running check, returned %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// New runs are refused while draining, listing checks still works.
+	if _, err := r.Cluster(context.Background(), false); err != ErrShutdown {
+		t.Fatalf("expected %v, got %v", ErrShutdown, err)
+	}
+	if _, err := r.Cluster(context.Background(), true); err != nil {
+		t.Fatal(err)
+	}
+
+	close(executor.release)
+	rs := <-result
+	if check := rs.checks["locking-check"]; check == nil || check.output != "started\nfinished\n" {
+		t.Fatalf("unexpected result %+v", check)
+	}
+	if err := <-shutdown; err != nil {
+		t.Fatal(err)
+	}
+	if n := executor.executions(); n != 1 {
+		t.Fatalf("expected 1 execution, got %d", n)
+	}
+}
+
+func TestShutdownIdle(t *testing.T) {
+	r, _ := newGateRunner(t, false)
+
+	if err := r.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Cluster(context.Background(), false); err != ErrShutdown {
+		t.Fatalf("expected %v, got %v", ErrShutdown, err)
+	}
+}
+
+func TestShutdownTimeout(t *testing.T) {
+	r, executor := newGateRunner(t, false)
+
+	canceled := make(chan error)
+	go func() {
+		_, err := r.Cluster(context.Background(), false)
+		canceled <- err
+	}()
+	executor.waitForExecutions(t, 1)
+
+	// The run is canceled when the drain times out.
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := r.Shutdown(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("expected %v, got %v", context.DeadlineExceeded, err)
+	}
+	if err := <-canceled; err != ErrShutdown {
+		t.Fatalf("expected %v, got %v", ErrShutdown, err)
+	}
+}
@@ -0,0 +1,165 @@
+package runner
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// CheckState describes how the status of a check developed over past runs.
+type CheckState struct {
+	// Status is the status of the latest run.
+	Status int `json:"status"`
+
+	// PreviousStatus is the status before the last transition. It is nil until the status changed once.
+	PreviousStatus *int `json:"previous_status,omitempty"`
+
+	// Since is the time of the last transition, or of the first run.
+	Since time.Time `json:"since"`
+
+	// FirstFailed is the time of the first run of the current streak of runs which were not OK. It is nil if the
+	// check is OK.
+	FirstFailed *time.Time `json:"first_failed,omitempty"`
+
+	// LastOK is the time of the latest OK run. It is nil if the check was never OK.
+	LastOK *time.Time `json:"last_ok,omitempty"`
+
+	// ConsecutiveFailures is the number of runs in a row which were not OK.
+	ConsecutiveFailures int `json:"consecutive_failures"`
+
+	lastRun time.Time
+}
+
+// Changed returns true if the latest run changed the status of the check. The first run of a check is not a change.
+func (s CheckState) Changed() bool {
+	return s.PreviousStatus != nil && s.Since.Equal(s.lastRun)
+}
+
+// StateTracker tracks the state of checks across runs, per role, suite and check, so that a check which is part of
+// several suites has a separate state in each of them. If a path is given, the states are persisted to that file after
+// every run and loaded from it on creation.
+type StateTracker struct {
+	path string
+
+	mu     sync.Mutex
+	states map[string]*CheckState
+}
+
+// NewStateTracker returns a *StateTracker persisting states to path. If path is empty, states are only kept in
+// memory.
+func NewStateTracker(path string) (*StateTracker, error) {
+	t := &StateTracker{path: path, states: make(map[string]*CheckState)}
+	if path == "" {
+		return t, nil
+	}
+
+	body, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return t, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to read state file")
+	}
+	if err := json.Unmarshal(body, &t.states); err != nil {
+		return nil, errors.Wrap(err, "unable to decode state file")
+	}
+	return t, nil
+}
+
+// State returns the state of a check of suite for role, and false if the check has not been run yet.
+func (t *StateTracker) State(role, suite, check string) (CheckState, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	s, ok := t.states[stateKey(role, suite, check)]
+	if !ok {
+		return CheckState{}, false
+	}
+	return *s, true
+}
+
+func stateKey(role, suite, check string) string {
+	return role + "/" + suite + "/" + check
+}
+
+// update updates the states of the checks of suite executed in rs and sets their state in the responses. Checks
+// which failed to execute are recorded as UNKNOWN.
+func (t *StateTracker) update(role, suite string, rs *CombinedResponse) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for name, resp := range rs.checks {
+		t.updateCheck(role, suite, name, resp)
+	}
+	for name, resp := range rs.failed {
+		t.updateCheck(role, suite, name, resp)
+	}
+
+	return t.save()
+}
+
+// updateCheck updates the state of a single check from its response and sets it in the response.
+func (t *StateTracker) updateCheck(role, suite, name string, resp *Response) {
+	key := stateKey(role, suite, name)
+	s, ok := t.states[key]
+	if !ok {
+		s = &CheckState{Status: resp.status, Since: resp.start}
+		t.states[key] = s
+	} else if s.Status != resp.status {
+		previous := s.Status
+		s.PreviousStatus = &previous
+		s.Status = resp.status
+		s.Since = resp.start
+	}
+
+	start := resp.start
+	if resp.status == statusOK {
+		s.LastOK = &start
+		s.FirstFailed = nil
+		s.ConsecutiveFailures = 0
+	} else {
+		if s.ConsecutiveFailures == 0 {
+			s.FirstFailed = &start
+		}
+		s.ConsecutiveFailures++
+	}
+
+	state := *s
+	state.lastRun = resp.start
+	resp.state = &state
+}
+
+// save writes the states to the state file, replacing it atomically.
+func (t *StateTracker) save() error {
+	if t.path == "" {
+		return nil
+	}
+
+	body, err := json.Marshal(t.states)
+	if err != nil {
+		return errors.Wrap(err, "unable to encode states")
+	}
+
+	f, err := ioutil.TempFile(filepath.Dir(t.path), "."+filepath.Base(t.path)+".tmp")
+	if err != nil {
+		return errors.Wrap(err, "unable to create temporary state file")
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := f.Write(body); err != nil {
+		f.Close()
+		return errors.Wrap(err, "unable to write temporary state file")
+	}
+	if err := f.Close(); err != nil {
+		return errors.Wrap(err, "unable to write temporary state file")
+	}
+	if err := os.Rename(f.Name(), t.path); err != nil {
+		return errors.Wrap(err, "unable to replace state file")
+	}
+	return nil
+}
@@ -0,0 +1,248 @@
+package runner
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+func TestStateTracker(t *testing.T) {
+	dir, err := ioutil.TempDir("", "state-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "states.json")
+
+	newRunner := func() *Runner {
+		r, err := NewRunner("agent")
+		if err != nil {
+			t.Fatal(err)
+		}
+		cfg := `{"node_checks": {"checks": {"check1": {"cmd": ["check1"], "timeout": "1s"}}, "poststart": ["check1"]}}`
+		if err := r.Load(strings.NewReader(cfg)); err != nil {
+			t.Fatal(err)
+		}
+		r.States, err = NewStateTracker(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return r
+	}
+
+	// The check's status is set by the command of the executor, so that it can change between runs.
+	var status int
+	r := newRunner()
+	r.Executor = ExecutorFunc(func(ctx context.Context, cmd []string) ([]byte, []byte, int, error) {
+		return nil, nil, status, nil
+	})
+
+	run := func(s int) *CheckState {
+		status = s
+		rs, err := r.PostStart(context.TODO(), false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return rs.checks["check1"].State()
+	}
+
+	first := run(statusOK)
+	if first.Status != statusOK || first.PreviousStatus != nil || first.Changed() || !first.LastOK.Equal(first.Since) || first.ConsecutiveFailures != 0 {
+		t.Fatalf("unexpected state after first run %+v", first)
+	}
+
+	failed := run(statusCritical)
+	if !failed.Changed() || *failed.PreviousStatus != statusOK || failed.ConsecutiveFailures != 1 ||
+		!failed.FirstFailed.Equal(failed.Since) || failed.LastOK != first.LastOK {
+		t.Fatalf("unexpected state after failure %+v", failed)
+	}
+
+	warning := run(statusWarning)
+	if !warning.Changed() || *warning.PreviousStatus != statusCritical || warning.ConsecutiveFailures != 2 ||
+		!warning.FirstFailed.Equal(*failed.FirstFailed) {
+		t.Fatalf("unexpected state after second failure %+v", warning)
+	}
+
+	still := run(statusWarning)
+	if still.Changed() || still.Since != warning.Since || still.ConsecutiveFailures != 3 {
+		t.Fatalf("unexpected state after unchanged run %+v", still)
+	}
+
+	// The state is included in the response.
+	status = statusWarning
+	rs, err := r.PostStart(context.TODO(), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := json.Marshal(rs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(body), `"consecutive_failures":4`) || !strings.Contains(string(body), `"previous_status":2`) {
+		t.Fatalf("expected state in response, got %s", body)
+	}
+
+	// States are loaded from the state file, and recovery resets the failures.
+	r = newRunner()
+	r.Executor = ExecutorFunc(func(ctx context.Context, cmd []string) ([]byte, []byte, int, error) {
+		return nil, nil, statusOK, nil
+	})
+	if s, ok := r.States.State("agent", SuiteNodePostStart, "check1"); !ok || s.ConsecutiveFailures != 4 {
+		t.Fatalf("expected persisted state, got %+v", s)
+	}
+	recovered := run(statusOK)
+	if !recovered.Changed() || recovered.ConsecutiveFailures != 0 || recovered.FirstFailed != nil || !recovered.LastOK.Equal(recovered.Since) {
+		t.Fatalf("unexpected state after recovery %+v", recovered)
+	}
+
+	// States are tracked per role.
+	if _, ok := r.States.State("master", SuiteNodePostStart, "check1"); ok {
+		t.Fatal("expected no state for master")
+	}
+}
+
+func TestStateFailedToExecute(t *testing.T) {
+	r, err := NewRunner("agent")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := `{"node_checks": {"checks": {"check1": {"cmd": ["check1"], "timeout": "1s"}}, "poststart": ["check1"]}}`
+	if err := r.Load(strings.NewReader(cfg)); err != nil {
+		t.Fatal(err)
+	}
+	r.States, err = NewStateTracker("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The check is OK, then its command can't be executed anymore.
+	var execErr error
+	r.Executor = ExecutorFunc(func(ctx context.Context, cmd []string) ([]byte, []byte, int, error) {
+		return nil, nil, statusOK, execErr
+	})
+	if _, err := r.PostStart(context.TODO(), false); err != nil {
+		t.Fatal(err)
+	}
+
+	execErr = errors.New("executable file not found")
+	for i := 1; i <= 2; i++ {
+		start := time.Now()
+		rs, err := r.PostStart(context.TODO(), false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		failed := rs.Failed()
+		if len(failed) != 1 || failed[0].Status() != statusUnknown || failed[0].Start().Before(start) ||
+			!strings.Contains(failed[0].Output(), "executable file not found") {
+			t.Fatalf("expected check1 to be UNKNOWN, got %+v", failed)
+		}
+		s := failed[0].State()
+		if s == nil || s.Status != statusUnknown || *s.PreviousStatus != statusOK || s.ConsecutiveFailures != i ||
+			s.Changed() != (i == 1) {
+			t.Fatalf("unexpected state after %d failures to execute %+v", i, s)
+		}
+		if tracked, _ := r.States.State("agent", SuiteNodePostStart, "check1"); tracked.ConsecutiveFailures != i {
+			t.Fatalf("unexpected tracked state %+v", tracked)
+		}
+
+		body, err := json.Marshal(rs)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(body), `"states":{"check1":{"status":3`) {
+			t.Fatalf("expected state in response, got %s", body)
+		}
+	}
+
+	// Checks which could not be found have no state.
+	if _, err := r.PostStart(context.TODO(), false, "missing"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := r.States.State("agent", SuiteNodePostStart, "missing"); ok {
+		t.Fatal("expected no state for a missing check")
+	}
+}
+
+func TestStatePerSuite(t *testing.T) {
+	r, err := NewRunner("agent")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := `
+{
+  "cluster_checks": {"check1": {"cmd": ["cluster"], "timeout": "1s"}},
+  "node_checks": {
+    "checks": {"check1": {"cmd": ["node"], "timeout": "1s"}},
+    "prestart": ["check1"],
+    "poststart": ["check1"]
+  }
+}`
+	if err := r.Load(strings.NewReader(cfg)); err != nil {
+		t.Fatal(err)
+	}
+	r.States, err = NewStateTracker("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The node check fails before the node is started, and the cluster check never does.
+	var status int
+	r.Executor = ExecutorFunc(func(ctx context.Context, cmd []string) ([]byte, []byte, int, error) {
+		if cmd[0] == "cluster" {
+			return nil, nil, statusOK, nil
+		}
+		return nil, nil, status, nil
+	})
+
+	status = statusCritical
+	for i := 0; i < 2; i++ {
+		if _, err := r.PreStart(context.TODO(), false); err != nil {
+			t.Fatal(err)
+		}
+	}
+	status = statusOK
+	if _, err := r.PostStart(context.TODO(), false); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Cluster(context.TODO(), false); err != nil {
+		t.Fatal(err)
+	}
+
+	for suite, expected := range map[string]int{SuiteNodePreStart: 2, SuiteNodePostStart: 0, SuiteCluster: 0} {
+		s, ok := r.States.State("agent", suite, "check1")
+		if !ok || s.ConsecutiveFailures != expected || s.PreviousStatus != nil {
+			t.Fatalf("expected %d consecutive failures of check1 in %s, got %+v", expected, suite, s)
+		}
+	}
+}
+
+func TestNoStateTracker(t *testing.T) {
+	r, err := NewRunner("agent")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Executor = &fakeExecutor{}
+	cfg := `{"node_checks": {"checks": {"check1": {"cmd": ["check1"], "timeout": "1s"}}, "poststart": ["check1"]}}`
+	if err := r.Load(strings.NewReader(cfg)); err != nil {
+		t.Fatal(err)
+	}
+
+	rs, err := r.PostStart(context.TODO(), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := json.Marshal(rs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(body), "state") {
+		t.Fatalf("expected no state in response, got %s", body)
+	}
+}
@@ -0,0 +1,132 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os/exec"
+	"sync"
+	"syscall"
+)
+
+// StreamHandler receives the output of checks while they run and their results as they finish. Its methods are not
+// called concurrently, and not anymore once the run returned.
+type StreamHandler interface {
+	// CheckOutput is called for each line of output of a check, without the line ending.
+	CheckOutput(check, line string)
+
+	// CheckResult is called when a check finished. err is set if the check could not be found or executed, in which
+	// case resp only carries the check's name.
+	CheckResult(check string, resp *Response, err error)
+}
+
+// StreamingExecutor is implemented by executors which can report the output of a command while it runs. Executors
+// which don't implement it report the output when the command exits.
+type StreamingExecutor interface {
+	// ExecuteStream runs cmd like Executor.Execute, writing its stdout and stderr to output as they are produced.
+	ExecuteStream(ctx context.Context, cmd []string, output io.Writer) (code int, err error)
+}
+
+// ExecuteStream runs cmd as a child process and copies its combined stdout and stderr to output until it exits.
+func (e LocalExecutor) ExecuteStream(ctx context.Context, cmd []string, output io.Writer) (int, error) {
+	return runCommand(ctx, localCommand(cmd, e.Env), output, output)
+}
+
+// exitCode returns the exit code of a command from the error it exited with. err is returned if the command did not
+// exit on its own.
+func exitCode(err error) (int, error) {
+	if err == nil {
+		return 0, nil
+	}
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		if status, ok := exitErr.Sys().(syscall.WaitStatus); ok && status.ExitStatus() != -1 {
+			return status.ExitStatus(), nil
+		}
+	}
+	return 0, err
+}
+
+// Stream runs the checks of suite like Cluster, PreStart or PostStart, passing their output and results to h while
+// they run.
+func (r *Runner) Stream(ctx context.Context, suite string, h StreamHandler, selectiveChecks ...string) (*CombinedResponse, error) {
+	checks, names, err := r.suiteChecks(suite)
+	if err != nil {
+		return nil, err
+	}
+	return r.run(ctx, suite, checks, false, names, h, selectiveChecks...)
+}
+
+// guardedStream passes the output and results of checks to a StreamHandler until it is closed, so that the handler is
+// not called anymore after a run returned, e.g. because it was canceled while checks were still running.
+type guardedStream struct {
+	mu     sync.Mutex
+	h      StreamHandler
+	closed bool
+}
+
+func (g *guardedStream) CheckOutput(check, line string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if !g.closed {
+		g.h.CheckOutput(check, line)
+	}
+}
+
+func (g *guardedStream) CheckResult(check string, resp *Response, err error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if !g.closed {
+		g.h.CheckResult(check, resp, err)
+	}
+}
+
+// close stops passing output and results to the handler. It waits for calls of the handler in progress to return.
+func (g *guardedStream) close() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.closed = true
+}
+
+// lineWriter keeps everything written to it and calls emit for each complete line.
+type lineWriter struct {
+	emit func(line string)
+
+	mu      sync.Mutex
+	output  bytes.Buffer
+	partial []byte
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.output.Write(p)
+	w.partial = append(w.partial, p...)
+	for {
+		i := bytes.IndexByte(w.partial, '\n')
+		if i < 0 {
+			break
+		}
+		w.emit(string(bytes.TrimSuffix(w.partial[:i], []byte("\r"))))
+		w.partial = w.partial[i+1:]
+	}
+	return len(p), nil
+}
+
+// flush emits the last line if it is not terminated by a line ending.
+func (w *lineWriter) flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if len(w.partial) > 0 {
+		w.emit(string(w.partial))
+		w.partial = nil
+	}
+}
+
+// bytes returns everything written to w.
+func (w *lineWriter) bytes() []byte {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.output.Bytes()
+}
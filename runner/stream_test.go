@@ -0,0 +1,186 @@
+package runner
+
+import (
+	"context"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordingHandler is a StreamHandler recording the output lines and results it receives.
+type recordingHandler struct {
+	mu      sync.Mutex
+	lines   map[string][]string
+	results map[string]*Response
+	errs    map[string]error
+
+	// firstLine is the time the first line was received, finished the times the results were received.
+	firstLine time.Time
+	finished  map[string]time.Time
+}
+
+func newRecordingHandler() *recordingHandler {
+	return &recordingHandler{
+		lines:    make(map[string][]string),
+		results:  make(map[string]*Response),
+		errs:     make(map[string]error),
+		finished: make(map[string]time.Time),
+	}
+}
+
+func (h *recordingHandler) CheckOutput(check, line string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.firstLine.IsZero() {
+		h.firstLine = time.Now()
+	}
+	h.lines[check] = append(h.lines[check], line)
+}
+
+func (h *recordingHandler) CheckResult(check string, resp *Response, err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.finished[check] = time.Now()
+	h.results[check] = resp
+	h.errs[check] = err
+}
+
+func TestStream(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("test checks require sh")
+	}
+
+	r, err := NewRunner("agent")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := `
+{
+  "node_checks": {
+    "checks": {
+      "slow": {
+        "cmd": ["sh", "-c", "echo one; sleep 0.5; echo two >&2; printf three; exit 1"],
+        "timeout": "5s"
+      },
+      "builtin": {
+        "type": "sysctl",
+        "params": {"key": "kernel.nonexistent_key", "value": "1"},
+        "timeout": "1s"
+      }
+    },
+    "poststart": ["slow", "builtin"]
+  }
+}`
+	if err := r.Load(strings.NewReader(cfg)); err != nil {
+		t.Fatal(err)
+	}
+
+	h := newRecordingHandler()
+	rs, err := r.Stream(context.TODO(), SuiteNodePostStart, h, "slow", "missing")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if lines := strings.Join(h.lines["slow"], ","); lines != "one,two,three" {
+		t.Fatalf("expected lines one,two,three, got %s", lines)
+	}
+	if h.finished["slow"].Sub(h.firstLine) < 300*time.Millisecond {
+		t.Fatal("expected the first line to be streamed before the check finished")
+	}
+	if resp := h.results["slow"]; resp == nil || resp.status != statusWarning || resp.output != "one\ntwo\nthree" || h.errs["slow"] != nil {
+		t.Fatalf("unexpected result %+v, %v", resp, h.errs["slow"])
+	}
+	if h.errs["missing"] == nil {
+		t.Fatal("expected error for missing check")
+	}
+	if rs.status != statusWarning || rs.checks["slow"].output != "one\ntwo\nthree" {
+		t.Fatalf("unexpected combined response %+v", rs)
+	}
+
+	// The output of in-process checks and executors without streaming support is reported when they finish.
+	r.Executor = &fakeExecutor{}
+	h = newRecordingHandler()
+	if _, err := r.Stream(context.TODO(), SuiteNodePostStart, h); err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Join(h.lines["slow"], ","); !strings.HasPrefix(lines, "sh -c") {
+		t.Fatalf("expected output of the fake executor, got %s", lines)
+	}
+	if len(h.lines["builtin"]) == 0 || h.results["builtin"] == nil {
+		t.Fatalf("expected output and result of the builtin check, got %v", h.lines["builtin"])
+	}
+
+	if _, err := r.Stream(context.TODO(), "node", h); err == nil {
+		t.Fatal("expected error for invalid suite")
+	}
+}
+
+func TestStreamTimeout(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("test checks require sh")
+	}
+
+	r, err := NewRunner("agent")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := `{"cluster_checks": {"hang": {"cmd": ["sleep", "10"], "timeout": "200ms"}}}`
+	if err := r.Load(strings.NewReader(cfg)); err != nil {
+		t.Fatal(err)
+	}
+
+	h := newRecordingHandler()
+	rs, err := r.Stream(context.TODO(), SuiteCluster, h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp := rs.checks["hang"]; resp == nil || resp.status != statusUnknown || !strings.Contains(resp.output, "exceeded timeout") {
+		t.Fatalf("expected timeout, got %+v", resp)
+	}
+}
+
+func TestStreamCanceled(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("test checks require sh")
+	}
+
+	r, err := NewRunner("agent")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := `{"cluster_checks": {"slow": {"cmd": ["sh", "-c", "echo one; sleep 10"], "timeout": "20s"}}}`
+	if err := r.Load(strings.NewReader(cfg)); err != nil {
+		t.Fatal(err)
+	}
+
+	// Cancel the run once the first line was streamed.
+	h := newRecordingHandler()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		for {
+			h.mu.Lock()
+			streamed := !h.firstLine.IsZero()
+			h.mu.Unlock()
+			if streamed {
+				cancel()
+				return
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}()
+
+	if _, err := r.Stream(ctx, SuiteCluster, h); err != context.Canceled {
+		t.Fatalf("expected %s, got %v", context.Canceled, err)
+	}
+
+	// The handler must not be called anymore once Stream returned, e.g. with the result of the killed check.
+	time.Sleep(500 * time.Millisecond)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if len(h.results) != 0 {
+		t.Fatalf("expected no results after the run was canceled, got %v", h.results)
+	}
+}
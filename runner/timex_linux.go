@@ -0,0 +1,36 @@
+package runner
+
+import (
+	"syscall"
+	"time"
+)
+
+const (
+	// staUnsync is set in timex.status when the kernel clock is not synchronized, see adjtimex(2).
+	staUnsync = 0x0040
+	// staNano is set in timex.status when the offset is reported in nanoseconds instead of microseconds.
+	staNano = 0x2000
+	// timeError is the clock state returned by adjtimex(2) when the clock is not synchronized.
+	timeError = 5
+)
+
+// readKernelTimex returns the kernel clock state as reported by the adjtimex syscall.
+func readKernelTimex() (timex, error) {
+	var tx syscall.Timex
+	state, err := syscall.Adjtimex(&tx)
+	if err != nil {
+		return timex{}, err
+	}
+
+	offsetUnit := time.Microsecond
+	if tx.Status&staNano != 0 {
+		offsetUnit = time.Nanosecond
+	}
+
+	return timex{
+		synchronized:   state != timeError && tx.Status&staUnsync == 0,
+		offset:         time.Duration(tx.Offset) * offsetUnit,
+		estimatedError: time.Duration(tx.Esterror) * time.Microsecond,
+		maxError:       time.Duration(tx.Maxerror) * time.Microsecond,
+	}, nil
+}
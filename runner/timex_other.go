@@ -0,0 +1,15 @@
+//go:build !linux
+// +build !linux
+
+package runner
+
+import (
+	"runtime"
+
+	"github.com/pkg/errors"
+)
+
+// readKernelTimex is only supported on Linux.
+func readKernelTimex() (timex, error) {
+	return timex{}, errors.Errorf("time_sync check is not supported on %s", runtime.GOOS)
+}
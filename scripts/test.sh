@@ -16,7 +16,7 @@ set -o pipefail
 export PATH="${GOPATH}/bin:${PATH}"
 
 PACKAGES="$(go list -mod=vendor ./... )"
-SUBDIRS="api cmd config runner"
+SUBDIRS="aggregate api auth client cmd config history listen output runner servertls sshexec systemd webhook"
 SOURCE_DIR=$(git rev-parse --show-toplevel)
 BUILD_DIR="${SOURCE_DIR}/build"
 
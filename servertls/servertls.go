@@ -0,0 +1,206 @@
+// Package servertls provides TLS configurations for the check runner HTTP server, which reload their certificates
+// when the files change and optionally require client certificates.
+package servertls
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// DefaultReloadInterval is the default minimum interval between checks whether the certificate files changed.
+const DefaultReloadInterval = 5 * time.Second
+
+// Options configures the TLS configuration of a server.
+type Options struct {
+	// CertFile and KeyFile are the PEM encoded certificate chain and private key of the server.
+	CertFile string
+	KeyFile  string
+
+	// ClientCAFile holds the PEM encoded CA certificates which client certificates are verified with. If it is set,
+	// clients must present a certificate.
+	ClientCAFile string
+
+	// ClientSubjects restricts the clients to certificates with one of the given subjects, either the common name or
+	// the full distinguished name like "CN=dcos-check-runner,O=Mesosphere". All verified clients are allowed if it is
+	// empty.
+	ClientSubjects []string
+
+	// ReloadInterval is the minimum interval between checks whether the files changed. The files are checked during
+	// TLS handshakes, so they are only reloaded when clients connect. It defaults to DefaultReloadInterval.
+	ReloadInterval time.Duration
+}
+
+// NewConfig returns a TLS configuration for a server as configured by opts. The certificate, key and client CA files
+// are loaded immediately, and reloaded when they change. If reloading fails, the previous files are used until they
+// change again.
+func NewConfig(opts Options) (*tls.Config, error) {
+	if opts.CertFile == "" || opts.KeyFile == "" {
+		return nil, errors.New("a TLS certificate and key are required")
+	}
+	if len(opts.ClientSubjects) > 0 && opts.ClientCAFile == "" {
+		return nil, errors.New("a client CA is required to restrict client certificate subjects")
+	}
+	if opts.ReloadInterval == 0 {
+		opts.ReloadInterval = DefaultReloadInterval
+	}
+
+	r := &reloader{opts: opts, now: time.Now}
+	r.stamps = r.stat()
+	if err := r.load(); err != nil {
+		return nil, err
+	}
+	r.lastCheck = r.now()
+
+	return &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		GetConfigForClient: r.configForClient,
+	}, nil
+}
+
+// fileStamp identifies a version of a file.
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+// reloader keeps the current certificates and reloads them when their files change.
+type reloader struct {
+	opts Options
+
+	// now returns the current time. It is replaced in tests.
+	now func() time.Time
+
+	mu        sync.Mutex
+	lastCheck time.Time
+	stamps    []fileStamp
+	cert      *tls.Certificate
+	clientCAs *x509.CertPool
+}
+
+// files returns the files which are loaded by r.
+func (r *reloader) files() []string {
+	files := []string{r.opts.CertFile, r.opts.KeyFile}
+	if r.opts.ClientCAFile != "" {
+		files = append(files, r.opts.ClientCAFile)
+	}
+	return files
+}
+
+// stat returns the current stamps of r's files. Files which can't be read have a zero stamp.
+func (r *reloader) stat() []fileStamp {
+	var stamps []fileStamp
+	for _, f := range r.files() {
+		var stamp fileStamp
+		if info, err := os.Stat(f); err == nil {
+			stamp = fileStamp{info.ModTime(), info.Size()}
+		}
+		stamps = append(stamps, stamp)
+	}
+	return stamps
+}
+
+// load loads the certificate, key and client CAs from their files. r.mu must be held, unless r is not used yet.
+func (r *reloader) load() error {
+	cert, err := tls.LoadX509KeyPair(r.opts.CertFile, r.opts.KeyFile)
+	if err != nil {
+		return errors.Wrap(err, "unable to load TLS certificate")
+	}
+
+	var clientCAs *x509.CertPool
+	if r.opts.ClientCAFile != "" {
+		pem, err := ioutil.ReadFile(r.opts.ClientCAFile)
+		if err != nil {
+			return errors.Wrap(err, "unable to read client CA")
+		}
+		clientCAs = x509.NewCertPool()
+		if !clientCAs.AppendCertsFromPEM(pem) {
+			return errors.Errorf("no certificates found in client CA %s", r.opts.ClientCAFile)
+		}
+	}
+
+	r.cert = &cert
+	r.clientCAs = clientCAs
+	return nil
+}
+
+// maybeReload reloads the files if ReloadInterval passed since they were last checked and they changed.
+func (r *reloader) maybeReload() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := r.now()
+	if now.Sub(r.lastCheck) < r.opts.ReloadInterval {
+		return
+	}
+	r.lastCheck = now
+
+	stamps := r.stat()
+	if stampsEqual(stamps, r.stamps) {
+		return
+	}
+	// The files are only retried when they change again, e.g. when a certificate was replaced before its key.
+	r.stamps = stamps
+
+	if err := r.load(); err != nil {
+		logrus.WithError(err).Error("Unable to reload TLS certificates, keeping the previous ones")
+		return
+	}
+	logrus.Info("Reloaded TLS certificates")
+}
+
+func stampsEqual(a, b []fileStamp) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !a[i].modTime.Equal(b[i].modTime) || a[i].size != b[i].size {
+			return false
+		}
+	}
+	return true
+}
+
+// configForClient returns the TLS configuration with the current certificates for a handshake.
+func (r *reloader) configForClient(*tls.ClientHelloInfo) (*tls.Config, error) {
+	r.maybeReload()
+
+	r.mu.Lock()
+	cert, clientCAs := r.cert, r.clientCAs
+	r.mu.Unlock()
+
+	cfg := &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		Certificates: []tls.Certificate{*cert},
+	}
+	if clientCAs != nil {
+		cfg.ClientAuth = tls.RequireAndVerifyClientCert
+		cfg.ClientCAs = clientCAs
+		if len(r.opts.ClientSubjects) > 0 {
+			cfg.VerifyPeerCertificate = r.verifyClientSubject
+		}
+	}
+	return cfg, nil
+}
+
+// verifyClientSubject returns an error unless the subject of the verified client certificate is allowed by
+// ClientSubjects.
+func (r *reloader) verifyClientSubject(rawCerts [][]byte, verifiedChains [][]*x509.Certificate) error {
+	if len(verifiedChains) == 0 || len(verifiedChains[0]) == 0 {
+		return errors.New("no verified client certificate")
+	}
+
+	subject := verifiedChains[0][0].Subject
+	for _, allowed := range r.opts.ClientSubjects {
+		if allowed == subject.CommonName || allowed == subject.String() {
+			return nil
+		}
+	}
+	return errors.Errorf("client certificate subject %s is not allowed", subject)
+}
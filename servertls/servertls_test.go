@@ -0,0 +1,298 @@
+package servertls
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// testCA is a certificate authority issuing certificates for tests.
+type testCA struct {
+	cert   *x509.Certificate
+	key    *ecdsa.PrivateKey
+	serial int64
+}
+
+func newTestCA(t *testing.T) *testCA {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testCA{cert: cert, key: key, serial: 1}
+}
+
+// issue returns a PEM encoded certificate and key for subject.
+func (ca *testCA) issue(t *testing.T, subject pkix.Name, usage x509.ExtKeyUsage) ([]byte, []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca.serial++
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(ca.serial),
+		Subject:      subject,
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+func (ca *testCA) pem() []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.cert.Raw})
+}
+
+func (ca *testCA) pool() *x509.CertPool {
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.cert)
+	return pool
+}
+
+// writes counts the calls of writeFiles.
+var writes int
+
+// writeFiles writes the given files to dir. Their modification times are set apart from previous writes, so that the
+// changes are detected even with a coarse file system clock.
+func writeFiles(t *testing.T, dir string, files map[string][]byte) {
+	writes++
+	mtime := time.Now().Add(time.Duration(writes) * time.Minute)
+	for name, data := range files {
+		path := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(path, data, 0600); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// serve serves HTTP with cfg on a local port and returns its address.
+func serve(t *testing.T, cfg *tls.Config) (string, func()) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	})}
+	go s.Serve(tls.NewListener(l, cfg))
+	return "https://" + l.Addr().String(), func() { s.Close() }
+}
+
+// get sends a request to url and returns the serial number of the server certificate.
+func get(client *http.Client, url string) (int64, error) {
+	resp, err := client.Get(url)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	return resp.TLS.PeerCertificates[0].SerialNumber.Int64(), nil
+}
+
+func newClient(roots *x509.CertPool, certs ...tls.Certificate) *http.Client {
+	return &http.Client{Transport: &http.Transport{
+		TLSClientConfig:   &tls.Config{RootCAs: roots, Certificates: certs},
+		DisableKeepAlives: true,
+	}}
+}
+
+func TestReload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "servertls")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	ca := newTestCA(t)
+	cert, key := ca.issue(t, pkix.Name{CommonName: "server"}, x509.ExtKeyUsageServerAuth)
+	writeFiles(t, dir, map[string][]byte{"server.crt": cert, "server.key": key})
+
+	cfg, err := NewConfig(Options{
+		CertFile:       filepath.Join(dir, "server.crt"),
+		KeyFile:        filepath.Join(dir, "server.key"),
+		ReloadInterval: time.Nanosecond,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	url, stop := serve(t, cfg)
+	defer stop()
+
+	client := newClient(ca.pool())
+	serial, err := get(client, url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if serial != 2 {
+		t.Fatalf("expected certificate 2, got %d", serial)
+	}
+
+	// A certificate without its key is not loaded.
+	cert, key = ca.issue(t, pkix.Name{CommonName: "server"}, x509.ExtKeyUsageServerAuth)
+	writeFiles(t, dir, map[string][]byte{"server.crt": cert})
+	if serial, err = get(client, url); err != nil || serial != 2 {
+		t.Fatalf("expected the previous certificate 2, got %d, %v", serial, err)
+	}
+
+	writeFiles(t, dir, map[string][]byte{"server.key": key})
+	if serial, err = get(client, url); err != nil || serial != 3 {
+		t.Fatalf("expected the reloaded certificate 3, got %d, %v", serial, err)
+	}
+}
+
+func TestReloadInterval(t *testing.T) {
+	dir, err := ioutil.TempDir("", "servertls")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	ca := newTestCA(t)
+	cert, key := ca.issue(t, pkix.Name{CommonName: "server"}, x509.ExtKeyUsageServerAuth)
+	writeFiles(t, dir, map[string][]byte{"server.crt": cert, "server.key": key})
+
+	r := &reloader{
+		opts: Options{
+			CertFile:       filepath.Join(dir, "server.crt"),
+			KeyFile:        filepath.Join(dir, "server.key"),
+			ReloadInterval: time.Minute,
+		},
+	}
+	now := time.Now()
+	r.now = func() time.Time { return now }
+	r.stamps = r.stat()
+	if err := r.load(); err != nil {
+		t.Fatal(err)
+	}
+	r.lastCheck = now
+
+	cert, key = ca.issue(t, pkix.Name{CommonName: "server"}, x509.ExtKeyUsageServerAuth)
+	writeFiles(t, dir, map[string][]byte{"server.crt": cert, "server.key": key})
+
+	r.maybeReload()
+	if serial := certSerial(t, r.cert); serial != 2 {
+		t.Fatalf("expected no reload within the interval, got certificate %d", serial)
+	}
+
+	now = now.Add(time.Minute)
+	r.maybeReload()
+	if serial := certSerial(t, r.cert); serial != 3 {
+		t.Fatalf("expected the reloaded certificate 3, got %d", serial)
+	}
+}
+
+func certSerial(t *testing.T, cert *tls.Certificate) int64 {
+	c, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c.SerialNumber.Int64()
+}
+
+func TestClientCertificates(t *testing.T) {
+	dir, err := ioutil.TempDir("", "servertls")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	ca := newTestCA(t)
+	cert, key := ca.issue(t, pkix.Name{CommonName: "server"}, x509.ExtKeyUsageServerAuth)
+	writeFiles(t, dir, map[string][]byte{"server.crt": cert, "server.key": key, "ca.crt": ca.pem()})
+
+	cfg, err := NewConfig(Options{
+		CertFile:       filepath.Join(dir, "server.crt"),
+		KeyFile:        filepath.Join(dir, "server.key"),
+		ClientCAFile:   filepath.Join(dir, "ca.crt"),
+		ClientSubjects: []string{"dcos-check-runner", "CN=prometheus,O=Monitoring"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	url, stop := serve(t, cfg)
+	defer stop()
+
+	clientCert := func(subject pkix.Name, issuer *testCA) tls.Certificate {
+		cert, key := issuer.issue(t, subject, x509.ExtKeyUsageClientAuth)
+		c, err := tls.X509KeyPair(cert, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return c
+	}
+
+	for _, tt := range []struct {
+		name    string
+		certs   []tls.Certificate
+		allowed bool
+	}{
+		{"no client certificate", nil, false},
+		{"allowed common name", []tls.Certificate{clientCert(pkix.Name{CommonName: "dcos-check-runner"}, ca)}, true},
+		{"allowed subject", []tls.Certificate{clientCert(pkix.Name{CommonName: "prometheus", Organization: []string{"Monitoring"}}, ca)}, true},
+		{"subject not allowed", []tls.Certificate{clientCert(pkix.Name{CommonName: "mallory"}, ca)}, false},
+		{"unknown CA", []tls.Certificate{clientCert(pkix.Name{CommonName: "dcos-check-runner"}, newTestCA(t))}, false},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := get(newClient(ca.pool(), tt.certs...), url)
+			if tt.allowed && err != nil {
+				t.Fatalf("expected the client to be allowed, got %s", err)
+			}
+			if !tt.allowed && err == nil {
+				t.Fatal("expected the client to be rejected")
+			}
+		})
+	}
+}
+
+func TestNewConfigErrors(t *testing.T) {
+	if _, err := NewConfig(Options{CertFile: "server.crt"}); err == nil {
+		t.Fatal("expected an error without a key")
+	}
+	if _, err := NewConfig(Options{CertFile: "server.crt", KeyFile: "server.key", ClientSubjects: []string{"a"}}); err == nil {
+		t.Fatal("expected an error for client subjects without a client CA")
+	}
+	if _, err := NewConfig(Options{CertFile: "nonexistent.crt", KeyFile: "nonexistent.key"}); err == nil {
+		t.Fatal("expected an error for missing files")
+	}
+}
@@ -0,0 +1,102 @@
+package sshexec
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// dialTimeout is the timeout for establishing the SSH connection.
+const dialTimeout = 10 * time.Second
+
+// Options configure authentication and host key verification of an SSH connection.
+type Options struct {
+	// User is the remote user to log in as.
+	User string
+
+	// KeyFile is a private key file used for public key authentication. Keys held by the agent at SSH_AUTH_SOCK are
+	// used in addition.
+	KeyFile string
+
+	// KnownHostsFile is the known_hosts file used to verify the server's host key.
+	KnownHostsFile string
+
+	// InsecureIgnoreHostKey disables host key verification.
+	InsecureIgnoreHostKey bool
+}
+
+// NewClientConfig returns an *ssh.ClientConfig for the given options, and a function closing the connection to the
+// SSH agent. The agent signs during the handshake, so the function must only be called once no more connections are
+// established with the config. Agent errors are logged and ignored if a key file is given.
+func NewClientConfig(opts Options) (*ssh.ClientConfig, func() error, error) {
+	var signers []ssh.Signer
+
+	if opts.KeyFile != "" {
+		key, err := ioutil.ReadFile(opts.KeyFile)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "unable to read SSH key")
+		}
+		signer, err := ssh.ParsePrivateKey(key)
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "unable to parse SSH key %s", opts.KeyFile)
+		}
+		signers = append(signers, signer)
+	}
+
+	closeAgent := func() error { return nil }
+	if sock := os.Getenv("SSH_AUTH_SOCK"); sock != "" {
+		conn, agentSigners, err := dialAgent(sock)
+		switch {
+		case err == nil:
+			signers = append(signers, agentSigners...)
+			closeAgent = conn.Close
+		case opts.KeyFile != "":
+			logrus.Warnf("Ignoring SSH agent: %s", err)
+		default:
+			return nil, nil, err
+		}
+	}
+
+	if len(signers) == 0 {
+		closeAgent()
+		return nil, nil, errors.New("no SSH key given and no keys available from SSH agent")
+	}
+
+	hostKeyCallback := ssh.InsecureIgnoreHostKey()
+	if !opts.InsecureIgnoreHostKey {
+		var err error
+		hostKeyCallback, err = knownhosts.New(opts.KnownHostsFile)
+		if err != nil {
+			closeAgent()
+			return nil, nil, errors.Wrap(err, "unable to read known hosts")
+		}
+	}
+
+	return &ssh.ClientConfig{
+		User:            opts.User,
+		Auth:            []ssh.AuthMethod{ssh.PublicKeys(signers...)},
+		HostKeyCallback: hostKeyCallback,
+		Timeout:         dialTimeout,
+	}, closeAgent, nil
+}
+
+// dialAgent connects to the SSH agent listening on sock and returns the connection along with the agent's keys.
+func dialAgent(sock string) (net.Conn, []ssh.Signer, error) {
+	conn, err := net.Dial("unix", sock)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "unable to connect to SSH agent")
+	}
+	signers, err := agent.NewClient(conn).Signers()
+	if err != nil {
+		conn.Close()
+		return nil, nil, errors.Wrap(err, "unable to get keys from SSH agent")
+	}
+	return conn, signers, nil
+}
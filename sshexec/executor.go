@@ -0,0 +1,156 @@
+// Package sshexec provides a runner.Executor which runs check commands on a remote host over SSH.
+package sshexec
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/ssh"
+)
+
+// DefaultMaxSessions is the default number of commands an Executor runs concurrently. It matches the default of
+// OpenSSH's MaxSessions setting, above which the server refuses to open new sessions on a connection.
+const DefaultMaxSessions = 10
+
+// Executor runs check commands on a remote host over a single SSH connection, opening a session per command. It
+// implements runner.Executor.
+type Executor struct {
+	// Env is set in the environment of remote commands.
+	Env map[string]string
+
+	client   *ssh.Client
+	sessions chan struct{}
+}
+
+// Dial connects to the SSH server at addr and returns an Executor running commands on it.
+func Dial(addr string, config *ssh.ClientConfig) (*Executor, error) {
+	client, err := ssh.Dial("tcp", addr, config)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to connect to %s", addr)
+	}
+	return NewExecutor(client, DefaultMaxSessions), nil
+}
+
+// NewExecutor returns an Executor running commands over client, with at most maxSessions commands running
+// concurrently.
+func NewExecutor(client *ssh.Client, maxSessions int) *Executor {
+	if maxSessions < 1 {
+		maxSessions = DefaultMaxSessions
+	}
+	return &Executor{
+		client:   client,
+		sessions: make(chan struct{}, maxSessions),
+	}
+}
+
+// Close closes the underlying SSH connection.
+func (e *Executor) Close() error {
+	return e.client.Close()
+}
+
+// Execute runs cmd in a new session on the remote host and returns its output and exit code. When ctx is done the
+// remote command is sent SIGKILL and the session is closed.
+func (e *Executor) Execute(ctx context.Context, cmd []string) ([]byte, []byte, int, error) {
+	select {
+	case e.sessions <- struct{}{}:
+		defer func() { <-e.sessions }()
+	case <-ctx.Done():
+		return nil, nil, 0, ctx.Err()
+	}
+
+	session, err := e.client.NewSession()
+	if err != nil {
+		return nil, nil, 0, errors.Wrap(err, "unable to open SSH session")
+	}
+	defer session.Close()
+
+	var stdout, stderr bytes.Buffer
+	session.Stdout = &stdout
+	session.Stderr = &stderr
+
+	if err := session.Start(e.command(cmd)); err != nil {
+		return nil, nil, 0, errors.Wrap(err, "unable to start remote command")
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- session.Wait()
+	}()
+
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		// Not all servers support signals, closing the session closes the command's stdio as a fallback.
+		session.Signal(ssh.SIGKILL)
+		session.Close()
+		return nil, nil, 0, ctx.Err()
+	}
+
+	if err != nil {
+		exitErr, ok := err.(*ssh.ExitError)
+		if !ok {
+			return nil, nil, 0, errors.Wrap(err, "remote command failed")
+		}
+		return stdout.Bytes(), stderr.Bytes(), exitErr.ExitStatus(), nil
+	}
+	return stdout.Bytes(), stderr.Bytes(), 0, nil
+}
+
+// command returns cmd as a string for the remote user's shell. If Env is set, the command is run with env(1).
+func (e *Executor) command(cmd []string) string {
+	if len(e.Env) > 0 {
+		var env []string
+		for k, v := range e.Env {
+			env = append(env, k+"="+v)
+		}
+		sort.Strings(env)
+		cmd = append(append([]string{"env"}, env...), cmd...)
+	}
+
+	quoted := make([]string, len(cmd))
+	for i, arg := range cmd {
+		quoted[i] = quoteArg(arg)
+	}
+	return strings.Join(quoted, " ")
+}
+
+// quoteArg returns arg quoted for a POSIX shell. Arguments consisting only of safe characters are returned as is.
+func quoteArg(arg string) string {
+	if arg == "" {
+		return "''"
+	}
+
+	safe := true
+	for _, r := range arg {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("-_./=:,@%+", r)) {
+			safe = false
+			break
+		}
+	}
+	if safe {
+		return arg
+	}
+	return "'" + strings.Replace(arg, "'", `'\''`, -1) + "'"
+}
+
+// ParseTarget parses an SSH target of the form [user@]host[:port] and returns the user and the host:port address.
+// If no user is given, defaultUser is returned. The port defaults to 22.
+func ParseTarget(target, defaultUser string) (user, addr string, err error) {
+	user = defaultUser
+	host := target
+	if i := strings.LastIndex(target, "@"); i >= 0 {
+		user, host = target[:i], target[i+1:]
+	}
+	if user == "" || host == "" {
+		return "", "", errors.Errorf("invalid SSH target %q, expected [user@]host[:port]", target)
+	}
+
+	if _, _, err := net.SplitHostPort(host); err != nil {
+		host = net.JoinHostPort(strings.Trim(host, "[]"), "22")
+	}
+	return user, host, nil
+}
@@ -0,0 +1,426 @@
+//go:build !windows
+// +build !windows
+
+package sshexec
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/binary"
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/dcos/dcos-check-runner/runner"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// testServer is a minimal in-process SSH server which runs exec requests with /bin/sh.
+type testServer struct {
+	listener  net.Listener
+	hostKey   ssh.Signer
+	clientKey ssh.Signer
+	config    *ssh.ServerConfig
+	wg        sync.WaitGroup
+}
+
+func newSigner(t *testing.T) ssh.Signer {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := ssh.NewSignerFromKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signer
+}
+
+func newTestServer(t *testing.T) *testServer {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := &testServer{
+		listener:  listener,
+		hostKey:   newSigner(t),
+		clientKey: newSigner(t),
+	}
+	s.config = &ssh.ServerConfig{
+		PublicKeyCallback: func(conn ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			if conn.User() == "dcos" && string(key.Marshal()) == string(s.clientKey.PublicKey().Marshal()) {
+				return nil, nil
+			}
+			return nil, ssh.ErrNoAuth
+		},
+	}
+	s.config.AddHostKey(s.hostKey)
+
+	go s.serve()
+	return s
+}
+
+func (s *testServer) Close() {
+	s.listener.Close()
+	s.wg.Wait()
+}
+
+func (s *testServer) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.handleConn(conn)
+		}()
+	}
+}
+
+func (s *testServer) handleConn(conn net.Conn) {
+	sconn, chans, reqs, err := ssh.NewServerConn(conn, s.config)
+	if err != nil {
+		conn.Close()
+		return
+	}
+	defer sconn.Close()
+	go ssh.DiscardRequests(reqs)
+
+	for newChan := range chans {
+		if newChan.ChannelType() != "session" {
+			newChan.Reject(ssh.UnknownChannelType, "unsupported channel type")
+			continue
+		}
+		channel, requests, err := newChan.Accept()
+		if err != nil {
+			return
+		}
+		go s.handleSession(channel, requests)
+	}
+}
+
+func (s *testServer) handleSession(channel ssh.Channel, requests <-chan *ssh.Request) {
+	defer channel.Close()
+
+	var cmd *exec.Cmd
+	exited := make(chan struct{})
+	for req := range requests {
+		switch req.Type {
+		case "exec":
+			if cmd != nil || len(req.Payload) < 4 {
+				req.Reply(false, nil)
+				continue
+			}
+			command := string(req.Payload[4:])
+			cmd = exec.Command("/bin/sh", "-c", command)
+			cmd.Stdout = channel
+			cmd.Stderr = channel.Stderr()
+			if err := cmd.Start(); err != nil {
+				req.Reply(false, nil)
+				return
+			}
+			req.Reply(true, nil)
+
+			go func() {
+				defer close(exited)
+				status := make([]byte, 4)
+				if err := cmd.Wait(); err != nil {
+					if exitErr, ok := err.(*exec.ExitError); ok {
+						binary.BigEndian.PutUint32(status, uint32(exitErr.Sys().(syscall.WaitStatus).ExitStatus()))
+					}
+				}
+				channel.SendRequest("exit-status", false, status)
+				channel.Close()
+			}()
+		case "signal":
+			if cmd != nil {
+				cmd.Process.Kill()
+			}
+			req.Reply(true, nil)
+		default:
+			req.Reply(false, nil)
+		}
+	}
+
+	// The client closed the session.
+	if cmd != nil {
+		cmd.Process.Kill()
+		<-exited
+	}
+}
+
+func (s *testServer) dial(t *testing.T) *Executor {
+	e, err := Dial(s.listener.Addr().String(), &ssh.ClientConfig{
+		User:            "dcos",
+		Auth:            []ssh.AuthMethod{ssh.PublicKeys(s.clientKey)},
+		HostKeyCallback: ssh.FixedHostKey(s.hostKey.PublicKey()),
+		Timeout:         time.Second,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return e
+}
+
+func TestExecute(t *testing.T) {
+	s := newTestServer(t)
+	defer s.Close()
+	e := s.dial(t)
+	defer e.Close()
+
+	tests := []struct {
+		cmd            []string
+		expectedStdout string
+		expectedStderr string
+		expectedCode   int
+	}{
+		{[]string{"echo", "hello"}, "hello\n", "", 0},
+		{[]string{"echo", "it's", "a b", "$HOME", ""}, "it's a b $HOME \n", "", 0},
+		{[]string{"sh", "-c", "echo out; echo err >&2; exit 2"}, "out\n", "err\n", 2},
+	}
+	for _, test := range tests {
+		stdout, stderr, code, err := e.Execute(context.TODO(), test.cmd)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(stdout) != test.expectedStdout || string(stderr) != test.expectedStderr || code != test.expectedCode {
+			t.Fatalf("%v: expected (%q, %q, %d), got (%q, %q, %d)", test.cmd, test.expectedStdout, test.expectedStderr,
+				test.expectedCode, stdout, stderr, code)
+		}
+	}
+
+	// Env is set in the environment of the remote command.
+	e.Env = map[string]string{"FOO": "foo bar", "BAR": "$baz"}
+	stdout, _, _, err := e.Execute(context.TODO(), []string{"sh", "-c", "echo $FOO $BAR"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(stdout) != "foo bar $baz\n" {
+		t.Fatalf("unexpected output %q", stdout)
+	}
+	e.Env = nil
+
+	// The remote command is stopped when the context is done.
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, _, _, err := e.Execute(ctx, []string{"sleep", "10"}); err != context.DeadlineExceeded {
+		t.Fatalf("expected error %s, got %v", context.DeadlineExceeded, err)
+	}
+	if time.Since(start) > 2*time.Second {
+		t.Fatalf("expected Execute to return after the timeout, took %s", time.Since(start))
+	}
+}
+
+func TestRunnerWithExecutor(t *testing.T) {
+	s := newTestServer(t)
+	defer s.Close()
+	e := s.dial(t)
+	defer e.Close()
+
+	r, err := runner.NewRunner("agent")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Executor = e
+
+	cfg := `
+{
+  "node_checks": {
+    "checks": {
+      "check1": {
+        "cmd": ["echo", "check1"],
+        "timeout": "1s"
+      },
+      "check2": {
+        "cmd": ["sh", "-c", "echo check2; exit 1"],
+        "timeout": "1s"
+      },
+      "check3": {
+        "cmd": ["sleep", "10"],
+        "timeout": "200ms"
+      }
+    },
+    "prestart": ["check1", "check2", "check3"]
+  }
+}`
+	if err := r.Load(strings.NewReader(cfg)); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := r.PreStart(context.TODO(), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := json.Marshal(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var resp struct {
+		Status int `json:"status"`
+		Checks map[string]struct {
+			Output string `json:"output"`
+			Status int    `json:"status"`
+		} `json:"checks"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		t.Fatal(err)
+	}
+
+	if resp.Status != 3 {
+		t.Fatalf("expected status 3, got %d", resp.Status)
+	}
+	if check := resp.Checks["check1"]; check.Status != 0 || check.Output != "check1\n" {
+		t.Fatalf("unexpected result for check1: %+v", check)
+	}
+	if check := resp.Checks["check2"]; check.Status != 1 || check.Output != "check2\n" {
+		t.Fatalf("unexpected result for check2: %+v", check)
+	}
+	if check := resp.Checks["check3"]; check.Status != 3 || check.Output != "command [sleep 10] exceeded timeout 200ms and was killed" {
+		t.Fatalf("unexpected result for check3: %+v", check)
+	}
+}
+
+// writeECKey writes key to path in PEM format.
+func writeECKey(path string, key *ecdsa.PrivateKey) error {
+	der, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}), 0600)
+}
+
+func TestNewClientConfig(t *testing.T) {
+	os.Unsetenv("SSH_AUTH_SOCK")
+
+	s := newTestServer(t)
+	defer s.Close()
+
+	dir, err := ioutil.TempDir("", "sshexec")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// Write the client key in PEM format.
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyFile := filepath.Join(dir, "id_ecdsa")
+	if err := writeECKey(keyFile, key); err != nil {
+		t.Fatal(err)
+	}
+	s.clientKey, err = ssh.NewSignerFromKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	addr := s.listener.Addr().String()
+	knownHostsFile := filepath.Join(dir, "known_hosts")
+	knownHosts := knownhosts.Line([]string{knownhosts.Normalize(addr)}, s.hostKey.PublicKey()) + "\n"
+	if err := ioutil.WriteFile(knownHostsFile, []byte(knownHosts), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	config, closeAgent, err := NewClientConfig(Options{User: "dcos", KeyFile: keyFile, KnownHostsFile: knownHostsFile})
+	if err != nil {
+		t.Fatal(err)
+	}
+	e, err := Dial(addr, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.Close()
+	closeAgent()
+
+	// Unknown host keys are rejected.
+	otherKnownHosts := knownhosts.Line([]string{knownhosts.Normalize(addr)}, newSigner(t).PublicKey()) + "\n"
+	if err := ioutil.WriteFile(knownHostsFile, []byte(otherKnownHosts), 0600); err != nil {
+		t.Fatal(err)
+	}
+	config, _, err = NewClientConfig(Options{User: "dcos", KeyFile: keyFile, KnownHostsFile: knownHostsFile})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Dial(addr, config); err == nil {
+		t.Fatal("expected an error connecting to a host with an unknown key")
+	}
+
+	// Host key verification can be disabled.
+	config, _, err = NewClientConfig(Options{User: "dcos", KeyFile: keyFile, InsecureIgnoreHostKey: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	e, err = Dial(addr, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.Close()
+
+	if _, _, err := NewClientConfig(Options{User: "dcos"}); err == nil {
+		t.Fatal("expected an error without any keys")
+	}
+
+	// An unavailable SSH agent is ignored if a key file is given.
+	os.Setenv("SSH_AUTH_SOCK", filepath.Join(dir, "agent.sock"))
+	defer os.Unsetenv("SSH_AUTH_SOCK")
+	config, _, err = NewClientConfig(Options{User: "dcos", KeyFile: keyFile, InsecureIgnoreHostKey: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	e, err = Dial(addr, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.Close()
+
+	if _, _, err := NewClientConfig(Options{User: "dcos", InsecureIgnoreHostKey: true}); err == nil {
+		t.Fatal("expected an error without a key file and SSH agent")
+	}
+}
+
+func TestParseTarget(t *testing.T) {
+	tests := []struct {
+		target       string
+		expectedUser string
+		expectedAddr string
+	}{
+		{"core@10.0.0.1", "core", "10.0.0.1:22"},
+		{"core@10.0.0.1:2222", "core", "10.0.0.1:2222"},
+		{"master.mesos", "root", "master.mesos:22"},
+		{"centos@[fd00::1]", "centos", "[fd00::1]:22"},
+		{"centos@[fd00::1]:2222", "centos", "[fd00::1]:2222"},
+	}
+	for _, test := range tests {
+		user, addr, err := ParseTarget(test.target, "root")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if user != test.expectedUser || addr != test.expectedAddr {
+			t.Fatalf("%s: expected %s, %s, got %s, %s", test.target, test.expectedUser, test.expectedAddr, user, addr)
+		}
+	}
+
+	for _, target := range []string{"", "core@", "@host"} {
+		if _, _, err := ParseTarget(target, ""); err == nil {
+			t.Fatalf("expected an error parsing %q", target)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+// Package systemd notifies the systemd service manager of the state of the check runner, so that it can be run as a
+// Type=notify service with a watchdog.
+//
+// Notifications are sent to the socket in the NOTIFY_SOCKET environment variable. If it is not set, e.g. because the
+// check runner was not started by systemd, notifications are silently dropped.
+package systemd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coreos/go-systemd/daemon"
+	"github.com/dcos/dcos-check-runner/output"
+	"github.com/dcos/dcos-check-runner/runner"
+	"github.com/sirupsen/logrus"
+)
+
+// Notifier sends notifications to the service manager. It implements runner.Observer to report a summary of the last
+// run of checks in the service's status.
+type Notifier struct {
+	mu     sync.Mutex
+	status string
+}
+
+// NewNotifier returns a Notifier.
+func NewNotifier() *Notifier {
+	return &Notifier{}
+}
+
+// Ready notifies the service manager that the check runner finished starting up or reloading its configuration.
+func (n *Notifier) Ready() {
+	n.notify(daemon.SdNotifyReady)
+}
+
+// Reloading notifies the service manager that the check runner is reloading its configuration. Ready must be called
+// when reloading finished, whether it succeeded or not.
+func (n *Notifier) Reloading() {
+	n.notify(daemon.SdNotifyReloading)
+}
+
+// Stopping notifies the service manager that the check runner is shutting down.
+func (n *Notifier) Stopping() {
+	n.notify(daemon.SdNotifyStopping)
+}
+
+// Status sets the status of the service shown by systemctl status.
+func (n *Notifier) Status(status string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if status == n.status {
+		return
+	}
+	n.status = status
+	n.notify("STATUS=" + status)
+}
+
+// ObserveChecks sets the status of the service to a summary of the run of suite.
+func (n *Notifier) ObserveChecks(suite string, rs *runner.CombinedResponse) {
+	var failed []string
+	for _, check := range rs.Checks() {
+		if check.Status() != 0 {
+			failed = append(failed, fmt.Sprintf("%s %s", check.Name(), output.StatusName(check.Status())))
+		}
+	}
+
+	status := fmt.Sprintf("Last %s run: %s, %d checks", suite, output.StatusName(rs.Status()), len(rs.Checks()))
+	if len(failed) > 0 {
+		status += " (" + strings.Join(failed, ", ") + ")"
+	}
+	if errs := len(rs.Errors()); errs > 0 {
+		status += fmt.Sprintf(", %d failed to execute", errs)
+	}
+	n.Status(status)
+}
+
+// Watchdog sends keep-alive pings to the service manager until ctx is done, at half the watchdog interval configured
+// for the service. Pings are only sent while healthy returns nil, so that the service manager restarts the check runner
+// if it stays unhealthy for the watchdog interval. Watchdog returns immediately if the watchdog is not enabled.
+func (n *Notifier) Watchdog(ctx context.Context, healthy func() error) {
+	interval, err := daemon.SdWatchdogEnabled(false)
+	if err != nil {
+		logrus.Warnf("Invalid systemd watchdog configuration: %s", err)
+		return
+	}
+	if interval == 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval / 2)
+	defer ticker.Stop()
+	for {
+		if err := healthy(); err != nil {
+			logrus.Warnf("Skipping systemd watchdog ping, check runner is not healthy: %s", err)
+		} else {
+			n.notify(daemon.SdNotifyWatchdog)
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// notify sends state to the service manager, logging errors instead of returning them, as notifications are best
+// effort.
+func (n *Notifier) notify(state string) {
+	if _, err := daemon.SdNotify(false, state); err != nil {
+		logrus.Warnf("Unable to notify systemd of %q: %s", state, err)
+	}
+}
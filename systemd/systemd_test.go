@@ -0,0 +1,187 @@
+//go:build !windows
+// +build !windows
+
+package systemd
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dcos/dcos-check-runner/runner"
+	"github.com/pkg/errors"
+)
+
+// fakeNotifySocket is a datagram socket set as NOTIFY_SOCKET which records the notifications sent to it.
+type fakeNotifySocket struct {
+	conn *net.UnixConn
+	dir  string
+
+	messages chan string
+}
+
+func newFakeNotifySocket(t *testing.T) *fakeNotifySocket {
+	dir, err := ioutil.TempDir("", "systemd-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "notify.sock")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		os.RemoveAll(dir)
+		t.Fatal(err)
+	}
+	os.Setenv("NOTIFY_SOCKET", path)
+
+	s := &fakeNotifySocket{conn: conn, dir: dir, messages: make(chan string, 100)}
+	go func() {
+		buf := make([]byte, 4096)
+		for {
+			n, err := conn.Read(buf)
+			if err != nil {
+				close(s.messages)
+				return
+			}
+			s.messages <- string(buf[:n])
+		}
+	}()
+	return s
+}
+
+func (s *fakeNotifySocket) Close() {
+	os.Unsetenv("NOTIFY_SOCKET")
+	s.conn.Close()
+	os.RemoveAll(s.dir)
+}
+
+// expect fails the test unless the next notification received is message.
+func (s *fakeNotifySocket) expect(t *testing.T, message string) {
+	select {
+	case m := <-s.messages:
+		if m != message {
+			t.Fatalf("expected notification %q, got %q", message, m)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("expected notification %q, got none", message)
+	}
+}
+
+// expectNone fails the test if a notification is received.
+func (s *fakeNotifySocket) expectNone(t *testing.T) {
+	select {
+	case m := <-s.messages:
+		t.Fatalf("expected no notification, got %q", m)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestNotifier(t *testing.T) {
+	s := newFakeNotifySocket(t)
+	defer s.Close()
+
+	n := NewNotifier()
+	n.Ready()
+	s.expect(t, "READY=1")
+	n.Reloading()
+	s.expect(t, "RELOADING=1")
+	n.Stopping()
+	s.expect(t, "STOPPING=1")
+
+	// Unchanged statuses are not sent again.
+	n.Status("Listening")
+	s.expect(t, "STATUS=Listening")
+	n.Status("Listening")
+	s.expectNone(t)
+}
+
+func TestObserveChecks(t *testing.T) {
+	s := newFakeNotifySocket(t)
+	defer s.Close()
+
+	r, err := runner.NewRunner("master")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := `{"cluster_checks": {
+		"check1": {"cmd": ["check1"], "timeout": "1s"},
+		"check2": {"cmd": ["check2"], "timeout": "1s"},
+		"check3": {"cmd": ["check3"], "timeout": "1s"}
+	}}`
+	if err := r.Load(strings.NewReader(cfg)); err != nil {
+		t.Fatal(err)
+	}
+	statuses := map[string]int{"check1": 0, "check2": 2, "check3": 1}
+	r.Executor = runner.ExecutorFunc(func(ctx context.Context, cmd []string) ([]byte, []byte, int, error) {
+		return nil, nil, statuses[cmd[0]], nil
+	})
+	r.Observers = append(r.Observers, NewNotifier())
+
+	if _, err := r.Cluster(context.Background(), false); err != nil {
+		t.Fatal(err)
+	}
+	s.expect(t, "STATUS=Last cluster run: CRITICAL, 3 checks (check2 CRITICAL, check3 WARNING)")
+
+	statuses["check2"], statuses["check3"] = 0, 0
+	if _, err := r.Cluster(context.Background(), false); err != nil {
+		t.Fatal(err)
+	}
+	s.expect(t, "STATUS=Last cluster run: OK, 3 checks")
+}
+
+func TestWatchdog(t *testing.T) {
+	s := newFakeNotifySocket(t)
+	defer s.Close()
+
+	n := NewNotifier()
+
+	// The watchdog is disabled without WATCHDOG_USEC.
+	n.Watchdog(context.Background(), func() error { return nil })
+	s.expectNone(t)
+
+	os.Setenv("WATCHDOG_USEC", "20000")
+	defer os.Unsetenv("WATCHDOG_USEC")
+
+	var (
+		mu        sync.Mutex
+		unhealthy error
+	)
+	healthy := func() error {
+		mu.Lock()
+		defer mu.Unlock()
+		return unhealthy
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		n.Watchdog(ctx, healthy)
+	}()
+	s.expect(t, "WATCHDOG=1")
+	s.expect(t, "WATCHDOG=1")
+
+	// Pings stop while the runner is unhealthy.
+	mu.Lock()
+	unhealthy = errors.New("hung")
+	mu.Unlock()
+	// Drain a ping which may have been sent before.
+	time.Sleep(20 * time.Millisecond)
+	for len(s.messages) > 0 {
+		<-s.messages
+	}
+	s.expectNone(t)
+
+	mu.Lock()
+	unhealthy = nil
+	mu.Unlock()
+	s.expect(t, "WATCHDOG=1")
+
+	cancel()
+	<-done
+}
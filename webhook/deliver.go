@@ -0,0 +1,109 @@
+package webhook
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// SignatureHeader carries the hex encoded HMAC-SHA256 of the request body, prefixed with "sha256=".
+const SignatureHeader = "X-Check-Runner-Signature"
+
+// deliveryError is returned for failed deliveries. Permanent errors are not retried.
+type deliveryError struct {
+	msg       string
+	permanent bool
+}
+
+func (e *deliveryError) Error() string {
+	return e.msg
+}
+
+// deliverQueue delivers the events of q until the notifier is closed.
+func (n *Notifier) deliverQueue(q *queue) {
+	defer n.wg.Done()
+
+	var backoff time.Duration
+	for {
+		seq, body, ok, err := q.peek()
+		if err != nil {
+			logrus.Errorf("Unable to read webhook queue for %s: %s", q.url, err)
+		}
+		if !ok {
+			select {
+			case <-q.wake:
+				continue
+			case <-n.done:
+				return
+			case <-time.After(n.cfg.MaxBackoff):
+				// Retry reading the queue after errors.
+				continue
+			}
+		}
+
+		err = n.deliver(q.url, body)
+		if err == nil || err.(*deliveryError).permanent {
+			if err != nil {
+				logrus.Errorf("Dropping webhook event for %s: %s", q.url, err)
+			}
+			if err := q.remove(seq); err != nil {
+				logrus.Errorf("Unable to remove webhook event for %s: %s", q.url, err)
+			}
+			backoff = 0
+			continue
+		}
+
+		if backoff == 0 {
+			backoff = n.cfg.MinBackoff
+		} else if backoff *= 2; backoff > n.cfg.MaxBackoff {
+			backoff = n.cfg.MaxBackoff
+		}
+		logrus.Warnf("Unable to deliver webhook event to %s, retrying in %s: %s", q.url, backoff, err)
+		select {
+		case <-time.After(backoff):
+		case <-n.done:
+			return
+		}
+	}
+}
+
+// deliver posts body to url. Client errors other than 408 and 429 are permanent, all other failures are retried.
+func (n *Notifier) deliver(url string, body []byte) error {
+	req, err := http.NewRequest("POST", url, bytes.NewReader(body))
+	if err != nil {
+		return &deliveryError{err.Error(), true}
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if len(n.cfg.Secret) > 0 {
+		req.Header.Set(SignatureHeader, "sha256="+Sign(n.cfg.Secret, body))
+	}
+
+	resp, err := n.cfg.Client.Do(req)
+	if err != nil {
+		return &deliveryError{err.Error(), false}
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, resp.Body)
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+	permanent := resp.StatusCode >= 400 && resp.StatusCode < 500 &&
+		resp.StatusCode != http.StatusRequestTimeout && resp.StatusCode != http.StatusTooManyRequests
+	return &deliveryError{fmt.Sprintf("unexpected response %s", resp.Status), permanent}
+}
+
+// Sign returns the hex encoded HMAC-SHA256 of body with secret, as sent in the SignatureHeader.
+func Sign(secret, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,264 @@
+// Package webhook notifies webhooks of check status transitions.
+//
+// Events are queued on disk, one queue per webhook URL, so that notifications survive restarts and a failing webhook
+// doesn't delay the others. Failed deliveries are retried with exponential backoff.
+package webhook
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/dcos/dcos-check-runner/runner"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// DefaultMaxQueued is the default maximum number of events queued per webhook.
+	DefaultMaxQueued = 1000
+
+	// DefaultTimeout is the default timeout of a webhook request.
+	DefaultTimeout = 10 * time.Second
+
+	defaultMinBackoff = time.Second
+	defaultMaxBackoff = 5 * time.Minute
+
+	statesFile = "states.json"
+)
+
+// Event is posted to webhooks when a check transitions between statuses.
+type Event struct {
+	ID        string    `json:"id"`
+	Time      time.Time `json:"time"`
+	Node      string    `json:"node"`
+	Role      string    `json:"role"`
+	Suite     string    `json:"suite"`
+	Check     string    `json:"check"`
+	OldStatus int       `json:"old_status"`
+	NewStatus int       `json:"new_status"`
+	Output    string    `json:"output"`
+}
+
+// Config configures a Notifier.
+type Config struct {
+	// URLs are the webhooks events are posted to.
+	URLs []string
+
+	// Secret is the key of the HMAC-SHA256 signature sent in the SignatureHeader. If empty, events are not signed.
+	Secret []byte
+
+	// Debounce is the number of consecutive runs a check must be in a new status before the transition is
+	// notified. Defaults to 1.
+	Debounce int
+
+	// QueueDir is the directory events are queued in.
+	QueueDir string
+
+	// MaxQueued is the maximum number of events queued per webhook. When a queue is full, the oldest event is
+	// dropped. Defaults to DefaultMaxQueued.
+	MaxQueued int
+
+	// Node and Role identify the node in events.
+	Node string
+	Role string
+
+	// Client is used for webhook requests. If nil, a client with DefaultTimeout is used.
+	Client *http.Client
+
+	// MinBackoff and MaxBackoff bound the delay between retries of failed deliveries.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+}
+
+// checkState is the notified status of a check and a pending transition to a different status.
+type checkState struct {
+	Status       int `json:"status"`
+	Pending      int `json:"pending"`
+	PendingCount int `json:"pending_count"`
+}
+
+// Notifier posts events to webhooks when checks transition between statuses. It implements runner.Observer.
+type Notifier struct {
+	cfg    Config
+	queues []*queue
+
+	mu     sync.Mutex
+	states map[string]*checkState
+
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewNotifier returns a *Notifier for cfg and starts delivering queued events.
+func NewNotifier(cfg Config) (*Notifier, error) {
+	if len(cfg.URLs) == 0 {
+		return nil, errors.New("no webhook URLs configured")
+	}
+	if cfg.QueueDir == "" {
+		return nil, errors.New("no webhook queue directory configured")
+	}
+	if cfg.Debounce < 1 {
+		cfg.Debounce = 1
+	}
+	if cfg.MaxQueued < 1 {
+		cfg.MaxQueued = DefaultMaxQueued
+	}
+	if cfg.Client == nil {
+		cfg.Client = &http.Client{Timeout: DefaultTimeout}
+	}
+	if cfg.MinBackoff <= 0 {
+		cfg.MinBackoff = defaultMinBackoff
+	}
+	if cfg.MaxBackoff < cfg.MinBackoff {
+		cfg.MaxBackoff = defaultMaxBackoff
+	}
+
+	n := &Notifier{
+		cfg:    cfg,
+		states: make(map[string]*checkState),
+		done:   make(chan struct{}),
+	}
+
+	if err := os.MkdirAll(cfg.QueueDir, 0755); err != nil {
+		return nil, errors.Wrap(err, "unable to create webhook queue directory")
+	}
+	body, err := ioutil.ReadFile(filepath.Join(cfg.QueueDir, statesFile))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, errors.Wrap(err, "unable to read webhook states")
+	}
+	if err == nil {
+		if err := json.Unmarshal(body, &n.states); err != nil {
+			return nil, errors.Wrap(err, "unable to decode webhook states")
+		}
+	}
+
+	for _, url := range cfg.URLs {
+		q, err := openQueue(cfg.QueueDir, url, cfg.MaxQueued)
+		if err != nil {
+			return nil, err
+		}
+		n.queues = append(n.queues, q)
+	}
+	for _, q := range n.queues {
+		n.wg.Add(1)
+		go n.deliverQueue(q)
+	}
+	return n, nil
+}
+
+// Close stops delivering events. Queued events are delivered after the next start.
+func (n *Notifier) Close() error {
+	close(n.done)
+	n.wg.Wait()
+	return nil
+}
+
+// ObserveChecks queues events for the checks in rs whose status transitioned.
+func (n *Notifier) ObserveChecks(suite string, rs *runner.CombinedResponse) {
+	events, err := n.transitions(suite, rs)
+	if err != nil {
+		logrus.Errorf("Unable to save webhook states: %s", err)
+	}
+
+	for _, e := range events {
+		body, err := json.Marshal(e)
+		if err != nil {
+			logrus.Errorf("Unable to encode webhook event: %s", err)
+			continue
+		}
+		for _, q := range n.queues {
+			if err := q.push(body); err != nil {
+				logrus.Errorf("Unable to queue webhook event for %s: %s", q.url, err)
+			}
+		}
+	}
+}
+
+// transitions updates the states of the checks in rs and returns events for the debounced transitions. Checks which
+// failed to execute are UNKNOWN, with the error as their output.
+func (n *Notifier) transitions(suite string, rs *runner.CombinedResponse) ([]Event, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	var events []Event
+	for _, check := range append(rs.Checks(), rs.Failed()...) {
+		key := suite + "/" + check.Name()
+		s, ok := n.states[key]
+		if !ok {
+			// The first status of a check is not a transition.
+			n.states[key] = &checkState{Status: check.Status()}
+			continue
+		}
+
+		if check.Status() == s.Status {
+			s.PendingCount = 0
+			continue
+		}
+		if s.PendingCount > 0 && s.Pending == check.Status() {
+			s.PendingCount++
+		} else {
+			s.Pending, s.PendingCount = check.Status(), 1
+		}
+		if s.PendingCount < n.cfg.Debounce {
+			continue
+		}
+
+		events = append(events, Event{
+			ID:        newEventID(),
+			Time:      check.Start(),
+			Node:      n.cfg.Node,
+			Role:      n.cfg.Role,
+			Suite:     suite,
+			Check:     check.Name(),
+			OldStatus: s.Status,
+			NewStatus: check.Status(),
+			Output:    check.Output(),
+		})
+		s.Status, s.PendingCount = check.Status(), 0
+	}
+
+	return events, n.saveStates()
+}
+
+// saveStates writes the check states to the queue directory, replacing the file atomically.
+func (n *Notifier) saveStates() error {
+	body, err := json.Marshal(n.states)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(filepath.Join(n.cfg.QueueDir, statesFile), body)
+}
+
+// newEventID returns a random event ID.
+func newEventID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return time.Now().Format(time.RFC3339Nano)
+	}
+	return hex.EncodeToString(b)
+}
+
+// writeFileAtomic writes data to a temporary file in the directory of path and renames it to path.
+func writeFileAtomic(path string, data []byte) error {
+	f, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
@@ -0,0 +1,280 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dcos/dcos-check-runner/runner"
+	"github.com/pkg/errors"
+)
+
+// testServer records the events posted to it. It fails the first failures requests.
+type testServer struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	events   []Event
+	sigs     []string
+	failures int
+	received chan struct{}
+}
+
+func newTestServer(failures int) *testServer {
+	s := &testServer{failures: failures, received: make(chan struct{}, 100)}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if s.failures > 0 {
+			s.failures--
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+
+		var e Event
+		json.Unmarshal(body, &e)
+		s.events = append(s.events, e)
+		s.sigs = append(s.sigs, r.Header.Get(SignatureHeader)+" "+Sign([]byte("secret"), body))
+		s.received <- struct{}{}
+	}))
+	return s
+}
+
+// waitEvents waits until n events were received and returns them.
+func (s *testServer) waitEvents(t *testing.T, n int) []Event {
+	for i := 0; i < n; i++ {
+		select {
+		case <-s.received:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for event %d", i+1)
+		}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Event(nil), s.events...)
+}
+
+// newTestRunner returns a runner with a single poststart check whose status is read from *status.
+func newTestRunner(t *testing.T, status *int) *runner.Runner {
+	r, err := runner.NewRunner("agent")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := `{"node_checks": {"checks": {"check1": {"cmd": ["check1"], "timeout": "1s"}}, "poststart": ["check1"]}}`
+	if err := r.Load(strings.NewReader(cfg)); err != nil {
+		t.Fatal(err)
+	}
+	r.Executor = runner.ExecutorFunc(func(ctx context.Context, cmd []string) ([]byte, []byte, int, error) {
+		return []byte("status output\n"), nil, *status, nil
+	})
+	return r
+}
+
+func newTestDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "webhook-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func TestNotifier(t *testing.T) {
+	dir := newTestDir(t)
+	defer os.RemoveAll(dir)
+	s := newTestServer(2)
+	defer s.Close()
+
+	n, err := NewNotifier(Config{
+		URLs:       []string{s.URL},
+		Secret:     []byte("secret"),
+		Debounce:   2,
+		QueueDir:   dir,
+		Node:       "10.0.0.1",
+		Role:       "agent",
+		MinBackoff: 10 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer n.Close()
+
+	var status int
+	r := newTestRunner(t, &status)
+	r.Observers = []runner.Observer{n}
+
+	for _, status = range []int{0, 2, 0, 2, 2, 2, 0, 0} {
+		if _, err := r.PostStart(context.TODO(), false); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Only transitions which lasted for two runs are notified, after retrying the failed deliveries.
+	events := s.waitEvents(t, 2)
+	if len(events) != 2 {
+		t.Fatalf("expected 2 events, got %+v", events)
+	}
+	e := events[0]
+	if e.Node != "10.0.0.1" || e.Role != "agent" || e.Suite != runner.SuiteNodePostStart || e.Check != "check1" ||
+		e.OldStatus != 0 || e.NewStatus != 2 || e.Output != "status output\n" || e.ID == "" || e.Time.IsZero() {
+		t.Fatalf("unexpected event %+v", e)
+	}
+	if e := events[1]; e.OldStatus != 2 || e.NewStatus != 0 || e.ID == events[0].ID {
+		t.Fatalf("unexpected event %+v", e)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, sig := range s.sigs {
+		parts := strings.Split(sig, " ")
+		if parts[0] != "sha256="+parts[1] {
+			t.Fatalf("invalid signature %s", parts[0])
+		}
+	}
+}
+
+func TestNotifierFailedToExecute(t *testing.T) {
+	dir := newTestDir(t)
+	defer os.RemoveAll(dir)
+	s := newTestServer(0)
+	defer s.Close()
+
+	n, err := NewNotifier(Config{URLs: []string{s.URL}, Debounce: 1, QueueDir: dir, MinBackoff: 10 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer n.Close()
+
+	// The check is OK, then its command can't be executed anymore.
+	var execErr error
+	r := newTestRunner(t, new(int))
+	r.Executor = runner.ExecutorFunc(func(ctx context.Context, cmd []string) ([]byte, []byte, int, error) {
+		return nil, nil, 0, execErr
+	})
+	r.Observers = []runner.Observer{n}
+
+	for _, execErr = range []error{nil, errors.New("executable file not found")} {
+		if _, err := r.PostStart(context.TODO(), false); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	events := s.waitEvents(t, 1)
+	if e := events[0]; e.Check != "check1" || e.OldStatus != 0 || e.NewStatus != 3 ||
+		!strings.Contains(e.Output, "executable file not found") || e.Time.IsZero() {
+		t.Fatalf("unexpected event %+v", e)
+	}
+}
+
+func TestNotifierRestart(t *testing.T) {
+	dir := newTestDir(t)
+	defer os.RemoveAll(dir)
+
+	// The webhook fails while the transition is observed.
+	s := newTestServer(1000)
+	defer s.Close()
+
+	cfg := Config{URLs: []string{s.URL}, QueueDir: dir, MinBackoff: time.Hour}
+	n, err := NewNotifier(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var status int
+	r := newTestRunner(t, &status)
+	r.Observers = []runner.Observer{n}
+	for _, status = range []int{0, 1} {
+		if _, err := r.PostStart(context.TODO(), false); err != nil {
+			t.Fatal(err)
+		}
+	}
+	n.Close()
+
+	// After a restart the queued event is delivered, and the notified status is remembered.
+	s.mu.Lock()
+	s.failures = 0
+	s.mu.Unlock()
+	cfg.MinBackoff = 0
+	n, err = NewNotifier(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer n.Close()
+	r.Observers = []runner.Observer{n}
+
+	if e := s.waitEvents(t, 1); e[0].OldStatus != 0 || e[0].NewStatus != 1 {
+		t.Fatalf("unexpected event %+v", e[0])
+	}
+
+	for _, status = range []int{1, 0} {
+		if _, err := r.PostStart(context.TODO(), false); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if e := s.waitEvents(t, 1); len(e) != 2 || e[1].OldStatus != 1 || e[1].NewStatus != 0 {
+		t.Fatalf("unexpected events %+v", e)
+	}
+}
+
+func TestQueueBound(t *testing.T) {
+	dir := newTestDir(t)
+	defer os.RemoveAll(dir)
+
+	q, err := openQueue(dir, "http://example.com", 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, body := range []string{"1", "2", "3", "4", "5"} {
+		if err := q.push([]byte(body)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var bodies []string
+	for {
+		seq, body, ok, err := q.peek()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !ok {
+			break
+		}
+		bodies = append(bodies, string(body))
+		if err := q.remove(seq); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if strings.Join(bodies, ",") != "3,4,5" {
+		t.Fatalf("expected the 3 newest events, got %v", bodies)
+	}
+}
+
+func TestDeliverPermanentErrors(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/bad":
+			w.WriteHeader(http.StatusBadRequest)
+		case "/throttled":
+			w.WriteHeader(http.StatusTooManyRequests)
+		}
+	}))
+	defer s.Close()
+
+	n := &Notifier{cfg: Config{Client: http.DefaultClient}}
+	if err := n.deliver(s.URL+"/ok", []byte("{}")); err != nil {
+		t.Fatal(err)
+	}
+	if err := n.deliver(s.URL+"/bad", []byte("{}")); err == nil || !err.(*deliveryError).permanent {
+		t.Fatalf("expected permanent error, got %v", err)
+	}
+	if err := n.deliver(s.URL+"/throttled", []byte("{}")); err == nil || err.(*deliveryError).permanent {
+		t.Fatalf("expected temporary error, got %v", err)
+	}
+}
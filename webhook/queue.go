@@ -0,0 +1,137 @@
+package webhook
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+const eventExt = ".json"
+
+// queue is a bounded FIFO of events for a webhook, stored as one file per event.
+type queue struct {
+	url string
+	dir string
+	max int
+
+	mu  sync.Mutex
+	seq int
+
+	// wake is signaled when an event is pushed.
+	wake chan struct{}
+}
+
+// openQueue opens the queue of url in a subdirectory of dir named after the hash of the URL.
+func openQueue(dir, url string, max int) (*queue, error) {
+	sum := sha256.Sum256([]byte(url))
+	q := &queue{
+		url:  url,
+		dir:  filepath.Join(dir, hex.EncodeToString(sum[:8])),
+		max:  max,
+		wake: make(chan struct{}, 1),
+	}
+	if err := os.MkdirAll(q.dir, 0755); err != nil {
+		return nil, errors.Wrap(err, "unable to create webhook queue directory")
+	}
+
+	events, err := q.list()
+	if err != nil {
+		return nil, err
+	}
+	if len(events) > 0 {
+		q.seq = events[len(events)-1]
+	}
+	return q, nil
+}
+
+// push appends an event to the queue, dropping the oldest events if the queue is full.
+func (q *queue) push(body []byte) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.seq++
+	if err := writeFileAtomic(q.path(q.seq), body); err != nil {
+		return err
+	}
+
+	events, err := q.list()
+	if err != nil {
+		return err
+	}
+	for len(events) > q.max {
+		logrus.Warnf("Webhook queue for %s is full, dropping the oldest event", q.url)
+		if err := os.Remove(q.path(events[0])); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		events = events[1:]
+	}
+
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// peek returns the sequence number and body of the oldest event, and false if the queue is empty.
+func (q *queue) peek() (int, []byte, bool, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	events, err := q.list()
+	if err != nil || len(events) == 0 {
+		return 0, nil, false, err
+	}
+	body, err := ioutil.ReadFile(q.path(events[0]))
+	if err != nil {
+		return 0, nil, false, err
+	}
+	return events[0], body, true, nil
+}
+
+// remove removes the event with the given sequence number.
+func (q *queue) remove(seq int) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if err := os.Remove(q.path(seq)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func (q *queue) path(seq int) string {
+	return filepath.Join(q.dir, fmt.Sprintf("%020d%s", seq, eventExt))
+}
+
+// list returns the sequence numbers of the queued events, oldest first.
+func (q *queue) list() ([]int, error) {
+	files, err := ioutil.ReadDir(q.dir)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to read webhook queue")
+	}
+
+	var events []int
+	for _, f := range files {
+		if !strings.HasSuffix(f.Name(), eventExt) {
+			continue
+		}
+		seq, err := strconv.Atoi(strings.TrimSuffix(f.Name(), eventExt))
+		if err != nil {
+			continue
+		}
+		events = append(events, seq)
+	}
+	sort.Ints(events)
+	return events, nil
+}